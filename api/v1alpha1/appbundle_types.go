@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
@@ -24,7 +25,22 @@ import (
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
+const (
+	// ApproveGroupAnnotation approves one or more manual groups (comma separated)
+	// for the current generation of the AppBundle
+	ApproveGroupAnnotation = "app.example.com/approve-group"
+	// RetryAnnotation requests another deployment attempt of a failed or rolled back
+	// AppBundle. A value naming a group retries that group and the groups after it, keeping
+	// the groups deployed before it; any other value retries every group.
+	RetryAnnotation = "app.example.com/retry"
+	// SkipPreDeleteHooksAnnotation set to "true" deletes the AppBundle without running its
+	// PreDelete hooks, for instance when a hook cannot complete
+	SkipPreDeleteHooksAnnotation = "app.example.com/skip-predelete-hooks"
+)
+
 // Component represents a Kubernetes resource template within a group
+// +kubebuilder:validation:XValidation:rule="[has(self.helmChart), has(self.kustomize), has(self.source)].filter(x, x).size() <= 1",message="helmChart, kustomize and source are mutually exclusive"
+// +kubebuilder:validation:XValidation:rule="!has(self.hook) || !has(self.porchPackageRef)",message="hooks cannot be delivered through Porch"
 type Component struct {
 	// Name is the unique identifier for the component within a group
 	// +kubebuilder:validation:Required
@@ -39,16 +55,255 @@ type Component struct {
 	// Template is the Kubernetes resource template to be deployed
 	// This can be any valid Kubernetes resource (Deployment, Service, ConfigMap, etc.)
 	// When PorchPackageRef is specified, Template is optional - the controller will
-	// auto-discover resources from the deployed package
+	// auto-discover resources from the deployed package. It is ignored when HelmChart,
+	// Kustomize or Source is set.
 	// +optional
 	// +kubebuilder:pruning:PreserveUnknownFields
 	Template runtime.RawExtension `json:"template,omitempty"`
 
+	// Substitute resolves the Go template actions ({{ }}) and CEL expressions (${ }) in
+	// Template. It defaults to true in bundles that declare parameters or outputs, and to
+	// false otherwise, so templates that carry such syntax of their own, like Prometheus
+	// alerting rules, are applied as they are.
+	// +optional
+	Substitute *bool `json:"substitute,omitempty"`
+
 	// PorchPackageRef references a Porch package for this component
 	// When specified, the controller creates a PackageVariant and auto-discovers
 	// the resources deployed by Porch for monitoring
 	// +optional
 	PorchPackageRef *PorchPackageReference `json:"porchPackageRef,omitempty"`
+
+	// HelmChart renders a Helm chart into the component's resources
+	// The rendered resources are applied in Helm's install order, with pre-install hooks
+	// before and post-install hooks after them
+	// +optional
+	HelmChart *HelmChartSource `json:"helmChart,omitempty"`
+
+	// Kustomize builds a kustomization into the component's resources
+	// The resources are applied in kustomize's output order
+	// +optional
+	Kustomize *KustomizeSource `json:"kustomize,omitempty"`
+
+	// Source reads the component's manifests from an OCI artifact or a Git repository
+	// instead of Template; a directory holding a kustomization is built with kustomize
+	// +optional
+	Source *ComponentSource `json:"source,omitempty"`
+
+	// IgnoreDifferences lists fields of this component's resources that are managed by other
+	// actors; they are preserved on update and excluded from drift detection
+	// +optional
+	IgnoreDifferences []IgnoreDifference `json:"ignoreDifferences,omitempty"`
+
+	// Outputs extract values from the component's live objects once they are ready; later
+	// components reference them as ${components.NAME.outputs.OUTPUT}
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Outputs []ComponentOutput `json:"outputs,omitempty"`
+
+	// DeletionPolicy overrides the bundle's deletion policy for the objects of this component
+	// +kubebuilder:validation:Enum=Delete;Orphan;Retain
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// AdoptionPolicy overrides the bundle's adoption policy for the objects of this component
+	// +kubebuilder:validation:Enum=Never;IfUnowned;Force
+	// +optional
+	AdoptionPolicy AdoptionPolicy `json:"adoptionPolicy,omitempty"`
+
+	// Hook runs the component at a fixed point of the rollout instead of deploying it with
+	// the other components. PreGroup and PostGroup hooks run before and after the other
+	// components of their group, PostDeploy hooks once every group is deployed, and PreDelete
+	// hooks when the bundle is deleted. The rollout waits for hook Jobs to complete.
+	// +kubebuilder:validation:Enum=PreGroup;PostGroup;PostDeploy;PreDelete
+	// +optional
+	Hook HookType `json:"hook,omitempty"`
+
+	// HookDeletePolicies determine when the objects of a hook are deleted. Without any, the
+	// previous run is deleted before the hook runs again.
+	// +optional
+	HookDeletePolicies []HookDeletePolicy `json:"hookDeletePolicies,omitempty"`
+
+	// ConfigFrom names components of the bundle whose ConfigMaps and Secrets the workloads of
+	// this component depend on, in addition to the ones their pod templates reference. A
+	// change to any of them restarts the workloads.
+	// +optional
+	ConfigFrom []string `json:"configFrom,omitempty"`
+
+	// RolloutStrategy rolls out changes to the component's Deployments in canary steps
+	// instead of updating them in one go
+	// +optional
+	RolloutStrategy *RolloutStrategy `json:"rolloutStrategy,omitempty"`
+}
+
+// RolloutStrategy determines how changes to a workload are rolled out
+type RolloutStrategy struct {
+	// Canary moves replicas to the new version step by step, checking gates in between
+	// +kubebuilder:validation:Required
+	Canary *CanaryStrategy `json:"canary"`
+}
+
+// CanaryStrategy runs the new version of a Deployment next to the stable one in steps
+type CanaryStrategy struct {
+	// Steps split the replicas between the stable and the canary Deployment. After the last
+	// step the stable Deployment is updated and the canary removed.
+	// +kubebuilder:validation:MinItems=1
+	Steps []CanaryStep `json:"steps"`
+
+	// Analysis holds the metric gates evaluated after every step
+	// +optional
+	Analysis *CanaryAnalysis `json:"analysis,omitempty"`
+}
+
+// CanaryStep sets the share of replicas running the new version, then waits
+// +kubebuilder:validation:XValidation:rule="has(self.weight) != has(self.replicas)",message="exactly one of weight and replicas must be set"
+type CanaryStep struct {
+	// Weight is the percentage of replicas running the new version
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	Weight *int32 `json:"weight,omitempty"`
+
+	// Replicas is the number of replicas running the new version
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Pause is how long the step runs before its gates are evaluated
+	// +optional
+	Pause *metav1.Duration `json:"pause,omitempty"`
+}
+
+// CanaryAnalysis checks metrics of a canary against success conditions
+type CanaryAnalysis struct {
+	// Provider answers the metric queries
+	// +kubebuilder:validation:Required
+	Provider MetricProvider `json:"provider"`
+
+	// Metrics must all succeed after each step, otherwise the rollout is aborted
+	// +kubebuilder:validation:MinItems=1
+	// +listType=map
+	// +listMapKey=name
+	Metrics []AnalysisMetric `json:"metrics"`
+}
+
+// MetricProvider locates the metrics backend of a canary analysis
+type MetricProvider struct {
+	// Type of the provider. Prometheus queries a Prometheus-compatible HTTP API.
+	// +kubebuilder:validation:Enum=Prometheus
+	// +kubebuilder:default=Prometheus
+	// +optional
+	Type MetricProviderType `json:"type,omitempty"`
+
+	// Address of the provider, such as http://prometheus.monitoring:9090
+	// +kubebuilder:validation:Required
+	Address string `json:"address"`
+}
+
+// MetricProviderType names a metrics backend
+type MetricProviderType string
+
+const (
+	// MetricProviderPrometheus queries the Prometheus HTTP API
+	MetricProviderPrometheus MetricProviderType = "Prometheus"
+)
+
+// AnalysisMetric is a query whose result must meet a condition
+type AnalysisMetric struct {
+	// Name of the metric
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Query returns a single value, such as the error ratio of the canary's pods
+	// +kubebuilder:validation:Required
+	Query string `json:"query"`
+
+	// SuccessCondition is a CEL expression over the query's value, named result, that must
+	// be true, such as "result < 0.05"
+	// +kubebuilder:validation:Required
+	SuccessCondition string `json:"successCondition"`
+}
+
+// HookType determines when a hook component runs
+type HookType string
+
+const (
+	// HookPreGroup runs before the other components of the hook's group
+	HookPreGroup HookType = "PreGroup"
+	// HookPostGroup runs after the other components of the hook's group are ready
+	HookPostGroup HookType = "PostGroup"
+	// HookPostDeploy runs after every group is deployed
+	HookPostDeploy HookType = "PostDeploy"
+	// HookPreDelete runs when the bundle is deleted, before any object is removed
+	HookPreDelete HookType = "PreDelete"
+)
+
+// HookDeletePolicy determines when the objects of a hook are deleted, like the hook delete
+// policies of Helm and Argo CD
+// +kubebuilder:validation:Enum=BeforeHookCreation;HookSucceeded;HookFailed
+type HookDeletePolicy string
+
+const (
+	// HookDeleteBeforeCreation deletes the previous run before the hook runs again
+	HookDeleteBeforeCreation HookDeletePolicy = "BeforeHookCreation"
+	// HookDeleteSucceeded deletes the hook once it succeeded
+	HookDeleteSucceeded HookDeletePolicy = "HookSucceeded"
+	// HookDeleteFailed deletes the hook once it failed
+	HookDeleteFailed HookDeletePolicy = "HookFailed"
+)
+
+// ComponentOutput extracts a value from a live object of a component with either a JSONPath
+// or a CEL expression
+// +kubebuilder:validation:XValidation:rule="has(self.jsonPath) != has(self.expression)",message="exactly one of jsonPath and expression must be set"
+type ComponentOutput struct {
+	// Name of the output
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z_][a-zA-Z0-9_]*$`
+	Name string `json:"name"`
+
+	// Kind of the object to read; defaults to the kind of the component's first object
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// ObjectName is the name of the object to read; defaults to the name of the first
+	// object of Kind
+	// +optional
+	ObjectName string `json:"objectName,omitempty"`
+
+	// JSONPath selects the value, such as {.spec.clusterIP}
+	// +optional
+	JSONPath string `json:"jsonPath,omitempty"`
+
+	// Expression is a CEL expression over the live object, bound to object, such as
+	// object.status.loadBalancer.ingress[0].ip
+	// +optional
+	Expression string `json:"expression,omitempty"`
+}
+
+// IgnoreDifference selects fields that the controller leaves to other actors,
+// such as spec.replicas under an HPA or sidecars injected by a webhook
+type IgnoreDifference struct {
+	// Group is the API group of the resources the rule applies to; empty matches any group
+	// +optional
+	Group string `json:"group,omitempty"`
+
+	// Kind of the resources the rule applies to; empty matches any kind
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// Name of the resource the rule applies to; empty matches any name
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// JSONPointers are RFC 6901 paths of ignored fields, e.g. /spec/replicas
+	// +optional
+	JSONPointers []string `json:"jsonPointers,omitempty"`
+
+	// JQPathExpressions are jq-style paths of ignored fields, e.g. .spec.template.spec.containers[].image
+	// Supported forms are .field, ["field"], [index] and [] (every element)
+	// +optional
+	JQPathExpressions []string `json:"jqPathExpressions,omitempty"`
 }
 
 // Group represents a collection of related components
@@ -67,8 +322,26 @@ type Group struct {
 	// +kubebuilder:validation:MinItems=1
 	// +kubebuilder:validation:Required
 	Components []Component `json:"components"`
+
+	// Approval controls whether the group is deployed automatically or waits for sign-off
+	// With Manual, the controller stops before this group until it is approved through the
+	// app.example.com/approve-group annotation or status.approvedGroups for the current generation
+	// +kubebuilder:validation:Enum=Auto;Manual
+	// +kubebuilder:default=Auto
+	// +optional
+	Approval ApprovalMode `json:"approval,omitempty"`
 }
 
+// ApprovalMode determines whether a group needs manual approval before deployment
+type ApprovalMode string
+
+const (
+	// ApprovalAuto deploys the group without waiting for approval
+	ApprovalAuto ApprovalMode = "Auto"
+	// ApprovalManual waits for the group to be approved before deploying it
+	ApprovalManual ApprovalMode = "Manual"
+)
+
 // PorchPackageReference contains information to reference a Porch package
 type PorchPackageReference struct {
 	// PackageName is the name of the package in the upstream repository
@@ -88,19 +361,470 @@ type PorchPackageReference struct {
 	Revision string `json:"revision,omitempty"`
 }
 
+// HelmChartSource references a Helm chart and the values it is rendered with
+type HelmChartSource struct {
+	// Chart is the name of the chart in the repository
+	// +kubebuilder:validation:Required
+	Chart string `json:"chart"`
+
+	// Version of the chart; a semver range selects the latest matching version of an
+	// HTTP repository. Defaults to the latest version.
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// RepoURL is the repository holding the chart: an HTTP chart repository (https://) or
+	// an OCI registry (oci://)
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^(https?|oci)://`
+	RepoURL string `json:"repoURL"`
+
+	// ReleaseName is the Helm release name the chart is rendered with
+	// Defaults to the component name
+	// +optional
+	ReleaseName string `json:"releaseName,omitempty"`
+
+	// Values override the chart's default values
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Values runtime.RawExtension `json:"values,omitempty"`
+
+	// ValuesFrom merges values kept in ConfigMaps or Secrets in the AppBundle namespace,
+	// in order, before Values
+	// +optional
+	ValuesFrom []ValuesReference `json:"valuesFrom,omitempty"`
+}
+
+// ValuesReference selects values stored in a ConfigMap or Secret
+type ValuesReference struct {
+	// Kind of the object holding the values
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	// +kubebuilder:validation:Required
+	Kind string `json:"kind"`
+
+	// Name of the ConfigMap or Secret
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Key holding the values as YAML
+	// +kubebuilder:default=values.yaml
+	// +optional
+	Key string `json:"key,omitempty"`
+
+	// Optional skips the reference when the object or key does not exist
+	// +optional
+	Optional bool `json:"optional,omitempty"`
+}
+
+// KustomizeSource locates a kustomization; exactly one of ConfigMapRef, OCI and SourceRef
+// must be set
+// +kubebuilder:validation:XValidation:rule="[has(self.configMapRef), has(self.oci), has(self.sourceRef)].filter(x, x).size() == 1",message="exactly one of configMapRef, oci and sourceRef must be set"
+type KustomizeSource struct {
+	// Path of the directory holding the kustomization within the source
+	// +kubebuilder:default="."
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// ConfigMapRef names a ConfigMap in the AppBundle namespace whose keys are the files of
+	// the kustomization
+	// +optional
+	ConfigMapRef *LocalObjectReference `json:"configMapRef,omitempty"`
+
+	// OCI pulls the kustomization from an OCI artifact whose layer is a tarball, such as one
+	// pushed with `flux push artifact`
+	// +optional
+	OCI *OCIArtifactSource `json:"oci,omitempty"`
+
+	// SourceRef reads the kustomization from the artifact of a Flux source in the cluster
+	// Its namespace defaults to the AppBundle namespace
+	// +optional
+	SourceRef *FluxSourceReference `json:"sourceRef,omitempty"`
+}
+
+// LocalObjectReference names an object in the AppBundle namespace
+type LocalObjectReference struct {
+	// Name of the object
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+}
+
+// OCIArtifactSource locates an artifact in an OCI registry
+type OCIArtifactSource struct {
+	// URL of the repository, such as oci://ghcr.io/org/manifests
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^oci://`
+	URL string `json:"url"`
+
+	// Tag of the artifact
+	// Defaults to latest
+	// +optional
+	Tag string `json:"tag,omitempty"`
+
+	// Digest pins the artifact's manifest; when set, Tag is ignored and the artifact is
+	// verified against it
+	// +kubebuilder:validation:Pattern=`^sha256:[a-f0-9]{64}$`
+	// +optional
+	Digest string `json:"digest,omitempty"`
+
+	// Insecure allows plain HTTP connections to the registry
+	// +optional
+	Insecure bool `json:"insecure,omitempty"`
+}
+
+// ComponentSource locates the manifests of a component; exactly one of OCI and Git must be
+// set, and OCI artifacts must be pinned by digest
+// +kubebuilder:validation:XValidation:rule="has(self.oci) != has(self.git)",message="exactly one of oci and git must be set"
+// +kubebuilder:validation:XValidation:rule="!has(self.oci) || has(self.oci.digest)",message="oci sources must be pinned by digest"
+type ComponentSource struct {
+	// Path of the directory holding the manifests within the source
+	// +kubebuilder:default="."
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// OCI pulls the manifests from an OCI artifact whose layer is a tarball
+	// +optional
+	OCI *OCIArtifactSource `json:"oci,omitempty"`
+
+	// Git reads the manifests from a Git repository
+	// +optional
+	Git *GitSource `json:"git,omitempty"`
+}
+
+// GitSource locates a commit of a Git repository
+type GitSource struct {
+	// URL of the repository
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url"`
+
+	// Ref is the branch, tag or full commit SHA to read
+	// Defaults to the repository's default branch
+	// +optional
+	Ref string `json:"ref,omitempty"`
+
+	// SecretRef names a Secret in the AppBundle namespace with the username and password
+	// used to clone the repository
+	// +optional
+	SecretRef *LocalObjectReference `json:"secretRef,omitempty"`
+
+	// Verify requires the commit to be signed by one of the given OpenPGP keys
+	// +optional
+	Verify *GitVerification `json:"verify,omitempty"`
+}
+
+// GitVerification holds the keys commits must be signed with
+type GitVerification struct {
+	// SecretRef names a Secret in the AppBundle namespace whose values are armored OpenPGP
+	// public keys
+	// +kubebuilder:validation:Required
+	SecretRef LocalObjectReference `json:"secretRef"`
+}
+
+// TemplateStatus records the AppBundleTemplate a bundle was rendered from
+type TemplateStatus struct {
+	// Name of the AppBundleTemplate
+	Name string `json:"name"`
+
+	// Generation of the template that was rendered
+	Generation int64 `json:"generation"`
+
+	// Spec is the template at Generation, read from the template or its
+	// AppBundleTemplateRevision while reconciling. It is not persisted.
+	Spec *AppBundleTemplateSpec `json:"-"`
+}
+
+// ComponentOutputs holds the resolved outputs of a component
+type ComponentOutputs struct {
+	// Group of the component
+	Group string `json:"group"`
+
+	// Component name
+	Component string `json:"component"`
+
+	// Values of the outputs by name
+	// +optional
+	Values map[string]apiextensionsv1.JSON `json:"values,omitempty"`
+}
+
+// CanaryStatus records the progress of a canary rollout, so it resumes where it left off
+type CanaryStatus struct {
+	// Group of the component
+	Group string `json:"group"`
+
+	// Component name
+	Component string `json:"component"`
+
+	// Name of the Deployment
+	Name string `json:"name"`
+
+	// Namespace of the Deployment
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// TemplateHash is the pod template being rolled out
+	TemplateHash string `json:"templateHash"`
+
+	// StableReplicas are the replicas of the stable Deployment before the rollout
+	StableReplicas int64 `json:"stableReplicas"`
+
+	// Step is the index of the current step
+	Step int32 `json:"step"`
+
+	// StepScaledAt is when the replicas of the current step were split between the two
+	// Deployments; both must become ready within the canary readiness timeout
+	// +optional
+	StepScaledAt *metav1.Time `json:"stepScaledAt,omitempty"`
+
+	// StepStartedAt is when both Deployments of the current step became ready; its pause
+	// runs from here
+	// +optional
+	StepStartedAt *metav1.Time `json:"stepStartedAt,omitempty"`
+}
+
+// TemplateReference selects the AppBundleTemplate a bundle is instantiated from
+type TemplateReference struct {
+	// Name of the AppBundleTemplate
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Generation pins the bundle to a generation of the template; changes to the template
+	// are not rolled out until the pin is updated. Without it, the bundle re-renders
+	// whenever the template changes.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Generation int64 `json:"generation,omitempty"`
+}
+
 // AppBundleSpec defines the desired state of AppBundle
+// +kubebuilder:validation:XValidation:rule="has(self.groups) != has(self.templateRef)",message="exactly one of groups and templateRef must be set"
+// +kubebuilder:validation:XValidation:rule="!has(self.templateRef) || !has(self.parameters)",message="parameters of a templated bundle are declared by its template"
 type AppBundleSpec struct {
 	// Groups is the list of component groups to be deployed
 	// Groups are deployed in order based on their Order field
 	// +kubebuilder:validation:MinItems=1
-	// +kubebuilder:validation:Required
-	Groups []Group `json:"groups"`
+	// +optional
+	Groups []Group `json:"groups,omitempty"`
+
+	// TemplateRef instantiates the bundle from the groups and parameters of an
+	// AppBundleTemplate instead of Groups
+	// +optional
+	TemplateRef *TemplateReference `json:"templateRef,omitempty"`
+
+	// Values set the bundle's parameters, overriding their defaults; parameter sources
+	// override values
+	// +optional
+	Values map[string]apiextensionsv1.JSON `json:"values,omitempty"`
+
+	// Parameters declares the typed values templates may reference as {{ .params.NAME }}
+	// or ${params.NAME}
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Parameters []Parameter `json:"parameters,omitempty"`
+
+	// ParameterSources read parameter values from the keys of ConfigMaps and Secrets in the
+	// AppBundle namespace, in order, overriding the parameters' defaults
+	// +optional
+	ParameterSources []ParameterSource `json:"parameterSources,omitempty"`
 
 	// PorchIntegration enables integration with Porch for package lifecycle management
 	// +optional
 	PorchIntegration *PorchIntegrationSpec `json:"porchIntegration,omitempty"`
+
+	// Backend selects how the bundle's components are delivered to the cluster
+	// Direct applies the templates, Porch also delivers components that reference a Porch
+	// package through PackageVariants, and Flux creates a Flux Kustomization per group.
+	// Defaults to Porch when Porch integration is enabled or a component references a
+	// Porch package, and to Direct otherwise.
+	// +kubebuilder:validation:Enum=Direct;Porch;Flux
+	// +optional
+	Backend DeliveryBackend `json:"backend,omitempty"`
+
+	// Flux configures the Flux backend
+	// +optional
+	Flux *FluxBackendSpec `json:"flux,omitempty"`
+
+	// ArgoCD makes the controller create an Argo CD Application for the bundle's manifests
+	// and mirror its sync and health status into the bundle's conditions
+	// +optional
+	ArgoCD *ArgoCDIntegrationSpec `json:"argocd,omitempty"`
+
+	// Mode selects whether the controller deploys the bundle or only plans it
+	// In Plan mode every object is validated with a server-side dry-run and the intended
+	// changes are reported in status.plan without modifying the cluster
+	// +kubebuilder:validation:Enum=Apply;Plan
+	// +kubebuilder:default=Apply
+	// +optional
+	Mode BundleMode `json:"mode,omitempty"`
+
+	// RollbackPolicy determines what the controller does when a component fails to deploy
+	// None leaves the bundle as it is, Group re-applies the last successfully deployed
+	// manifests of the failed group, and Bundle re-applies them for every group
+	// +kubebuilder:validation:Enum=None;Group;Bundle
+	// +kubebuilder:default=None
+	// +optional
+	RollbackPolicy RollbackPolicy `json:"rollbackPolicy,omitempty"`
+
+	// DeletionPolicy determines what happens to the deployed objects when the bundle is
+	// deleted. Delete removes them, Orphan only drops their owner references, and Retain also
+	// strips the tracking labels. Components may override it.
+	// +kubebuilder:validation:Enum=Delete;Orphan;Retain
+	// +kubebuilder:default=Delete
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// AdoptionPolicy determines whether existing objects the bundle did not create are taken
+	// over. Never refuses all of them, IfUnowned adopts objects without another AppBundle,
+	// controller or Helm release as owner, and Force takes over any object. Components may
+	// override it.
+	// +kubebuilder:validation:Enum=Never;IfUnowned;Force
+	// +kubebuilder:default=IfUnowned
+	// +optional
+	AdoptionPolicy AdoptionPolicy `json:"adoptionPolicy,omitempty"`
+
+	// Suspend tells the controller to stop reconciling this AppBundle
+	// Deployed resources are left untouched until suspend is cleared; deletion is still processed
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// IgnoreDifferences lists fields of any resource in the bundle that are managed by other
+	// actors; they are preserved on update and excluded from drift detection
+	// +optional
+	IgnoreDifferences []IgnoreDifference `json:"ignoreDifferences,omitempty"`
+
+	// DriftDetection configures how deployed resources are checked against their templates
+	// +optional
+	DriftDetection *DriftDetectionSpec `json:"driftDetection,omitempty"`
+
+	// RevisionHistoryLimit is the number of AppBundleRevision objects to keep
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=10
+	// +optional
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+
+	// RollbackTo pins the bundle to the manifests of a recorded AppBundleRevision
+	// While set, the groups in this spec are not deployed; remove it to resume normal rollouts
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RollbackTo *int64 `json:"rollbackTo,omitempty"`
+}
+
+// ParameterType is the type of a parameter's value
+// +kubebuilder:validation:Enum=string;integer;number;boolean;object;array
+type ParameterType string
+
+const (
+	// ParameterTypeString is a string value
+	ParameterTypeString ParameterType = "string"
+	// ParameterTypeInteger is a whole number
+	ParameterTypeInteger ParameterType = "integer"
+	// ParameterTypeNumber is any number
+	ParameterTypeNumber ParameterType = "number"
+	// ParameterTypeBoolean is true or false
+	ParameterTypeBoolean ParameterType = "boolean"
+	// ParameterTypeObject is a map; values from sources are parsed as YAML
+	ParameterTypeObject ParameterType = "object"
+	// ParameterTypeArray is a list; values from sources are parsed as YAML
+	ParameterTypeArray ParameterType = "array"
+)
+
+// Parameter declares a value that templates may reference
+type Parameter struct {
+	// Name of the parameter
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z_][a-zA-Z0-9_]*$`
+	Name string `json:"name"`
+
+	// Type of the parameter's value; values from parameter sources are parsed accordingly
+	// +kubebuilder:default=string
+	// +optional
+	Type ParameterType `json:"type,omitempty"`
+
+	// Description of the parameter
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// Default is used when no parameter source sets the parameter. Parameters without a
+	// default must be set by a source.
+	// +optional
+	Default *apiextensionsv1.JSON `json:"default,omitempty"`
+}
+
+// ParameterSource reads parameter values from a ConfigMap or Secret, one key per parameter
+type ParameterSource struct {
+	// Kind of the object holding the values
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	// +kubebuilder:validation:Required
+	Kind string `json:"kind"`
+
+	// Name of the ConfigMap or Secret
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Optional skips the source when the object does not exist
+	// +optional
+	Optional bool `json:"optional,omitempty"`
 }
 
+// DriftDetectionSpec configures drift detection for deployed resources
+type DriftDetectionSpec struct {
+	// Interval between periodic drift checks once the bundle is deployed
+	// Changes to watched resources owned by the bundle are checked immediately
+	// Defaults to 5m; set to 0s to disable periodic checks
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// SelfHeal re-applies drifted resources from their templates
+	// +optional
+	SelfHeal bool `json:"selfHeal,omitempty"`
+}
+
+// BundleMode selects how the controller handles the bundle's resources
+type BundleMode string
+
+const (
+	// ModeApply deploys the bundle's resources
+	ModeApply BundleMode = "Apply"
+	// ModePlan only reports the changes a deployment would make
+	ModePlan BundleMode = "Plan"
+)
+
+// RollbackPolicy determines the scope of an automatic rollback on failure
+type RollbackPolicy string
+
+const (
+	// RollbackPolicyNone disables automatic rollback
+	RollbackPolicyNone RollbackPolicy = "None"
+	// RollbackPolicyGroup rolls back only the group that failed
+	RollbackPolicyGroup RollbackPolicy = "Group"
+	// RollbackPolicyBundle rolls back every group of the bundle
+	RollbackPolicyBundle RollbackPolicy = "Bundle"
+)
+
+// DeletionPolicy determines what happens to deployed objects when their bundle is deleted
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes the objects
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyOrphan keeps the objects and removes the bundle's owner references
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+	// DeletionPolicyRetain keeps the objects and removes both the owner references and the
+	// tracking labels, detaching them from the bundle entirely
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+)
+
+// AdoptionPolicy determines whether existing objects are taken over by a bundle
+type AdoptionPolicy string
+
+const (
+	// AdoptionPolicyNever only updates objects the bundle created
+	AdoptionPolicyNever AdoptionPolicy = "Never"
+	// AdoptionPolicyIfUnowned also adopts objects that nobody else owns
+	AdoptionPolicyIfUnowned AdoptionPolicy = "IfUnowned"
+	// AdoptionPolicyForce takes over objects owned by others
+	AdoptionPolicyForce AdoptionPolicy = "Force"
+)
+
 // PorchIntegrationSpec defines configuration for Porch integration
 type PorchIntegrationSpec struct {
 	// Enabled determines if Porch integration is active
@@ -112,6 +836,109 @@ type PorchIntegrationSpec struct {
 	Repository string `json:"repository,omitempty"`
 }
 
+// DeliveryBackend selects how components are delivered to the cluster
+type DeliveryBackend string
+
+const (
+	// BackendDirect creates and updates the rendered templates directly
+	BackendDirect DeliveryBackend = "Direct"
+	// BackendPorch delivers components that reference a Porch package through PackageVariants
+	BackendPorch DeliveryBackend = "Porch"
+	// BackendFlux creates a Flux Kustomization per group that depends on the previous group
+	BackendFlux DeliveryBackend = "Flux"
+)
+
+// FluxBackendSpec defines where Flux finds the rendered manifests of a bundle
+type FluxBackendSpec struct {
+	// SourceRef is the Flux source holding the rendered manifests
+	// +kubebuilder:validation:Required
+	SourceRef FluxSourceReference `json:"sourceRef"`
+
+	// Path to the bundle's manifests within the source
+	// The manifests of each group are read from <path>/<group name>
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// Namespace the Kustomizations are created in
+	// Defaults to the namespace of the AppBundle
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Interval at which Flux reconciles the Kustomizations
+	// +kubebuilder:default="5m"
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// Prune makes Flux delete resources that were removed from the source
+	// +optional
+	Prune bool `json:"prune,omitempty"`
+}
+
+// FluxSourceReference identifies a Flux source
+type FluxSourceReference struct {
+	// Kind of the source
+	// +kubebuilder:validation:Enum=GitRepository;OCIRepository;Bucket
+	// +kubebuilder:default=GitRepository
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// Name of the source
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Namespace of the source
+	// Defaults to the namespace of the Kustomizations. Other namespaces are only allowed when
+	// the operator runs with --allow-cross-namespace-sources
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// ArgoCDIntegrationSpec defines configuration for the Argo CD Application of a bundle
+type ArgoCDIntegrationSpec struct {
+	// Enabled determines if the Argo CD Application is created
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Namespace the Application is created in
+	// +kubebuilder:default=argocd
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Project is the Argo CD project the Application belongs to
+	// +kubebuilder:default=default
+	// +optional
+	Project string `json:"project,omitempty"`
+
+	// RepoURL is the Git repository holding the bundle's rendered manifests
+	// Defaults to the repository of the Porch downstream Repository when Porch integration is enabled
+	// +optional
+	RepoURL string `json:"repoURL,omitempty"`
+
+	// Path to the manifests within the repository
+	// Defaults to the directory of the Porch downstream Repository, or the repository root
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// TargetRevision is the branch, tag or commit Argo CD syncs
+	// Defaults to the branch of the Porch downstream Repository, or HEAD
+	// +optional
+	TargetRevision string `json:"targetRevision,omitempty"`
+
+	// DestinationServer is the API server Argo CD deploys to
+	// +kubebuilder:default="https://kubernetes.default.svc"
+	// +optional
+	DestinationServer string `json:"destinationServer,omitempty"`
+
+	// DestinationNamespace is the default namespace for namespaced resources
+	// Defaults to the namespace of the AppBundle
+	// +optional
+	DestinationNamespace string `json:"destinationNamespace,omitempty"`
+
+	// AutomatedSync enables automated sync with pruning and self-heal
+	// +optional
+	AutomatedSync bool `json:"automatedSync,omitempty"`
+}
+
 // DeploymentPhase represents the current phase of deployment
 type DeploymentPhase string
 
@@ -124,6 +951,13 @@ const (
 	PhaseDeployed DeploymentPhase = "Deployed"
 	// PhaseFailed means deployment encountered an error
 	PhaseFailed DeploymentPhase = "Failed"
+	// PhaseAwaitingApproval means deployment stopped before a group that requires manual approval
+	PhaseAwaitingApproval DeploymentPhase = "AwaitingApproval"
+	// PhaseRolledBack means deployment failed and the last successfully deployed
+	// manifests were re-applied
+	PhaseRolledBack DeploymentPhase = "RolledBack"
+	// PhasePlanned means the bundle is in Plan mode and its intended changes were reported
+	PhasePlanned DeploymentPhase = "Planned"
 )
 
 // GroupStatus represents the status of a group
@@ -138,6 +972,19 @@ type GroupStatus struct {
 	// +optional
 	Message string `json:"message,omitempty"`
 
+	// StartedAt is the time deployment of the group started
+	// +optional
+	StartedAt *metav1.Time `json:"startedAt,omitempty"`
+
+	// CompletedAt is the time deployment of the group finished or failed
+	// +optional
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+
+	// SpecHash is the hash of the bundle spec the group was deployed from. A group deployed
+	// from the current spec is not deployed again by later reconciliations.
+	// +optional
+	SpecHash string `json:"specHash,omitempty"`
+
 	// ComponentStatuses contains status for each component
 	// +optional
 	ComponentStatuses []ComponentStatus `json:"componentStatuses,omitempty"`
@@ -158,6 +1005,117 @@ type ComponentStatus struct {
 	// ResourceRef references the deployed resource
 	// +optional
 	ResourceRef *ResourceReference `json:"resourceRef,omitempty"`
+
+	// Resources lists every deployed resource of a component that renders to more than
+	// one object, such as a Helm chart, in apply order
+	// +optional
+	Resources []ResourceReference `json:"resources,omitempty"`
+
+	// StartedAt is the time deployment of the component started
+	// +optional
+	StartedAt *metav1.Time `json:"startedAt,omitempty"`
+
+	// CompletedAt is the time deployment of the component finished or failed
+	// +optional
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+}
+
+// DriftedResource describes a deployed resource that differs from its template
+type DriftedResource struct {
+	ResourceReference `json:",inline"`
+
+	// Group the resource belongs to
+	Group string `json:"group"`
+
+	// Component the resource belongs to
+	Component string `json:"component"`
+
+	// Fields lists the paths of the fields that differ, or "<missing>" if the resource was deleted
+	// +optional
+	Fields []string `json:"fields,omitempty"`
+}
+
+// PlanAction is the change a deployment would make to an object
+type PlanAction string
+
+const (
+	// PlanActionCreate means the object does not exist and would be created
+	PlanActionCreate PlanAction = "Create"
+	// PlanActionUpdate means the object exists and differs from its template
+	PlanActionUpdate PlanAction = "Update"
+	// PlanActionNoOp means the object already matches its template
+	PlanActionNoOp PlanAction = "NoOp"
+	// PlanActionPrune means the object was deployed by the current revision and is no longer rendered
+	PlanActionPrune PlanAction = "Prune"
+)
+
+// PlannedObject describes the change a deployment would make to one object
+type PlannedObject struct {
+	ResourceReference `json:",inline"`
+
+	// Group the object belongs to
+	Group string `json:"group"`
+
+	// Component the object belongs to
+	Component string `json:"component"`
+
+	// Action the deployment would take
+	Action PlanAction `json:"action"`
+
+	// Fields lists the paths of the fields an update would change
+	// +optional
+	Fields []string `json:"fields,omitempty"`
+
+	// Unknown lists the paths of the fields that depend on outputs of components that are
+	// not deployed yet, whose values are only known once the deployment reaches them
+	// +optional
+	Unknown []string `json:"unknown,omitempty"`
+
+	// Message reports why the server-side dry-run rejected the object
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// PlanStatus reports the changes a deployment of the current spec would make
+type PlanStatus struct {
+	// ObservedGeneration is the generation of the spec the plan was computed for
+	ObservedGeneration int64 `json:"observedGeneration"`
+
+	// PlannedAt is the time the plan was computed
+	PlannedAt metav1.Time `json:"plannedAt"`
+
+	// Create is the number of objects that would be created
+	Create int32 `json:"create"`
+
+	// Update is the number of objects that would be updated
+	Update int32 `json:"update"`
+
+	// NoOp is the number of objects that are already up to date
+	NoOp int32 `json:"noOp"`
+
+	// Prune is the number of objects that would be deleted
+	Prune int32 `json:"prune"`
+
+	// Objects lists the planned change for every object
+	// +optional
+	Objects []PlannedObject `json:"objects,omitempty"`
+
+	// DiffConfigMap is the ConfigMap in the bundle's namespace holding the full diff of every object
+	// +optional
+	DiffConfigMap string `json:"diffConfigMap,omitempty"`
+}
+
+// GroupApproval records the approval of a manual group for a spec generation
+type GroupApproval struct {
+	// Name of the approved group
+	Name string `json:"name"`
+
+	// Generation of the AppBundle spec the approval applies to
+	Generation int64 `json:"generation"`
+
+	// ApprovedAt is the time the approval was recorded
+	// +optional
+	ApprovedAt metav1.Time `json:"approvedAt,omitempty"`
 }
 
 // ResourceReference contains information about a deployed resource
@@ -176,6 +1134,33 @@ type ResourceReference struct {
 	Namespace string `json:"namespace,omitempty"`
 }
 
+// InventoryEntry records an object created for a bundle, so that it can be deleted when the
+// bundle is
+type InventoryEntry struct {
+	ResourceReference `json:",inline"`
+
+	// Group the object was deployed by
+	// +optional
+	Group string `json:"group,omitempty"`
+
+	// Component the object was deployed by
+	// +optional
+	Component string `json:"component,omitempty"`
+}
+
+// CompletedHook records a hook that ran to completion for a spec, so that it is not run
+// again until the spec changes
+type CompletedHook struct {
+	// Group of the hook
+	Group string `json:"group"`
+
+	// Component name of the hook
+	Component string `json:"component"`
+
+	// SpecHash is the hash of the spec the hook completed for
+	SpecHash string `json:"specHash"`
+}
+
 // AppBundleStatus defines the observed state of AppBundle.
 type AppBundleStatus struct {
 	// Phase is the current overall deployment phase
@@ -194,6 +1179,45 @@ type AppBundleStatus struct {
 	// +optional
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 
+	// CurrentRevision is the AppBundleRevision whose manifests are currently deployed
+	// +optional
+	CurrentRevision int64 `json:"currentRevision,omitempty"`
+
+	// DriftedResources lists deployed resources that no longer match their templates
+	// +optional
+	DriftedResources []DriftedResource `json:"driftedResources,omitempty"`
+
+	// ApprovedGroups lists the manual groups approved for deployment
+	// Approvals only apply to the generation they were given for
+	// +optional
+	ApprovedGroups []GroupApproval `json:"approvedGroups,omitempty"`
+
+	// Plan reports the changes a deployment would make while the bundle is in Plan mode
+	// +optional
+	Plan *PlanStatus `json:"plan,omitempty"`
+
+	// Template records the AppBundleTemplate generation the bundle was last rendered from
+	// +optional
+	Template *TemplateStatus `json:"template,omitempty"`
+
+	// Outputs holds the values extracted from the components that declare outputs
+	// +optional
+	Outputs []ComponentOutputs `json:"outputs,omitempty"`
+
+	// Canaries records the canary rollouts in progress
+	// +optional
+	Canaries []CanaryStatus `json:"canaries,omitempty"`
+
+	// CompletedHooks lists the PreGroup, PostGroup and PostDeploy hooks that completed for
+	// the current spec. They are not run again until the spec changes.
+	// +optional
+	CompletedHooks []CompletedHook `json:"completedHooks,omitempty"`
+
+	// Inventory lists every object applied or discovered for the bundle in deployment order.
+	// Finalization deletes exactly these objects.
+	// +optional
+	Inventory []InventoryEntry `json:"inventory,omitempty"`
+
 	// Conditions represent the latest available observations of the AppBundle's state
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
@@ -0,0 +1,86 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AppBundleRevisionSpec records a single successful rollout of an AppBundle
+// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="AppBundleRevision spec is immutable"
+type AppBundleRevisionSpec struct {
+	// BundleName is the name of the AppBundle this revision belongs to
+	// +kubebuilder:validation:Required
+	BundleName string `json:"bundleName"`
+
+	// Revision is the sequence number of this rollout, starting at 1
+	// +kubebuilder:validation:Minimum=1
+	Revision int64 `json:"revision"`
+
+	// BundleGeneration is the AppBundle generation that was deployed
+	// +optional
+	BundleGeneration int64 `json:"bundleGeneration,omitempty"`
+
+	// SpecHash is the SHA-256 hash of the AppBundle spec that produced this revision
+	SpecHash string `json:"specHash"`
+
+	// DeployedAt is the time the rollout completed
+	DeployedAt metav1.Time `json:"deployedAt"`
+
+	// Manifests holds the gzip-compressed JSON of the rendered manifests, grouped in deployment order.
+	// The values of rendered Secrets are replaced by a hash and kept in the Secret named by SecretsRef.
+	Manifests []byte `json:"manifests"`
+
+	// SecretsRef names the Secret in the revision's namespace holding the values of the rendered Secrets
+	// +optional
+	SecretsRef string `json:"secretsRef,omitempty"`
+
+	// GroupStatuses records the outcome of each group and component of the rollout
+	// +optional
+	GroupStatuses []GroupStatus `json:"groupStatuses,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Bundle",type=string,JSONPath=`.spec.bundleName`
+// +kubebuilder:printcolumn:name="Revision",type=integer,JSONPath=`.spec.revision`
+// +kubebuilder:printcolumn:name="Deployed",type=date,JSONPath=`.spec.deployedAt`
+
+// AppBundleRevision is an immutable record of the manifests deployed by an AppBundle rollout
+type AppBundleRevision struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// metadata is a standard object metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty,omitzero"`
+
+	// spec holds the recorded rollout
+	// +required
+	Spec AppBundleRevisionSpec `json:"spec"`
+}
+
+// +kubebuilder:object:root=true
+
+// AppBundleRevisionList contains a list of AppBundleRevision
+type AppBundleRevisionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AppBundleRevision `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AppBundleRevision{}, &AppBundleRevisionList{})
+}
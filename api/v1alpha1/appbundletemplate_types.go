@@ -0,0 +1,73 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AppBundleTemplateSpec holds the groups and parameter schema that AppBundles referencing
+// the template are instantiated from
+type AppBundleTemplateSpec struct {
+	// Description of what the template deploys
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// Parameters declares the values bundles set through spec.values or parameter sources
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Parameters []Parameter `json:"parameters,omitempty"`
+
+	// Groups is the list of component groups every instance deploys
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:Required
+	Groups []Group `json:"groups"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Description",type=string,JSONPath=`.spec.description`
+// +kubebuilder:printcolumn:name="Generation",type=integer,JSONPath=`.metadata.generation`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// AppBundleTemplate is a reusable, parameterised set of groups that AppBundles reference
+// through spec.templateRef
+type AppBundleTemplate struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// metadata is a standard object metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty,omitzero"`
+
+	// spec holds the groups and parameters of the template
+	// +required
+	Spec AppBundleTemplateSpec `json:"spec"`
+}
+
+// +kubebuilder:object:root=true
+
+// AppBundleTemplateList contains a list of AppBundleTemplate
+type AppBundleTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AppBundleTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AppBundleTemplate{}, &AppBundleTemplateList{})
+}
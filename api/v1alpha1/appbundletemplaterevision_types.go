@@ -0,0 +1,71 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AppBundleTemplateRevisionSpec records a generation of an AppBundleTemplate that bundles
+// rendered
+// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="AppBundleTemplateRevision spec is immutable"
+type AppBundleTemplateRevisionSpec struct {
+	// TemplateName is the name of the AppBundleTemplate this revision belongs to
+	// +kubebuilder:validation:Required
+	TemplateName string `json:"templateName"`
+
+	// Generation of the template that was recorded
+	// +kubebuilder:validation:Minimum=1
+	Generation int64 `json:"generation"`
+
+	// Template is a copy of the template's spec at Generation
+	// +kubebuilder:validation:Required
+	Template AppBundleTemplateSpec `json:"template"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Template",type=string,JSONPath=`.spec.templateName`
+// +kubebuilder:printcolumn:name="Generation",type=integer,JSONPath=`.spec.generation`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// AppBundleTemplateRevision is an immutable copy of a generation of an AppBundleTemplate,
+// from which bundles pinned to that generation keep rendering after the template changes
+type AppBundleTemplateRevision struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// metadata is a standard object metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty,omitzero"`
+
+	// spec holds the recorded template generation
+	// +required
+	Spec AppBundleTemplateRevisionSpec `json:"spec"`
+}
+
+// +kubebuilder:object:root=true
+
+// AppBundleTemplateRevisionList contains a list of AppBundleTemplateRevision
+type AppBundleTemplateRevisionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AppBundleTemplateRevision `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AppBundleTemplateRevision{}, &AppBundleTemplateRevisionList{})
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:validation:XValidation:rule="!has(self.spec.porchIntegration) || !has(self.spec.porchIntegration.enabled) || !self.spec.porchIntegration.enabled",message="Porch integration is only available to AppBundles"
+// +kubebuilder:validation:XValidation:rule="!has(self.spec.argocd) || !has(self.spec.argocd.enabled) || !self.spec.argocd.enabled",message="Argo CD integration is only available to AppBundles"
+// +kubebuilder:validation:XValidation:rule="!has(self.spec.backend) || self.spec.backend != 'Porch'",message="the Porch backend is only available to AppBundles"
+// +kubebuilder:validation:XValidation:rule="!has(self.spec.backend) || self.spec.backend != 'Flux' || (has(self.spec.flux) && has(self.spec.flux.namespace) && self.spec.flux.namespace != '')",message="the Flux backend of a ClusterAppBundle requires spec.flux.namespace"
+
+// ClusterAppBundle is a cluster-scoped AppBundle for platform stacks such as CRDs, operators
+// and ClusterRoles. It owns every object it deploys, including cluster-scoped ones.
+type ClusterAppBundle struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// metadata is a standard object metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty,omitzero"`
+
+	// spec defines the desired state of ClusterAppBundle
+	// +required
+	Spec AppBundleSpec `json:"spec"`
+
+	// status defines the observed state of ClusterAppBundle
+	// +optional
+	Status AppBundleStatus `json:"status,omitempty,omitzero"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterAppBundleList contains a list of ClusterAppBundle
+type ClusterAppBundleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterAppBundle `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterAppBundle{}, &ClusterAppBundleList{})
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+	"github.com/example/appbundle-operator/internal/render"
+)
+
+// runApprove approves manual groups through the approve-group annotation
+func runApprove(ctx context.Context, c *cli, args []string, out io.Writer) error {
+	if err := expectArgs(args, 2, -1, "approve <name> <group>..."); err != nil {
+		return err
+	}
+	appBundle, err := c.getAppBundle(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	approved := map[string]bool{}
+	var groups []string
+	// Keep approvals that the controller has not consumed yet
+	for _, name := range strings.Split(appBundle.Annotations[appv1alpha1.ApproveGroupAnnotation], ",") {
+		if name = strings.TrimSpace(name); name != "" && !approved[name] {
+			approved[name] = true
+			groups = append(groups, name)
+		}
+	}
+	for _, name := range args[1:] {
+		group := findGroup(appBundle, name)
+		if group == nil {
+			return fmt.Errorf("AppBundle %s has no group %q", appBundle.Name, name)
+		}
+		if group.Approval != appv1alpha1.ApprovalManual {
+			_, _ = fmt.Fprintf(out, "Warning: group %s does not require approval\n", name)
+		}
+		if !approved[name] {
+			approved[name] = true
+			groups = append(groups, name)
+		}
+	}
+
+	if err := c.annotate(ctx, appBundle, appv1alpha1.ApproveGroupAnnotation, strings.Join(groups, ",")); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "Approved %s for generation %d of %s\n", strings.Join(args[1:], ", "), appBundle.Generation, appBundle.Name)
+	return err
+}
+
+// runRetry requests another deployment attempt of a failed or rolled back AppBundle, or of
+// one of its groups
+func runRetry(ctx context.Context, c *cli, args []string, out io.Writer) error {
+	if err := expectArgs(args, 1, 2, "retry <name> [<group>]"); err != nil {
+		return err
+	}
+	appBundle, err := c.getAppBundle(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if appBundle.Status.Phase != appv1alpha1.PhaseFailed && appBundle.Status.Phase != appv1alpha1.PhaseRolledBack {
+		return fmt.Errorf("AppBundle %s is %s, only failed or rolled back bundles can be retried",
+			appBundle.Name, appBundle.Status.Phase)
+	}
+
+	// A new value on every request, so that a retry is never mistaken for an earlier one
+	value := time.Now().UTC().Format(time.RFC3339)
+	target := appBundle.Name
+	if len(args) == 2 {
+		if findGroup(appBundle, args[1]) == nil {
+			return fmt.Errorf("AppBundle %s has no group %q", appBundle.Name, args[1])
+		}
+		value = args[1]
+		target = fmt.Sprintf("group %s of %s", args[1], appBundle.Name)
+	}
+	if err := c.annotate(ctx, appBundle, appv1alpha1.RetryAnnotation, value); err != nil {
+		return err
+	}
+	message := fmt.Sprintf("Requested retry of %s", target)
+	if failed := failedComponent(appBundle); failed != "" {
+		message += fmt.Sprintf(", which failed in %s", failed)
+	}
+	_, err = fmt.Fprintln(out, message)
+	return err
+}
+
+// annotate sets an annotation on the AppBundle, or with --cluster on the ClusterAppBundle,
+// with a merge patch
+func (c *cli) annotate(ctx context.Context, appBundle *appv1alpha1.AppBundle, key, value string) error {
+	var obj client.Object = appBundle
+	if c.cluster {
+		obj = &appv1alpha1.ClusterAppBundle{ObjectMeta: *appBundle.ObjectMeta.DeepCopy()}
+	}
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[key] = value
+	obj.SetAnnotations(annotations)
+	return c.client.Patch(ctx, obj, patch)
+}
+
+// failedComponent returns the first failed component as <group>/<component>, or an empty string
+func failedComponent(appBundle *appv1alpha1.AppBundle) string {
+	for _, groupStatus := range appBundle.Status.GroupStatuses {
+		for _, componentStatus := range groupStatus.ComponentStatuses {
+			if componentStatus.Phase == appv1alpha1.PhaseFailed {
+				return groupStatus.Name + "/" + componentStatus.Name
+			}
+		}
+	}
+	return ""
+}
+
+// findGroup returns the group with the given name, or nil
+func findGroup(appBundle *appv1alpha1.AppBundle, name string) *appv1alpha1.Group {
+	groups := render.Groups(appBundle)
+	for i := range groups {
+		if groups[i].Name == name {
+			return &groups[i]
+		}
+	}
+	return nil
+}
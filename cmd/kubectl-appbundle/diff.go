@@ -0,0 +1,76 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/example/appbundle-operator/internal/render"
+)
+
+// runDiff prints the differences between the rendered manifests and the live resources
+func runDiff(ctx context.Context, c *cli, args []string, out io.Writer) error {
+	if err := expectArgs(args, 1, 1, "diff <name>"); err != nil {
+		return err
+	}
+	appBundle, err := c.getAppBundle(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	objects, err := render.Manifests(ctx, appBundle, c.fetcher())
+	if err != nil {
+		return err
+	}
+
+	differing := 0
+	for _, desired := range objects {
+		header := fmt.Sprintf("=== %s (%s/%s)", resourceName(desired.GetKind(), desired.GetName()),
+			desired.GetLabels()[render.GroupLabel], desired.GetLabels()[render.ComponentLabel])
+
+		live := &unstructured.Unstructured{}
+		live.SetGroupVersionKind(desired.GroupVersionKind())
+		err := c.client.Get(ctx, types.NamespacedName{Name: desired.GetName(), Namespace: desired.GetNamespace()}, live)
+		if errors.IsNotFound(err) {
+			differing++
+			_, _ = fmt.Fprintf(out, "%s\n+ not deployed\n\n", header)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		diff, err := render.DiffObject(appBundle, desired, live)
+		if err != nil {
+			return err
+		}
+		if diff == "" {
+			continue
+		}
+		differing++
+		_, _ = fmt.Fprintf(out, "%s\n%s\n", header, diff)
+	}
+
+	_, err = fmt.Fprintf(out, "%d of %d object(s) differ from the rendered manifests\n", differing, len(objects))
+	return err
+}
@@ -0,0 +1,249 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// kubectl-appbundle is a kubectl plugin for inspecting and operating AppBundles
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+	"github.com/example/appbundle-operator/internal/render"
+	"github.com/example/appbundle-operator/internal/source"
+)
+
+const usage = `Inspect and operate AppBundles.
+
+Usage:
+  kubectl appbundle status <name>              Show groups and components with phases and durations
+  kubectl appbundle diff <name>                Show how live resources differ from the rendered manifests
+  kubectl appbundle render <name> | -f <file>  Print the manifests the operator applies
+  kubectl appbundle approve <name> <group>...  Approve manual groups for the current generation
+  kubectl appbundle retry <name> [<group>]     Retry a failed or rolled back AppBundle from its first group,
+                                               or only the given group and the groups after it
+
+Flags:
+  -n, --namespace string   Namespace of the AppBundle (defaults to the namespace of the current context)
+      --cluster            Operate on a ClusterAppBundle instead of an AppBundle
+      --kubeconfig string  Path to the kubeconfig file
+      --context string     Name of the kubeconfig context to use
+
+Render flags:
+  -f, --filename string    Render an AppBundle manifest from a file ("-" for stdin) without contacting a cluster
+  -o, --output-dir string  Write the manifests to a directory instead of stdout
+      --kustomization      Write one file per object and a kustomization.yaml to the output directory
+                           (one directory per group for bundles using the Flux backend)
+`
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(appv1alpha1.AddToScheme(scheme))
+}
+
+// command runs a subcommand with its positional arguments
+type command func(ctx context.Context, c *cli, args []string, out io.Writer) error
+
+var commands = map[string]command{
+	"status":  runStatus,
+	"diff":    runDiff,
+	"render":  runRender,
+	"approve": runApprove,
+	"retry":   runRetry,
+}
+
+// cli holds the options shared by all subcommands and the lazily created client
+type cli struct {
+	kubeconfig    string
+	kubeContext   string
+	namespace     string
+	cluster       bool
+	filename      string
+	outputDir     string
+	kustomization bool
+
+	client client.Client
+}
+
+func main() {
+	if err := run(context.Background(), os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, out io.Writer) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		_, err := fmt.Fprint(out, usage)
+		return err
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %q, see 'kubectl appbundle help'", args[0])
+	}
+
+	c := &cli{}
+	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.StringVar(&c.namespace, "n", "", "")
+	flags.StringVar(&c.namespace, "namespace", "", "")
+	flags.StringVar(&c.kubeconfig, "kubeconfig", "", "")
+	flags.StringVar(&c.kubeContext, "context", "", "")
+	flags.BoolVar(&c.cluster, "cluster", false, "")
+	flags.StringVar(&c.filename, "f", "", "")
+	flags.StringVar(&c.filename, "filename", "", "")
+	flags.StringVar(&c.outputDir, "o", "", "")
+	flags.StringVar(&c.outputDir, "output-dir", "", "")
+	flags.BoolVar(&c.kustomization, "kustomization", false, "")
+	positional, err := parseInterspersed(flags, args[1:])
+	if err != nil {
+		return err
+	}
+	return cmd(ctx, c, positional, out)
+}
+
+// parseInterspersed parses flags that may appear before, between or after positional arguments
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// connect builds a client from the kubeconfig, honouring the usual kubectl overrides
+func (c *cli) connect() error {
+	if c.client != nil {
+		return nil
+	}
+
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = c.kubeconfig
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules,
+		&clientcmd.ConfigOverrides{CurrentContext: c.kubeContext})
+
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	if c.namespace == "" {
+		if c.namespace, _, err = clientConfig.Namespace(); err != nil {
+			return err
+		}
+	}
+
+	k8sClient, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+	c.client = k8sClient
+	return nil
+}
+
+// fetcher returns the Fetcher for Helm charts and values. Values kept in ConfigMaps and
+// Secrets can only be read when connected to a cluster.
+func (c *cli) fetcher() render.Fetcher {
+	return source.NewFetcher(c.client)
+}
+
+// getAppBundle fetches an AppBundle from the selected namespace, or with --cluster a
+// ClusterAppBundle as the AppBundle without a namespace the operator reconciles it as
+func (c *cli) getAppBundle(ctx context.Context, name string) (*appv1alpha1.AppBundle, error) {
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+	appBundle := &appv1alpha1.AppBundle{}
+	if c.cluster {
+		clusterAppBundle := &appv1alpha1.ClusterAppBundle{}
+		if err := c.client.Get(ctx, types.NamespacedName{Name: name}, clusterAppBundle); err != nil {
+			return nil, err
+		}
+		appBundle.ObjectMeta = clusterAppBundle.ObjectMeta
+		appBundle.Spec = clusterAppBundle.Spec
+		appBundle.Status = clusterAppBundle.Status
+	} else if err := c.client.Get(ctx, types.NamespacedName{Name: name, Namespace: c.namespace}, appBundle); err != nil {
+		return nil, err
+	}
+	if err := c.resolveTemplate(ctx, appBundle); err != nil {
+		return nil, err
+	}
+	return appBundle, nil
+}
+
+// resolveTemplate reads the AppBundleTemplate of a templated bundle from the cluster, so
+// that its groups render as the operator renders them
+func (c *cli) resolveTemplate(ctx context.Context, appBundle *appv1alpha1.AppBundle) error {
+	ref := appBundle.Spec.TemplateRef
+	if ref == nil {
+		return nil
+	}
+	if err := c.connect(); err != nil {
+		return err
+	}
+	template := &appv1alpha1.AppBundleTemplate{}
+	if err := c.client.Get(ctx, types.NamespacedName{Name: ref.Name}, template); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		template = nil
+	}
+	_, err := render.ResolveTemplate(appBundle, template, func(name string, generation int64) (*appv1alpha1.AppBundleTemplateRevision, error) {
+		revision := &appv1alpha1.AppBundleTemplateRevision{}
+		err := c.client.Get(ctx, types.NamespacedName{Name: render.TemplateRevisionName(name, generation)}, revision)
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return revision, err
+	})
+	return err
+}
+
+// expectArgs checks the number of positional arguments of a subcommand
+func expectArgs(args []string, minArgs, maxArgs int, synopsis string) error {
+	if len(args) < minArgs || (maxArgs >= 0 && len(args) > maxArgs) {
+		return errors.New("usage: kubectl appbundle " + synopsis)
+	}
+	return nil
+}
+
+// resourceName formats a resource as Kind/name
+func resourceName(kind, name string) string {
+	return kind + "/" + name
+}
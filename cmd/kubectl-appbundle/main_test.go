@@ -0,0 +1,288 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+)
+
+// testBundle returns an AppBundle with two ConfigMap components in one group
+func testBundle(phase appv1alpha1.DeploymentPhase) *appv1alpha1.AppBundle {
+	configMap := func(name string) runtime.RawExtension {
+		return runtime.RawExtension{Raw: []byte(
+			`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"` + name + `"},"data":{"key":"value"}}`)}
+	}
+	return &appv1alpha1.AppBundle{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-app", Namespace: "default"},
+		Spec: appv1alpha1.AppBundleSpec{
+			Groups: []appv1alpha1.Group{{
+				Name:     "application",
+				Approval: appv1alpha1.ApprovalManual,
+				Components: []appv1alpha1.Component{
+					{Name: "settings", Template: configMap("settings")},
+					{Name: "flags", Order: 1, Template: configMap("flags")},
+				},
+			}},
+		},
+		Status: appv1alpha1.AppBundleStatus{
+			Phase: phase,
+			GroupStatuses: []appv1alpha1.GroupStatus{{
+				Name: "application",
+				ComponentStatuses: []appv1alpha1.ComponentStatus{
+					{Name: "settings", Phase: appv1alpha1.PhaseDeployed},
+					{Name: "flags", Phase: phase},
+				},
+			}},
+		},
+	}
+}
+
+// testCLI returns a cli connected to a fake client holding objects
+func testCLI(objects ...client.Object) *cli {
+	return &cli{
+		namespace: "default",
+		client:    fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(),
+	}
+}
+
+func TestParseArguments(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		positional []string
+		namespace  string
+		filename   string
+		outputDir  string
+		wantErr    bool
+	}{
+		{name: "positional only", args: []string{"my-app"}, positional: []string{"my-app"}},
+		{name: "flags before", args: []string{"-n", "prod", "my-app"}, positional: []string{"my-app"}, namespace: "prod"},
+		{name: "flags after", args: []string{"my-app", "--namespace=prod"}, positional: []string{"my-app"}, namespace: "prod"},
+		{
+			name:       "interspersed",
+			args:       []string{"my-app", "-o", "out", "group", "-f", "bundle.yaml"},
+			positional: []string{"my-app", "group"},
+			outputDir:  "out",
+			filename:   "bundle.yaml",
+		},
+		{name: "unknown flag", args: []string{"my-app", "--bogus"}, wantErr: true},
+		{name: "missing flag value", args: []string{"my-app", "-n"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &cli{}
+			flags := flag.NewFlagSet("test", flag.ContinueOnError)
+			flags.SetOutput(io.Discard)
+			flags.StringVar(&c.namespace, "n", "", "")
+			flags.StringVar(&c.namespace, "namespace", "", "")
+			flags.StringVar(&c.filename, "f", "", "")
+			flags.StringVar(&c.outputDir, "o", "", "")
+			positional, err := parseInterspersed(flags, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !slices.Equal(positional, tt.positional) {
+				t.Errorf("got positional %v, want %v", positional, tt.positional)
+			}
+			if c.namespace != tt.namespace || c.filename != tt.filename || c.outputDir != tt.outputDir {
+				t.Errorf("got namespace %q, filename %q, output dir %q", c.namespace, c.filename, c.outputDir)
+			}
+		})
+	}
+}
+
+func TestRunUsage(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantOut string
+		wantErr string
+	}{
+		{name: "no arguments", wantOut: "Usage:"},
+		{name: "help", args: []string{"help"}, wantOut: "kubectl appbundle retry <name>"},
+		{name: "unknown command", args: []string{"deploy"}, wantErr: `unknown command "deploy"`},
+		{name: "status without name", args: []string{"status"}, wantErr: "usage: kubectl appbundle status <name>"},
+		{name: "approve without group", args: []string{"approve", "my-app"}, wantErr: "usage: kubectl appbundle approve"},
+		{name: "retry with two groups", args: []string{"retry", "my-app", "application", "backend"}, wantErr: "usage: kubectl appbundle retry <name> [<group>]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := run(context.Background(), tt.args, &out)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("output %q does not contain %q", out.String(), tt.wantOut)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		live []client.Object
+		want []string
+	}{
+		{
+			name: "nothing deployed",
+			want: []string{"=== ConfigMap/settings (application/settings)\n+ not deployed", "2 of 2 object(s) differ"},
+		},
+		{
+			name: "changed value",
+			live: []client.Object{
+				&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "default"}, Data: map[string]string{"key": "edited"}},
+			},
+			want: []string{"=== ConfigMap/settings (application/settings)\n~ data.key\n- \"edited\"\n+ \"value\"", "2 of 2 object(s) differ"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			c := testCLI(append(tt.live, testBundle(appv1alpha1.PhaseDeployed))...)
+			if err := runDiff(context.Background(), c, []string{"my-app"}, &out); err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output %q does not contain %q", out.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestApprove(t *testing.T) {
+	bundle := testBundle(appv1alpha1.PhaseAwaitingApproval)
+	bundle.Annotations = map[string]string{appv1alpha1.ApproveGroupAnnotation: "earlier"}
+	c := testCLI(bundle)
+
+	var out bytes.Buffer
+	if err := runApprove(context.Background(), c, []string{"my-app", "missing"}, &out); err == nil {
+		t.Error("expected an unknown group to be rejected")
+	}
+	if err := runApprove(context.Background(), c, []string{"my-app", "application"}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	approved := &appv1alpha1.AppBundle{}
+	if err := c.client.Get(context.Background(), types.NamespacedName{Name: "my-app", Namespace: "default"}, approved); err != nil {
+		t.Fatal(err)
+	}
+	if got := approved.Annotations[appv1alpha1.ApproveGroupAnnotation]; got != "earlier,application" {
+		t.Errorf("got approvals %q, want earlier,application", got)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		phase     appv1alpha1.DeploymentPhase
+		group     string
+		wantOut   string
+		wantValue string
+		wantErr   bool
+	}{
+		{name: "failed", phase: appv1alpha1.PhaseFailed, wantOut: "Requested retry of my-app, which failed in application/flags"},
+		{name: "rolled back", phase: appv1alpha1.PhaseRolledBack, wantOut: "Requested retry of my-app\n"},
+		{
+			name:      "group",
+			phase:     appv1alpha1.PhaseFailed,
+			group:     "application",
+			wantOut:   "Requested retry of group application of my-app",
+			wantValue: "application",
+		},
+		{name: "unknown group", phase: appv1alpha1.PhaseFailed, group: "backend", wantErr: true},
+		{name: "deployed", phase: appv1alpha1.PhaseDeployed, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testCLI(testBundle(tt.phase))
+			args := []string{"my-app"}
+			if tt.group != "" {
+				args = append(args, tt.group)
+			}
+			var out bytes.Buffer
+			err := runRetry(context.Background(), c, args, &out)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("output %q does not contain %q", out.String(), tt.wantOut)
+			}
+			retried := &appv1alpha1.AppBundle{}
+			if err := c.client.Get(context.Background(), types.NamespacedName{Name: "my-app", Namespace: "default"}, retried); err != nil {
+				t.Fatal(err)
+			}
+			value := retried.Annotations[appv1alpha1.RetryAnnotation]
+			if value == "" || (tt.wantValue != "" && value != tt.wantValue) {
+				t.Errorf("got retry annotation %q, want %q", value, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestRetryClusterAppBundle(t *testing.T) {
+	bundle := testBundle(appv1alpha1.PhaseFailed)
+	clusterAppBundle := &appv1alpha1.ClusterAppBundle{
+		ObjectMeta: metav1.ObjectMeta{Name: "platform"},
+		Spec:       bundle.Spec,
+		Status:     bundle.Status,
+	}
+	c := testCLI(clusterAppBundle)
+	c.cluster = true
+
+	var out bytes.Buffer
+	if err := runRetry(context.Background(), c, []string{"platform", "application"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	retried := &appv1alpha1.ClusterAppBundle{}
+	if err := c.client.Get(context.Background(), types.NamespacedName{Name: "platform"}, retried); err != nil {
+		t.Fatal(err)
+	}
+	if got := retried.Annotations[appv1alpha1.RetryAnnotation]; got != "application" {
+		t.Errorf("got retry annotation %q, want application", got)
+	}
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"sigs.k8s.io/yaml"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+	"github.com/example/appbundle-operator/internal/render"
+)
+
+// runRender prints or writes the manifests the operator applies for an AppBundle, in
+// deployment order. With -f the AppBundle is read from a file and no cluster is contacted,
+// unless it references an AppBundleTemplate.
+func runRender(ctx context.Context, c *cli, args []string, out io.Writer) error {
+	var appBundle *appv1alpha1.AppBundle
+	var err error
+	if c.filename != "" {
+		if err := expectArgs(args, 0, 0, "render -f <file>"); err != nil {
+			return err
+		}
+		if appBundle, err = readAppBundle(c.filename); err == nil {
+			err = c.resolveTemplate(ctx, appBundle)
+		}
+	} else {
+		if err := expectArgs(args, 1, 1, "render <name> | -f <file>"); err != nil {
+			return err
+		}
+		appBundle, err = c.getAppBundle(ctx, args[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	if c.kustomization && c.outputDir == "" {
+		return errors.New("--kustomization requires --output-dir")
+	}
+	// Flux reads the manifests of each group from its own directory of the source
+	if c.kustomization && render.Backend(appBundle) == appv1alpha1.BackendFlux {
+		return writeFluxSources(ctx, c, appBundle, out)
+	}
+
+	objects, err := render.Manifests(ctx, appBundle, c.fetcher())
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case c.kustomization:
+		if err := render.WriteKustomization(c.outputDir, objects); err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "Wrote %d object(s) and %s to %s\n", len(objects), render.KustomizationFile, c.outputDir)
+		return err
+	case c.outputDir != "":
+		if err := os.MkdirAll(c.outputDir, 0o755); err != nil {
+			return err
+		}
+		path := filepath.Join(c.outputDir, appBundle.Name+".yaml")
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		if err := render.WriteManifests(file, objects); err != nil {
+			_ = file.Close()
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "Wrote %d object(s) to %s\n", len(objects), path)
+		return err
+	default:
+		return render.WriteManifests(out, objects)
+	}
+}
+
+// writeFluxSources writes the manifests of every group of a Flux bundle to <dir>/<group>,
+// together with a kustomization.yaml, so the directory can be committed to the Flux source
+func writeFluxSources(ctx context.Context, c *cli, appBundle *appv1alpha1.AppBundle, out io.Writer) error {
+	groups, err := render.Templates(ctx, appBundle, c.fetcher())
+	if err != nil {
+		return err
+	}
+	for _, group := range groups {
+		groupDir := filepath.Join(c.outputDir, group.Name)
+		if err := render.WriteKustomization(groupDir, group.Objects); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(out, "Wrote %d object(s) and %s to %s\n", len(group.Objects), render.KustomizationFile, groupDir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readAppBundle decodes an AppBundle manifest from a file, or from stdin if path is "-"
+func readAppBundle(path string) (*appv1alpha1.AppBundle, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	appBundle := &appv1alpha1.AppBundle{}
+	if err := yaml.Unmarshal(data, appBundle); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	if appBundle.Kind != "AppBundle" {
+		return nil, fmt.Errorf("%s does not contain an AppBundle (kind %q)", path, appBundle.Kind)
+	}
+	return appBundle, nil
+}
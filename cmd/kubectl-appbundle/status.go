@@ -0,0 +1,157 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+	"github.com/example/appbundle-operator/internal/render"
+)
+
+// runStatus prints the AppBundle's groups and components as a tree with phases and durations
+func runStatus(ctx context.Context, c *cli, args []string, out io.Writer) error {
+	if err := expectArgs(args, 1, 1, "status <name>"); err != nil {
+		return err
+	}
+	appBundle, err := c.getAppBundle(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	status := appBundle.Status
+	phase := status.Phase
+	if phase == "" {
+		phase = appv1alpha1.PhasePending
+	}
+	_, _ = fmt.Fprintf(out, "AppBundle:  %s/%s\n", appBundle.Namespace, appBundle.Name)
+	_, _ = fmt.Fprintf(out, "Phase:      %s\n", phase)
+	if status.Message != "" {
+		_, _ = fmt.Fprintf(out, "Message:    %s\n", status.Message)
+	}
+	_, _ = fmt.Fprintf(out, "Generation: %d (observed %d)\n", appBundle.Generation, status.ObservedGeneration)
+	if status.CurrentRevision > 0 {
+		_, _ = fmt.Fprintf(out, "Revision:   %d\n", status.CurrentRevision)
+	}
+	if appBundle.Spec.Suspend {
+		_, _ = fmt.Fprintln(out, "Suspended:  true")
+	}
+	for _, condition := range status.Conditions {
+		_, _ = fmt.Fprintf(out, "Condition:  %s=%s (%s)\n", condition.Type, condition.Status, condition.Reason)
+	}
+	for _, drifted := range status.DriftedResources {
+		_, _ = fmt.Fprintf(out, "Drifted:    %s in %s/%s %v\n",
+			resourceName(drifted.Kind, drifted.Name), drifted.Group, drifted.Component, drifted.Fields)
+	}
+	_, _ = fmt.Fprintln(out)
+
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "NAME\tPHASE\tDURATION\tDETAILS")
+	now := time.Now()
+
+	groups := append([]appv1alpha1.Group{}, render.Groups(appBundle)...)
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].Order < groups[j].Order })
+	for i, group := range groups {
+		groupPrefix, childPrefix := "├── ", "│   "
+		if i == len(groups)-1 {
+			groupPrefix, childPrefix = "└── ", "    "
+		}
+
+		groupStatus := findGroupStatus(status.GroupStatuses, group.Name)
+		groupPhase := appv1alpha1.PhasePending
+		var groupDuration, details string
+		if groupStatus != nil {
+			groupPhase = groupStatus.Phase
+			groupDuration = formatDuration(groupStatus.StartedAt, groupStatus.CompletedAt, now)
+			if groupPhase == appv1alpha1.PhaseFailed || groupPhase == appv1alpha1.PhaseAwaitingApproval {
+				details = groupStatus.Message
+			}
+		}
+		if group.Approval == appv1alpha1.ApprovalManual && details == "" {
+			details = "manual approval"
+		}
+		_, _ = fmt.Fprintf(writer, "%s%s\t%s\t%s\t%s\n", groupPrefix, group.Name, groupPhase, groupDuration, details)
+
+		components := append([]appv1alpha1.Component{}, group.Components...)
+		sort.SliceStable(components, func(i, j int) bool { return components[i].Order < components[j].Order })
+		for j, component := range components {
+			componentPrefix := "├── "
+			if j == len(components)-1 {
+				componentPrefix = "└── "
+			}
+
+			componentPhase := appv1alpha1.PhasePending
+			var componentDuration, componentDetails string
+			if componentStatus := findComponentStatus(groupStatus, component.Name); componentStatus != nil {
+				componentPhase = componentStatus.Phase
+				componentDuration = formatDuration(componentStatus.StartedAt, componentStatus.CompletedAt, now)
+				if componentStatus.ResourceRef != nil {
+					componentDetails = resourceName(componentStatus.ResourceRef.Kind, componentStatus.ResourceRef.Name)
+				}
+				if componentPhase == appv1alpha1.PhaseFailed {
+					componentDetails = componentStatus.Message
+				}
+			}
+			_, _ = fmt.Fprintf(writer, "%s%s%s\t%s\t%s\t%s\n",
+				childPrefix, componentPrefix, component.Name, componentPhase, componentDuration, componentDetails)
+		}
+	}
+	return writer.Flush()
+}
+
+// findGroupStatus returns the status of a group, or nil if it has not been deployed
+func findGroupStatus(statuses []appv1alpha1.GroupStatus, name string) *appv1alpha1.GroupStatus {
+	for i := range statuses {
+		if statuses[i].Name == name {
+			return &statuses[i]
+		}
+	}
+	return nil
+}
+
+// findComponentStatus returns the status of a component, or nil if it has not been deployed
+func findComponentStatus(groupStatus *appv1alpha1.GroupStatus, name string) *appv1alpha1.ComponentStatus {
+	if groupStatus == nil {
+		return nil
+	}
+	for i := range groupStatus.ComponentStatuses {
+		if groupStatus.ComponentStatuses[i].Name == name {
+			return &groupStatus.ComponentStatuses[i]
+		}
+	}
+	return nil
+}
+
+// formatDuration returns how long a step took, or has been running if it has not completed
+func formatDuration(started, completed *metav1.Time, now time.Time) string {
+	if started == nil {
+		return "-"
+	}
+	end := now
+	if completed != nil {
+		end = completed.Time
+	}
+	// Status timestamps have a resolution of one second
+	return end.Sub(started.Time).Round(time.Second).String()
+}
@@ -39,6 +39,7 @@ import (
 
 	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
 	"github.com/example/appbundle-operator/internal/controller"
+	"github.com/example/appbundle-operator/internal/source"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -63,6 +64,9 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var metricsLabelDetail string
+	var clusterBundleNamespace string
+	var allowCrossNamespaceSources bool
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -81,6 +85,13 @@ func main() {
 	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&metricsLabelDetail, "metrics-label-detail", string(controller.MetricsLabelDetailComponent),
+		"Labels attached to AppBundle component metrics: component, group or bundle. "+
+			"Lower detail levels reduce the number of time series on large clusters.")
+	flag.StringVar(&clusterBundleNamespace, "cluster-bundle-namespace", "appbundle-operator-system",
+		"The namespace holding the revisions of ClusterAppBundles and the ConfigMaps and Secrets they reference.")
+	flag.BoolVar(&allowCrossNamespaceSources, "allow-cross-namespace-sources", false,
+		"Allow kustomize components to read Flux sources outside the namespace of their AppBundle.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -89,6 +100,12 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	labelDetail, err := controller.ParseMetricsLabelDetail(metricsLabelDetail)
+	if err != nil {
+		setupLog.Error(err, "invalid --metrics-label-detail")
+		os.Exit(1)
+	}
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -202,13 +219,34 @@ func main() {
 		os.Exit(1)
 	}
 
+	var fetcherOptions []source.Option
+	if allowCrossNamespaceSources {
+		fetcherOptions = append(fetcherOptions, source.WithCrossNamespaceSources())
+	}
 	if err := (&controller.AppBundleReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("appbundle-controller"),
+
+		MetricsLabelDetail: labelDetail,
+		// Values are read directly so Secrets are not cached cluster-wide
+		Fetcher: source.NewFetcher(mgr.GetAPIReader(), fetcherOptions...),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "AppBundle")
 		os.Exit(1)
 	}
+	if err := (&controller.ClusterAppBundleReconciler{AppBundleReconciler: controller.AppBundleReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("clusterappbundle-controller"),
+
+		MetricsLabelDetail: labelDetail,
+		Fetcher:            source.NewFetcher(mgr.GetAPIReader(), fetcherOptions...),
+		ClusterNamespace:   clusterBundleNamespace,
+	}}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ClusterAppBundle")
+		os.Exit(1)
+	}
 	// +kubebuilder:scaffold:builder
 
 	if metricsCertWatcher != nil {
@@ -0,0 +1,105 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package analysis evaluates the metric gates of canary rollouts against a metrics provider
+package analysis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+)
+
+// Provider answers metric queries with a single value
+type Provider interface {
+	Query(ctx context.Context, query string) (float64, error)
+}
+
+// ProviderFunc creates the Provider described by a canary analysis
+type ProviderFunc func(provider appv1alpha1.MetricProvider) (Provider, error)
+
+// NewProvider creates a Provider for a provider type
+func NewProvider(provider appv1alpha1.MetricProvider) (Provider, error) {
+	switch provider.Type {
+	case "", appv1alpha1.MetricProviderPrometheus:
+		return NewPrometheus(provider.Address), nil
+	default:
+		return nil, fmt.Errorf("unsupported metric provider %q", provider.Type)
+	}
+}
+
+// Result is the outcome of a metric gate
+type Result struct {
+	Metric    string
+	Value     float64
+	Succeeded bool
+}
+
+// Evaluate queries every metric and checks its success condition. Queries and conditions
+// that fail to run are returned as errors, a value that misses its condition is not.
+func Evaluate(ctx context.Context, provider Provider, metrics []appv1alpha1.AnalysisMetric) ([]Result, error) {
+	results := make([]Result, 0, len(metrics))
+	for _, metric := range metrics {
+		value, err := provider.Query(ctx, metric.Query)
+		if err != nil {
+			return results, fmt.Errorf("metric %s: %w", metric.Name, err)
+		}
+		succeeded, err := checkCondition(metric.SuccessCondition, value)
+		if err != nil {
+			return results, fmt.Errorf("metric %s: %w", metric.Name, err)
+		}
+		results = append(results, Result{Metric: metric.Name, Value: value, Succeeded: succeeded})
+	}
+	return results, nil
+}
+
+// Failed returns the first result that missed its condition
+func Failed(results []Result) (Result, bool) {
+	for _, result := range results {
+		if !result.Succeeded {
+			return result, true
+		}
+	}
+	return Result{}, false
+}
+
+// checkCondition evaluates a CEL success condition with the query value bound to result
+func checkCondition(condition string, value float64) (bool, error) {
+	env, err := cel.NewEnv(cel.Variable("result", cel.DoubleType))
+	if err != nil {
+		return false, err
+	}
+	ast, issues := env.Compile(condition)
+	if issues != nil && issues.Err() != nil {
+		return false, fmt.Errorf("invalid success condition %q: %w", condition, issues.Err())
+	}
+	if ast.OutputType() != cel.BoolType {
+		return false, fmt.Errorf("success condition %q must return a bool", condition)
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		return false, err
+	}
+	out, _, err := program.Eval(map[string]interface{}{"result": value})
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate %q: %w", condition, err)
+	}
+	return out == types.True, nil
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package analysis
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+)
+
+const errorRatio = `sum(rate(http_requests_total{code=~"5.."}[1m])) / sum(rate(http_requests_total[1m]))`
+
+func TestPrometheusQuery(t *testing.T) {
+	standIn := NewStandIn(map[string]float64{errorRatio: 0.02})
+	server := httptest.NewServer(standIn)
+	defer server.Close()
+
+	provider, err := NewProvider(appv1alpha1.MetricProvider{Address: server.URL + "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := provider.Query(context.Background(), errorRatio)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != 0.02 {
+		t.Errorf("got %v, want 0.02", value)
+	}
+
+	standIn.Set(errorRatio, 0.5)
+	if value, _ = provider.Query(context.Background(), errorRatio); value != 0.5 {
+		t.Errorf("got %v after Set, want 0.5", value)
+	}
+
+	if _, err := provider.Query(context.Background(), "up"); err == nil || !strings.Contains(err.Error(), "0 samples") {
+		t.Errorf("expected an error for a query without samples, got %v", err)
+	}
+	if _, err := NewPrometheus("http://127.0.0.1:1").Query(context.Background(), "up"); err == nil {
+		t.Error("expected an error for an unreachable server")
+	}
+	if _, err := NewProvider(appv1alpha1.MetricProvider{Type: "Datadog"}); err == nil {
+		t.Error("expected an error for an unsupported provider")
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	standIn := NewStandIn(map[string]float64{errorRatio: 0.02, "latency": 0.8})
+	server := httptest.NewServer(standIn)
+	defer server.Close()
+	provider := NewPrometheus(server.URL)
+
+	metrics := []appv1alpha1.AnalysisMetric{
+		{Name: "error-ratio", Query: errorRatio, SuccessCondition: "result < 0.05"},
+		{Name: "latency", Query: "latency", SuccessCondition: "result <= 0.5"},
+	}
+	results, err := Evaluate(context.Background(), provider, metrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || !results[0].Succeeded || results[1].Succeeded {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	if failed, ok := Failed(results); !ok || failed.Metric != "latency" || failed.Value != 0.8 {
+		t.Errorf("expected latency to fail, got %+v", failed)
+	}
+
+	standIn.Set("latency", 0.3)
+	results, err = Evaluate(context.Background(), provider, metrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Failed(results); ok {
+		t.Errorf("expected every metric to succeed, got %+v", results)
+	}
+
+	for _, condition := range []string{"result <", "result + 1", "missing > 1"} {
+		_, err := Evaluate(context.Background(), provider, []appv1alpha1.AnalysisMetric{
+			{Name: "invalid", Query: errorRatio, SuccessCondition: condition},
+		})
+		if err == nil {
+			t.Errorf("expected condition %q to be rejected", condition)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Prometheus queries the HTTP API of Prometheus or a compatible server, such as Thanos or
+// Mimir, for instant values
+type Prometheus struct {
+	Address string
+	Client  *http.Client
+}
+
+// NewPrometheus creates a Prometheus provider for a server address
+func NewPrometheus(address string) *Prometheus {
+	return &Prometheus{Address: strings.TrimSuffix(address, "/"), Client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// queryResponse is the envelope of /api/v1/query
+type queryResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+// sample is an element of a vector result
+type sample struct {
+	Metric map[string]string `json:"metric"`
+	Value  []interface{}     `json:"value"`
+}
+
+// Query runs an instant query. Scalars return their value, vectors must hold exactly one
+// sample.
+func (p *Prometheus) Query(ctx context.Context, query string) (float64, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		p.Address+"/api/v1/query?"+url.Values{"query": {query}}.Encode(), nil)
+	if err != nil {
+		return 0, err
+	}
+	response, err := p.Client.Do(request)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query %s: %w", p.Address, err)
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	var body queryResponse
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("invalid response from %s (HTTP %d): %w", p.Address, response.StatusCode, err)
+	}
+	if body.Status != "success" {
+		return 0, fmt.Errorf("query %q failed: %s: %s", query, body.ErrorType, body.Error)
+	}
+
+	switch body.Data.ResultType {
+	case "scalar":
+		var value []interface{}
+		if err := json.Unmarshal(body.Data.Result, &value); err != nil {
+			return 0, err
+		}
+		return sampleValue(value)
+	case "vector":
+		var samples []sample
+		if err := json.Unmarshal(body.Data.Result, &samples); err != nil {
+			return 0, err
+		}
+		if len(samples) != 1 {
+			return 0, fmt.Errorf("query %q returned %d samples, expected 1", query, len(samples))
+		}
+		return sampleValue(samples[0].Value)
+	default:
+		return 0, fmt.Errorf("query %q returned unsupported result type %q", query, body.Data.ResultType)
+	}
+}
+
+// sampleValue parses a [timestamp, "value"] pair
+func sampleValue(value []interface{}) (float64, error) {
+	if len(value) != 2 {
+		return 0, fmt.Errorf("malformed sample %v", value)
+	}
+	text, ok := value[1].(string)
+	if !ok {
+		return 0, fmt.Errorf("malformed sample value %v", value[1])
+	}
+	return strconv.ParseFloat(text, 64)
+}
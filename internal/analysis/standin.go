@@ -0,0 +1,78 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package analysis
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// StandIn is a Prometheus-compatible query endpoint answering from fixed values. It serves
+// /api/v1/query for tests and local runs without a metrics backend; unknown queries return
+// an empty vector.
+type StandIn struct {
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewStandIn creates a StandIn answering the given queries
+func NewStandIn(values map[string]float64) *StandIn {
+	s := &StandIn{values: map[string]float64{}}
+	for query, value := range values {
+		s.values[query] = value
+	}
+	return s
+}
+
+// Set changes the value returned for a query
+func (s *StandIn) Set(query string, value float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[query] = value
+}
+
+// ServeHTTP answers instant queries in the Prometheus response format
+func (s *StandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Path != "/api/v1/query" {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"status": "error", "errorType": "not_found", "error": "unknown path " + r.URL.Path,
+		})
+		return
+	}
+
+	query := r.FormValue("query")
+	s.mu.Lock()
+	value, found := s.values[query]
+	s.mu.Unlock()
+
+	result := []interface{}{}
+	if found {
+		result = append(result, map[string]interface{}{
+			"metric": map[string]string{},
+			"value":  []interface{}{float64(time.Now().Unix()), strconv.FormatFloat(value, 'f', -1, 64)},
+		})
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "success",
+		"data":   map[string]interface{}{"resultType": "vector", "result": result},
+	})
+}
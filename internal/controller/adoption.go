@@ -0,0 +1,152 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+	"github.com/example/appbundle-operator/internal/render"
+)
+
+const (
+	// conditionOwnershipConflict reports an object the bundle refused to take over
+	conditionOwnershipConflict = "OwnershipConflict"
+	// helmReleaseAnnotation names the Helm release that installed an object
+	helmReleaseAnnotation = "meta.helm.sh/release-name"
+)
+
+// ownershipConflictError is returned when applying an object that the bundle may not adopt
+type ownershipConflictError struct {
+	kind, name, owner string
+}
+
+func (e *ownershipConflictError) Error() string {
+	if e.owner == "" {
+		return fmt.Sprintf("%s %s already exists and adoptionPolicy is Never", e.kind, e.name)
+	}
+	return fmt.Sprintf("%s %s is owned by %s", e.kind, e.name, e.owner)
+}
+
+// objectOwnership reports whether an existing object belongs to the bundle and otherwise
+// describes its owner, which is empty for objects nobody claims
+func (r *AppBundleReconciler) objectOwnership(ctx context.Context, appBundle *appv1alpha1.AppBundle, existing *unstructured.Unstructured) (bool, string, error) {
+	if inInventory(appBundle, existing) {
+		return true, "", nil
+	}
+	owner := bundleOwner(appBundle)
+	for _, ref := range existing.GetOwnerReferences() {
+		if ref.UID == owner.GetUID() {
+			return true, "", nil
+		}
+	}
+	if ref := metav1.GetControllerOf(existing); ref != nil {
+		return false, fmt.Sprintf("%s %s", ref.Kind, ref.Name), nil
+	}
+	// Objects outside the bundle's namespace carry no owner reference, only the labels
+	labels := existing.GetLabels()
+	if name, found := labels[render.AppBundleLabel]; found {
+		namespace, recorded := labels[render.AppBundleNamespaceLabel]
+		if !recorded {
+			// Objects labelled before the namespace was recorded belong to the bundle of that
+			// name in their own namespace if there is one, and otherwise to the bundle of that
+			// name that applies them. Applying the object records the namespace.
+			namespace = existing.GetNamespace()
+			if name == appBundle.Name && namespace != appBundle.Namespace {
+				exists, err := r.bundleExists(ctx, namespace, name)
+				if err != nil {
+					return false, "", err
+				}
+				if !exists {
+					return true, "", nil
+				}
+			}
+		}
+		if name == appBundle.Name && namespace == appBundle.Namespace {
+			return true, "", nil
+		}
+		if namespace == "" {
+			return false, fmt.Sprintf("ClusterAppBundle %s", name), nil
+		}
+		return false, fmt.Sprintf("AppBundle %s/%s", namespace, name), nil
+	}
+	if release := existing.GetAnnotations()[helmReleaseAnnotation]; release != "" {
+		return false, fmt.Sprintf("Helm release %s", release), nil
+	}
+	return false, "", nil
+}
+
+// bundleExists reports whether an AppBundle, or a ClusterAppBundle for an empty namespace,
+// exists
+func (r *AppBundleReconciler) bundleExists(ctx context.Context, namespace, name string) (bool, error) {
+	var bundle client.Object = &appv1alpha1.AppBundle{}
+	if namespace == "" {
+		bundle = &appv1alpha1.ClusterAppBundle{}
+	}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, bundle)
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// checkAdoption decides whether an existing object may be overwritten by the bundle under
+// the adoption policy of its component. A refusal is recorded in the OwnershipConflict
+// condition and returned as an ownershipConflictError.
+func (r *AppBundleReconciler) checkAdoption(ctx context.Context, appBundle *appv1alpha1.AppBundle, obj, existing *unstructured.Unstructured) error {
+	owned, owner, err := r.objectOwnership(ctx, appBundle, existing)
+	if err != nil || owned {
+		return err
+	}
+
+	name := inventoryName(appv1alpha1.InventoryEntry{ResourceReference: appv1alpha1.ResourceReference{
+		Name: existing.GetName(), Namespace: existing.GetNamespace(),
+	}})
+	component, _ := specComponent(appBundle, obj.GetLabels()[render.GroupLabel], obj.GetLabels()[render.ComponentLabel])
+	policy := render.AdoptionPolicy(appBundle, component)
+	if policy != appv1alpha1.AdoptionPolicyForce && (owner != "" || policy == appv1alpha1.AdoptionPolicyNever) {
+		conflict := &ownershipConflictError{kind: existing.GetKind(), name: name, owner: owner}
+		r.Recorder.Eventf(appBundle, corev1.EventTypeWarning, reasonOwnershipConflict, "%v", conflict)
+		meta.SetStatusCondition(&appBundle.Status.Conditions, metav1.Condition{
+			Type:               conditionOwnershipConflict,
+			Status:             metav1.ConditionTrue,
+			Reason:             reasonOwnershipConflict,
+			Message:            conflict.Error(),
+			ObservedGeneration: appBundle.Generation,
+		})
+		return conflict
+	}
+
+	log.FromContext(ctx).Info("Adopting existing resource", "kind", existing.GetKind(), "name", name, "previousOwner", owner)
+	if owner == "" {
+		r.Recorder.Eventf(appBundle, corev1.EventTypeNormal, reasonResourceAdopted, "Adopted %s %s", existing.GetKind(), name)
+	} else {
+		r.Recorder.Eventf(appBundle, corev1.EventTypeWarning, reasonResourceAdopted,
+			"Took over %s %s from %s", existing.GetKind(), name, owner)
+	}
+	return nil
+}
@@ -20,10 +20,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"sort"
-	"strconv"
+	"slices"
+	"sync"
 	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -31,24 +33,95 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+	"github.com/example/appbundle-operator/internal/analysis"
+	"github.com/example/appbundle-operator/internal/render"
+	"github.com/example/appbundle-operator/internal/source"
 )
 
 const (
 	appBundleFinalizer = "app.example.com/finalizer"
-	// Argo CD sync wave annotation
-	argoSyncWaveAnnotation = "argocd.argoproj.io/sync-wave"
+)
+
+// Event reasons recorded on the AppBundle during its lifecycle
+const (
+	reasonGroupStarted             = "GroupStarted"
+	reasonGroupCompleted           = "GroupCompleted"
+	reasonGroupFailed              = "GroupFailed"
+	reasonComponentApplied         = "ComponentApplied"
+	reasonComponentReady           = "ComponentReady"
+	reasonComponentFailed          = "ComponentFailed"
+	reasonReadinessTimeout         = "ReadinessTimeout"
+	reasonPackageVariantCreated    = "PackageVariantCreated"
+	reasonKustomizationApplied     = "KustomizationApplied"
+	reasonFinalizing               = "Finalizing"
+	reasonResourceDeleted          = "ResourceDeleted"
+	reasonResourceRetained         = "ResourceRetained"
+	reasonResourceAdopted          = "ResourceAdopted"
+	reasonOwnershipConflict        = "OwnershipConflict"
+	reasonHookStarted              = "HookStarted"
+	reasonHookSucceeded            = "HookSucceeded"
+	reasonHookFailed               = "HookFailed"
+	reasonHookSkipped              = "HookSkipped"
+	reasonDeleteFailed             = "DeleteFailed"
+	reasonDeletionTimeout          = "DeletionTimeout"
+	reasonFinalized                = "Finalized"
+	reasonRollbackStarted          = "RollbackStarted"
+	reasonRolledBack               = "RolledBack"
+	reasonRollbackFailed           = "RollbackFailed"
+	reasonRollbackSkipped          = "RollbackSkipped"
+	reasonRevisionCreated          = "RevisionCreated"
+	reasonRevisionTooLarge         = "RevisionTooLarge"
+	reasonSuspended                = "Suspended"
+	reasonResumed                  = "Resumed"
+	reasonApprovalRequired         = "ApprovalRequired"
+	reasonGroupApproved            = "GroupApproved"
+	reasonDriftDetected            = "DriftDetected"
+	reasonSelfHealed               = "SelfHealed"
+	reasonSelfHealFailed           = "SelfHealFailed"
+	reasonPlanReady                = "PlanReady"
+	reasonPlanFailed               = "PlanFailed"
+	reasonRetryRequested           = "RetryRequested"
+	reasonArgoCDApplicationCreated = "ArgoCDApplicationCreated"
+	reasonArgoCDApplicationFailed  = "ArgoCDApplicationFailed"
+	reasonInvalidParameters        = "InvalidParameters"
+	reasonTemplateUnavailable      = "TemplateUnavailable"
+	reasonTemplateChanged          = "TemplateChanged"
+	reasonUnsupported              = "Unsupported"
+	reasonCanaryStep               = "CanaryStep"
+	reasonCanaryPromoted           = "CanaryPromoted"
+	reasonCanaryAborted            = "CanaryAborted"
 )
 
 // AppBundleReconciler reconciles a AppBundle object
 type AppBundleReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	// MetricsLabelDetail limits the labels attached to component metrics.
+	// Defaults to per-component labels when empty.
+	MetricsLabelDetail MetricsLabelDetail
+
+	// Fetcher loads Helm charts and values for components.
+	// Defaults to a fetcher reading through Client when nil.
+	Fetcher     render.Fetcher
+	fetcherOnce sync.Once
+
+	// ClusterNamespace holds the revisions and plans of ClusterAppBundles and the
+	// ConfigMaps and Secrets they reference
+	ClusterNamespace string
+
+	// MetricProvider creates the providers answering the metric queries of canary analyses.
+	// Defaults to analysis.NewProvider when nil.
+	MetricProvider analysis.ProviderFunc
 }
 
 // +kubebuilder:rbac:groups=app.example.com,resources=appbundles,verbs=get;list;watch;create;update;patch;delete
@@ -58,7 +131,14 @@ type AppBundleReconciler struct {
 // +kubebuilder:rbac:groups=config.porch.kpt.dev,resources=packagevariants/status,verbs=get
 // +kubebuilder:rbac:groups=config.porch.kpt.dev,resources=repositories,verbs=get;list;watch
 // +kubebuilder:rbac:groups=config.porch.kpt.dev,resources=packagerevisions,verbs=get;list;watch
+// +kubebuilder:rbac:groups=kustomize.toolkit.fluxcd.io,resources=kustomizations,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=source.toolkit.fluxcd.io,resources=gitrepositories;ocirepositories;buckets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=argoproj.io,resources=applications,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=*,resources=*,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=app.example.com,resources=appbundlerevisions,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups=app.example.com,resources=appbundletemplates,verbs=get;list;watch
+// +kubebuilder:rbac:groups=app.example.com,resources=appbundletemplaterevisions,verbs=get;list;watch;create;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -74,16 +154,27 @@ func (r *AppBundleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		if errors.IsNotFound(err) {
 			// Object not found, could have been deleted after reconcile request
 			logger.Info("AppBundle resource not found. Ignoring since object must be deleted")
+			forgetBundleMetrics(req.Namespace, req.Name)
 			return ctrl.Result{}, nil
 		}
 		logger.Error(err, "Failed to get AppBundle")
 		return ctrl.Result{}, err
 	}
+	return r.reconcileBundle(ctx, appBundle)
+}
+
+// reconcileBundle deploys the groups of an AppBundle, or of a ClusterAppBundle reconciled
+// as an AppBundle without a namespace
+func (r *AppBundleReconciler) reconcileBundle(ctx context.Context, appBundle *appv1alpha1.AppBundle) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	// Export the phase the bundle ends up in after this reconciliation
+	defer recordBundlePhase(appBundle)
 
 	// Add finalizer if it doesn't exist
 	if !controllerutil.ContainsFinalizer(appBundle, appBundleFinalizer) {
 		controllerutil.AddFinalizer(appBundle, appBundleFinalizer)
-		if err := r.Update(ctx, appBundle); err != nil {
+		if err := r.updateBundle(ctx, appBundle); err != nil {
 			return ctrl.Result{}, err
 		}
 	}
@@ -91,28 +182,105 @@ func (r *AppBundleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	// Check if the AppBundle is being deleted
 	if !appBundle.DeletionTimestamp.IsZero() {
 		if controllerutil.ContainsFinalizer(appBundle, appBundleFinalizer) {
-			// Run finalization logic
-			if err := r.finalizeAppBundle(ctx, appBundle); err != nil {
-				return ctrl.Result{}, err
+			// The resources of a templated bundle are found through its template
+			if err := r.resolveTemplate(ctx, appBundle); err != nil {
+				logger.Error(err, "Failed to resolve AppBundleTemplate, cleaning up with the recorded one")
+				if err := r.resolveRecordedTemplate(ctx, appBundle); err != nil {
+					return ctrl.Result{}, err
+				}
+			}
+
+			// Run finalization logic, which requeues while objects are still terminating
+			if result, err := r.finalizeAppBundle(ctx, appBundle); err != nil || !result.IsZero() {
+				return result, err
+			}
+			forgetBundleMetrics(appBundle.Namespace, appBundle.Name)
+			if ref := appBundle.Spec.TemplateRef; ref != nil {
+				if err := r.pruneTemplateRevisions(ctx, ref.Name); err != nil {
+					return ctrl.Result{}, err
+				}
 			}
 
 			// Remove finalizer
 			controllerutil.RemoveFinalizer(appBundle, appBundleFinalizer)
-			if err := r.Update(ctx, appBundle); err != nil {
+			if err := r.updateBundle(ctx, appBundle); err != nil {
 				return ctrl.Result{}, err
 			}
 		}
 		return ctrl.Result{}, nil
 	}
 
+	// Leave everything untouched while the bundle is suspended
+	suspended, err := r.reconcileSuspend(ctx, appBundle)
+	if err != nil || suspended {
+		return ctrl.Result{}, err
+	}
+
+	// Record approvals for manual groups given through the approve-group annotation
+	if err := r.recordGroupApprovals(ctx, appBundle); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Clear the failure of a bundle whose retry was requested through the retry annotation
+	retryGroup, err := r.recordRetry(ctx, appBundle)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Render templated bundles from their AppBundleTemplate
+	if err := r.resolveTemplate(ctx, appBundle); err != nil {
+		logger.Error(err, "Failed to resolve AppBundleTemplate")
+		return r.rejectTemplate(ctx, appBundle, err)
+	}
+	if err := render.ValidateClusterBundle(appBundle); err != nil {
+		return r.rejectUnsupported(ctx, appBundle, err)
+	}
+
+	// Only report the intended changes while the bundle is in Plan mode
+	if appBundle.Spec.Mode == appv1alpha1.ModePlan {
+		return r.reconcilePlan(ctx, appBundle)
+	}
+	if appBundle.Status.Plan != nil {
+		if err := r.clearPlan(ctx, appBundle); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Keep the Argo CD Application in place and mirror its status
+	if err := r.reconcileArgoCD(ctx, appBundle); err != nil {
+		return ctrl.Result{}, err
+	}
+
 	// Initialize status if needed
 	if appBundle.Status.Phase == "" {
 		appBundle.Status.Phase = appv1alpha1.PhasePending
-		if err := r.Status().Update(ctx, appBundle); err != nil {
+		if err := r.updateBundleStatus(ctx, appBundle); err != nil {
 			return ctrl.Result{}, err
 		}
 	}
 
+	// A rolled back bundle is not retried until its spec changes
+	if appBundle.Status.Phase == appv1alpha1.PhaseRolledBack && appBundle.Status.ObservedGeneration == appBundle.Generation {
+		logger.Info("AppBundle was rolled back, waiting for a spec change before redeploying")
+		return ctrl.Result{}, nil
+	}
+
+	// Deploy a recorded revision instead of the groups when the bundle is pinned
+	if appBundle.Spec.RollbackTo != nil {
+		return r.reconcileRollbackTo(ctx, appBundle)
+	}
+
+	// Once the current spec is deployed, only check the deployed resources for drift
+	if appBundle.Status.Phase == appv1alpha1.PhaseDeployed && appBundle.Status.ObservedGeneration == appBundle.Generation {
+		return r.reconcileDrift(ctx, appBundle)
+	}
+
+	// Resolve parameters and template expressions before anything is applied
+	pruneOutputs(appBundle)
+	if err := render.ValidateParameters(ctx, appBundle, r.fetcher()); err != nil {
+		return r.rejectInvalidParameters(ctx, appBundle, err)
+	}
+
 	// Reconcile Porch packages if integration is enabled
 	if appBundle.Spec.PorchIntegration != nil && appBundle.Spec.PorchIntegration.Enabled {
 		if err := r.reconcilePorchPackages(ctx, appBundle); err != nil {
@@ -122,30 +290,68 @@ func (r *AppBundleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	}
 
 	// Sort groups by order
-	sortedGroups := make([]appv1alpha1.Group, len(appBundle.Spec.Groups))
-	copy(sortedGroups, appBundle.Spec.Groups)
-	sort.Slice(sortedGroups, func(i, j int) bool {
-		return sortedGroups[i].Order < sortedGroups[j].Order
-	})
+	sortedGroups := render.SortedGroups(appBundle)
+	pass := newDeployPass()
+	backend := r.backendFor(appBundle, pass)
+
+	hash, err := specHash(appBundle)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
 
 	// Deploy resources group by group
 	appBundle.Status.Phase = appv1alpha1.PhaseDeploying
+	previous := appBundle.Status.GroupStatuses
 	appBundle.Status.GroupStatuses = make([]appv1alpha1.GroupStatus, 0, len(sortedGroups))
 
 	for _, group := range sortedGroups {
-		groupStatus, err := r.reconcileGroup(ctx, appBundle, group)
+		// A group retry keeps the groups deployed before the retried group, and groups
+		// deployed from the current spec, such as before an approval or a canary pause,
+		// are not deployed again
+		if kept := retriedGroupStatus(previous, group, retryGroup); kept != nil {
+			appBundle.Status.GroupStatuses = append(appBundle.Status.GroupStatuses, *kept)
+			continue
+		}
+		retryGroup = ""
+		if kept := deployedGroupStatus(previous, group, hash); kept != nil {
+			appBundle.Status.GroupStatuses = append(appBundle.Status.GroupStatuses, *kept)
+			continue
+		}
+
+		if !isGroupApproved(appBundle, group) {
+			return r.waitForApproval(ctx, appBundle, group)
+		}
+
+		groupStatus, err := backend.deployGroup(ctx, appBundle, group)
+		if pause, paused := asCanaryPause(err); paused {
+			appBundle.Status.GroupStatuses = append(appBundle.Status.GroupStatuses, groupStatus)
+			return r.waitForCanaryPause(ctx, appBundle, pause)
+		}
 		if err != nil {
 			logger.Error(err, "Failed to reconcile group", "group", group.Name)
 			appBundle.Status.GroupStatuses = append(appBundle.Status.GroupStatuses, groupStatus)
-			return r.updateStatusWithError(ctx, appBundle, err)
+			return r.handleDeploymentFailure(ctx, appBundle, group, err)
 		}
+		groupStatus.SpecHash = hash
 		appBundle.Status.GroupStatuses = append(appBundle.Status.GroupStatuses, groupStatus)
 	}
 
+	// Run the PostDeploy hooks once every group is deployed
+	if err := r.runBundleHooks(ctx, appBundle, appv1alpha1.HookPostDeploy, pass); err != nil {
+		logger.Error(err, "PostDeploy hook failed")
+		return r.updateStatusWithError(ctx, appBundle, err)
+	}
+
 	// All groups deployed successfully
 	appBundle.Status.Phase = appv1alpha1.PhaseDeployed
 	appBundle.Status.Message = "All groups deployed successfully"
 	appBundle.Status.ObservedGeneration = appBundle.Generation
+	appBundle.Status.Canaries = nil
+
+	// Every resource was just applied from its template, so any earlier drift is gone
+	appBundle.Status.DriftedResources = nil
+	meta.RemoveStatusCondition(&appBundle.Status.Conditions, conditionDrifted)
+	meta.RemoveStatusCondition(&appBundle.Status.Conditions, conditionOwnershipConflict)
 
 	// Update condition
 	meta.SetStatusCondition(&appBundle.Status.Conditions, metav1.Condition{
@@ -156,40 +362,68 @@ func (r *AppBundleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		ObservedGeneration: appBundle.Generation,
 	})
 
-	if err := r.Status().Update(ctx, appBundle); err != nil {
+	// Record the applied manifests so the rollout can be audited and rolled back
+	if err := r.recordRevision(ctx, appBundle, pass); err != nil {
+		logger.Error(err, "Failed to record AppBundleRevision")
+		return ctrl.Result{}, err
+	}
+
+	if err := r.updateBundleStatus(ctx, appBundle); err != nil {
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{}, nil
+	// Check the deployed resources for drift periodically
+	return ctrl.Result{RequeueAfter: driftInterval(appBundle)}, nil
 }
 
-// reconcileGroup reconciles a single group of components
-func (r *AppBundleReconciler) reconcileGroup(ctx context.Context, appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group) (appv1alpha1.GroupStatus, error) {
+// reconcileGroup reconciles a single group of components, deploying each with deploy
+func (r *AppBundleReconciler) reconcileGroup(ctx context.Context, appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group, deploy componentDeployer, pass *deployPass) (appv1alpha1.GroupStatus, error) {
 	logger := log.FromContext(ctx)
 
+	groupStarted := metav1.Now()
 	groupStatus := appv1alpha1.GroupStatus{
 		Name:              group.Name,
 		Phase:             appv1alpha1.PhaseDeploying,
 		ComponentStatuses: make([]appv1alpha1.ComponentStatus, 0, len(group.Components)),
+		StartedAt:         &groupStarted,
 	}
 
-	// Sort components by order
-	sortedComponents := make([]appv1alpha1.Component, len(group.Components))
-	copy(sortedComponents, group.Components)
-	sort.Slice(sortedComponents, func(i, j int) bool {
-		return sortedComponents[i].Order < sortedComponents[j].Order
-	})
+	// Sort components by order, with the group's hooks before and after them
+	pre, components, post := render.GroupComponents(group)
+	sortedComponents := slices.Concat(pre, components, post)
 
 	// Calculate base sync wave for this group
-	baseSyncWave := group.Order * 100
+	baseSyncWave := render.BaseSyncWave(group)
+
+	r.Recorder.Eventf(appBundle, corev1.EventTypeNormal, reasonGroupStarted,
+		"Deploying group %s (order %d, %d components)", group.Name, group.Order, len(sortedComponents))
 
 	for _, component := range sortedComponents {
-		componentStatus, err := r.reconcileComponent(ctx, appBundle, group, component, baseSyncWave)
+		componentStarted := metav1.Now()
+		var componentStatus appv1alpha1.ComponentStatus
+		var err error
+		if component.Hook != "" {
+			componentStatus, err = r.runHook(ctx, appBundle, group, component, baseSyncWave, pass)
+		} else {
+			componentStatus, err = deploy(ctx, appBundle, group, component, baseSyncWave)
+		}
+		componentCompleted := metav1.Now()
+		componentStatus.StartedAt = &componentStarted
+		componentStatus.CompletedAt = &componentCompleted
+		if _, paused := asCanaryPause(err); paused {
+			componentStatus.CompletedAt = nil
+			groupStatus.Message = fmt.Sprintf("Component %s: %v", component.Name, err)
+			groupStatus.ComponentStatuses = append(groupStatus.ComponentStatuses, componentStatus)
+			return groupStatus, err
+		}
 		if err != nil {
 			logger.Error(err, "Failed to reconcile component", "group", group.Name, "component", component.Name)
 			groupStatus.Phase = appv1alpha1.PhaseFailed
 			groupStatus.Message = fmt.Sprintf("Failed to deploy component %s: %v", component.Name, err)
+			groupStatus.CompletedAt = &componentCompleted
 			groupStatus.ComponentStatuses = append(groupStatus.ComponentStatuses, componentStatus)
+			r.Recorder.Eventf(appBundle, corev1.EventTypeWarning, reasonGroupFailed,
+				"Group %s failed at component %s: %v", group.Name, component.Name, err)
 			return groupStatus, err
 		}
 		groupStatus.ComponentStatuses = append(groupStatus.ComponentStatuses, componentStatus)
@@ -197,11 +431,16 @@ func (r *AppBundleReconciler) reconcileGroup(ctx context.Context, appBundle *app
 
 	groupStatus.Phase = appv1alpha1.PhaseDeployed
 	groupStatus.Message = "All components deployed successfully"
+	groupCompleted := metav1.Now()
+	groupStatus.CompletedAt = &groupCompleted
+	r.Recorder.Eventf(appBundle, corev1.EventTypeNormal, reasonGroupCompleted,
+		"Group %s deployed successfully", group.Name)
 	return groupStatus, nil
 }
 
-// reconcileComponent reconciles a single component
-func (r *AppBundleReconciler) reconcileComponent(ctx context.Context, appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group, component appv1alpha1.Component, baseSyncWave int) (appv1alpha1.ComponentStatus, error) {
+// reconcileComponent applies the rendered objects of a single component in order and waits
+// for each to become ready. The applied objects are recorded in pass.
+func (r *AppBundleReconciler) reconcileComponent(ctx context.Context, appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group, component appv1alpha1.Component, baseSyncWave int, pass *deployPass) (appv1alpha1.ComponentStatus, error) {
 	logger := log.FromContext(ctx)
 
 	componentStatus := appv1alpha1.ComponentStatus{
@@ -209,57 +448,140 @@ func (r *AppBundleReconciler) reconcileComponent(ctx context.Context, appBundle
 		Phase: appv1alpha1.PhaseDeploying,
 	}
 
-	// If component has a Porch package reference, create PackageVariant
-	if component.PorchPackageRef != nil {
-		return r.reconcileComponentWithPorch(ctx, appBundle, group, component, baseSyncWave)
-	}
-
-	// Validate that template is provided for non-Porch components
-	if len(component.Template.Raw) == 0 {
-		componentStatus.Phase = appv1alpha1.PhaseFailed
-		componentStatus.Message = "Component must have either template or porchPackageRef specified"
-		return componentStatus, fmt.Errorf("component %s has neither template nor porchPackageRef", component.Name)
-	}
-
-	// Parse the template into an unstructured object
-	obj := &unstructured.Unstructured{}
-	if err := json.Unmarshal(component.Template.Raw, obj); err != nil {
+	objects, err := render.Component(ctx, appBundle, group, component, baseSyncWave, r.fetcher(), pass.configHashes(ctx, appBundle, r.fetcher()))
+	if err != nil {
 		componentStatus.Phase = appv1alpha1.PhaseFailed
-		componentStatus.Message = fmt.Sprintf("Failed to parse template: %v", err)
+		componentStatus.Message = err.Error()
 		return componentStatus, err
 	}
 
-	// Calculate sync wave for this component
-	syncWave := baseSyncWave + component.Order
+	var resources []appv1alpha1.ResourceReference
+	for _, obj := range objects {
+		r.setOwnerReference(ctx, appBundle, obj)
 
-	// Add Argo CD sync wave annotation
-	annotations := obj.GetAnnotations()
-	if annotations == nil {
-		annotations = make(map[string]string)
+		if render.RecreateHelmHook(obj) {
+			if err := r.deleteHelmHook(ctx, appBundle, obj); err != nil {
+				componentStatus.Phase = appv1alpha1.PhaseFailed
+				componentStatus.Message = err.Error()
+				return componentStatus, err
+			}
+		}
+
+		if usesCanary(component, obj) {
+			if err := r.runCanarySteps(ctx, appBundle, group, component, obj); err != nil {
+				if _, paused := asCanaryPause(err); paused {
+					componentStatus.Message = fmt.Sprintf("Canary rollout paused: %v", err)
+					return componentStatus, err
+				}
+				componentStatus.Phase = appv1alpha1.PhaseFailed
+				componentStatus.Message = fmt.Sprintf("Canary rollout aborted: %v", err)
+				return componentStatus, err
+			}
+		}
+
+		// Create or update the resource
+		logger.Info("Applying resource", "group", group.Name, "component", component.Name, "kind", obj.GetKind(), "name", obj.GetName())
+		applied := obj.DeepCopy()
+		operation, err := r.applyObject(ctx, appBundle, obj)
+		if err != nil {
+			componentStatus.Phase = appv1alpha1.PhaseFailed
+			componentStatus.Message = fmt.Sprintf("Failed to apply resource: %v", err)
+			r.countApplyError(appBundle, group.Name, component.Name, obj.GroupVersionKind())
+			r.Recorder.Eventf(appBundle, corev1.EventTypeWarning, reasonComponentFailed,
+				"Failed to apply %s %s for component %s: %v", obj.GetKind(), obj.GetName(), component.Name, err)
+			if usesCanary(component, obj) {
+				if abortErr := r.abortCanary(ctx, appBundle, group, component, obj); abortErr != nil {
+					return componentStatus, fmt.Errorf("%w; failed to abort canary: %v", err, abortErr)
+				}
+			}
+			return componentStatus, err
+		}
+		pass.recordApplied(group.Name, applied)
+		r.Recorder.Eventf(appBundle, corev1.EventTypeNormal, reasonComponentApplied,
+			"%s %s %s for component %s/%s", operationVerb(operation), obj.GetKind(), obj.GetName(), group.Name, component.Name)
+
+		// Wait for the resource to become ready
+		logger.Info("Waiting for resource to become ready", "kind", obj.GetKind(), "name", obj.GetName())
+		readinessStarted := time.Now()
+		if err := r.waitForResourceReady(ctx, obj); err != nil {
+			componentStatus.Phase = appv1alpha1.PhaseFailed
+			componentStatus.Message = fmt.Sprintf("Resource not ready: %v", err)
+			logger.Error(err, "Resource did not become ready", "kind", obj.GetKind(), "name", obj.GetName())
+			r.recordReadinessFailure(appBundle, group, component, obj, err)
+			r.cleanUpHelmHook(ctx, appBundle, obj, false)
+			// The stable Deployment already runs the new template, so only the canary goes
+			if usesCanary(component, obj) {
+				if _, deleteErr := r.deleteCanary(ctx, appBundle, obj); deleteErr != nil {
+					return componentStatus, fmt.Errorf("%w; failed to delete canary: %v", err, deleteErr)
+				}
+				forgetCanary(appBundle, group, component, obj)
+			}
+			return componentStatus, err
+		}
+		r.observeComponentReadiness(appBundle, group.Name, component.Name, readinessStarted)
+		r.cleanUpHelmHook(ctx, appBundle, obj, true)
+		if usesCanary(component, obj) {
+			if err := r.promoteCanary(ctx, appBundle, group, component, obj); err != nil {
+				componentStatus.Phase = appv1alpha1.PhaseFailed
+				componentStatus.Message = err.Error()
+				return componentStatus, err
+			}
+		}
+
+		logger.Info("Resource is ready", "kind", obj.GetKind(), "name", obj.GetName())
+		r.Recorder.Eventf(appBundle, corev1.EventTypeNormal, reasonComponentReady,
+			"%s %s for component %s/%s is ready", obj.GetKind(), obj.GetName(), group.Name, component.Name)
+		resources = append(resources, appv1alpha1.ResourceReference{
+			APIVersion: obj.GetAPIVersion(),
+			Kind:       obj.GetKind(),
+			Name:       obj.GetName(),
+			Namespace:  obj.GetNamespace(),
+		})
 	}
-	annotations[argoSyncWaveAnnotation] = strconv.Itoa(syncWave)
-	obj.SetAnnotations(annotations)
 
-	// Add labels for tracking
-	labels := obj.GetLabels()
-	if labels == nil {
-		labels = make(map[string]string)
+	if len(component.Outputs) > 0 {
+		if err := r.resolveOutputs(ctx, appBundle, group, component, objects); err != nil {
+			componentStatus.Phase = appv1alpha1.PhaseFailed
+			componentStatus.Message = err.Error()
+			r.Recorder.Eventf(appBundle, corev1.EventTypeWarning, reasonComponentFailed,
+				"Failed to resolve outputs of component %s/%s: %v", group.Name, component.Name, err)
+			return componentStatus, err
+		}
 	}
-	labels["app.example.com/appbundle"] = appBundle.Name
-	labels["app.example.com/group"] = group.Name
-	labels["app.example.com/component"] = component.Name
-	obj.SetLabels(labels)
 
-	// Set namespace if not specified
-	if obj.GetNamespace() == "" && appBundle.Namespace != "" {
-		obj.SetNamespace(appBundle.Namespace)
+	componentStatus.Phase = appv1alpha1.PhaseDeployed
+	componentStatus.Message = "Resource deployed successfully"
+	if len(resources) > 0 {
+		componentStatus.ResourceRef = &resources[0]
 	}
+	if len(resources) > 1 {
+		componentStatus.Message = fmt.Sprintf("%d resources deployed successfully", len(resources))
+		componentStatus.Resources = resources
+	}
+	return componentStatus, nil
+}
+
+// fetcher returns the Fetcher used to load Helm charts and values, creating one that reads
+// through the reconciler's client if none was configured
+func (r *AppBundleReconciler) fetcher() render.Fetcher {
+	r.fetcherOnce.Do(func() {
+		if r.Fetcher == nil {
+			r.Fetcher = source.NewFetcher(r.Client)
+		}
+	})
+	return r.Fetcher
+}
+
+// setOwnerReference makes the bundle the controller of obj when Kubernetes allows it
+func (r *AppBundleReconciler) setOwnerReference(ctx context.Context, appBundle *appv1alpha1.AppBundle, obj *unstructured.Unstructured) {
+	logger := log.FromContext(ctx)
 
 	// Set owner reference only if the resource is in the same namespace as the AppBundle
 	// Kubernetes doesn't allow cross-namespace owner references for security reasons
-	// Also skip for cluster-scoped resources (they have no namespace)
-	if obj.GetNamespace() != "" && obj.GetNamespace() == appBundle.Namespace {
-		if err := controllerutil.SetControllerReference(appBundle, obj, r.Scheme); err != nil {
+	// Also skip for cluster-scoped resources (they have no namespace), unless the bundle is
+	// a ClusterAppBundle, which can own any object
+	if isClusterBundle(appBundle) || (obj.GetNamespace() != "" && obj.GetNamespace() == appBundle.Namespace) {
+		if err := controllerutil.SetControllerReference(bundleOwner(appBundle), obj, r.Scheme); err != nil {
 			logger.Info("Warning: Failed to set owner reference, continuing without it",
 				"error", err,
 				"resource", obj.GetKind(),
@@ -275,8 +597,11 @@ func (r *AppBundleReconciler) reconcileComponent(ctx context.Context, appBundle
 			"namespace", obj.GetNamespace(),
 			"appBundleNamespace", appBundle.Namespace)
 	}
+}
 
-	// Create or update the resource
+// applyObject creates obj, or updates it in place if it already exists. On update, fields
+// covered by the bundle's ignoreDifferences rules keep their live values.
+func (r *AppBundleReconciler) applyObject(ctx context.Context, appBundle *appv1alpha1.AppBundle, obj *unstructured.Unstructured) (controllerutil.OperationResult, error) {
 	existingObj := &unstructured.Unstructured{}
 	existingObj.SetGroupVersionKind(obj.GroupVersionKind())
 	err := r.Get(ctx, types.NamespacedName{
@@ -285,50 +610,49 @@ func (r *AppBundleReconciler) reconcileComponent(ctx context.Context, appBundle
 	}, existingObj)
 
 	if err != nil {
-		if errors.IsNotFound(err) {
-			// Create the resource
-			logger.Info("Creating resource", "group", group.Name, "component", component.Name, "kind", obj.GetKind(), "name", obj.GetName())
-			if err := r.Create(ctx, obj); err != nil {
-				componentStatus.Phase = appv1alpha1.PhaseFailed
-				componentStatus.Message = fmt.Sprintf("Failed to create resource: %v", err)
-				return componentStatus, err
-			}
-		} else {
-			componentStatus.Phase = appv1alpha1.PhaseFailed
-			componentStatus.Message = fmt.Sprintf("Failed to get existing resource: %v", err)
-			return componentStatus, err
+		if !errors.IsNotFound(err) {
+			return controllerutil.OperationResultNone, fmt.Errorf("failed to get existing resource: %w", err)
 		}
-	} else {
-		// Update the resource
-		logger.Info("Updating resource", "group", group.Name, "component", component.Name, "kind", obj.GetKind(), "name", obj.GetName())
-		obj.SetResourceVersion(existingObj.GetResourceVersion())
-		if err := r.Update(ctx, obj); err != nil {
-			componentStatus.Phase = appv1alpha1.PhaseFailed
-			componentStatus.Message = fmt.Sprintf("Failed to update resource: %v", err)
-			return componentStatus, err
+		if err := r.Create(ctx, obj); err != nil {
+			return controllerutil.OperationResultNone, err
 		}
+		recordInventory(appBundle, obj, obj.GetLabels()[render.GroupLabel], obj.GetLabels()[render.ComponentLabel])
+		return controllerutil.OperationResultCreated, nil
 	}
 
-	// Wait for the resource to become ready
-	logger.Info("Waiting for resource to become ready", "kind", obj.GetKind(), "name", obj.GetName())
-	if err := r.waitForResourceReady(ctx, obj); err != nil {
-		componentStatus.Phase = appv1alpha1.PhaseFailed
-		componentStatus.Message = fmt.Sprintf("Resource not ready: %v", err)
-		logger.Error(err, "Resource did not become ready", "kind", obj.GetKind(), "name", obj.GetName())
-		return componentStatus, err
+	if err := r.checkAdoption(ctx, appBundle, obj, existingObj); err != nil {
+		return controllerutil.OperationResultNone, err
 	}
+	if err := render.ApplyIgnoreDifferences(appBundle, obj, existingObj); err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+	obj.SetResourceVersion(existingObj.GetResourceVersion())
+	if err := r.Update(ctx, obj); err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+	recordInventory(appBundle, obj, obj.GetLabels()[render.GroupLabel], obj.GetLabels()[render.ComponentLabel])
+	return controllerutil.OperationResultUpdated, nil
+}
 
-	componentStatus.Phase = appv1alpha1.PhaseDeployed
-	componentStatus.Message = "Resource deployed successfully"
-	componentStatus.ResourceRef = &appv1alpha1.ResourceReference{
-		APIVersion: obj.GetAPIVersion(),
-		Kind:       obj.GetKind(),
-		Name:       obj.GetName(),
-		Namespace:  obj.GetNamespace(),
+// operationVerb returns a human readable verb for an apply result
+func operationVerb(operation controllerutil.OperationResult) string {
+	if operation == controllerutil.OperationResultCreated {
+		return "Created"
 	}
+	return "Updated"
+}
 
-	logger.Info("Resource is ready", "kind", obj.GetKind(), "name", obj.GetName())
-	return componentStatus, nil
+// recordReadinessFailure emits a Warning event for a resource that did not become ready,
+// distinguishing readiness timeouts from other failures
+func (r *AppBundleReconciler) recordReadinessFailure(appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group, component appv1alpha1.Component, obj *unstructured.Unstructured, err error) {
+	if wait.Interrupted(err) {
+		r.countReadinessTimeout(appBundle, group.Name, component.Name)
+		r.Recorder.Eventf(appBundle, corev1.EventTypeWarning, reasonReadinessTimeout,
+			"Timed out waiting for %s %s of component %s to become ready", obj.GetKind(), obj.GetName(), component.Name)
+		return
+	}
+	r.Recorder.Eventf(appBundle, corev1.EventTypeWarning, reasonComponentFailed,
+		"%s %s of component %s did not become ready: %v", obj.GetKind(), obj.GetName(), component.Name, err)
 }
 
 // waitForResourceReady waits for a resource to become ready based on its kind
@@ -364,40 +688,50 @@ func (r *AppBundleReconciler) waitForResourceReady(ctx context.Context, obj *uns
 
 	// For resources that need readiness checks
 	return wait.PollUntilContextTimeout(ctx, pollInterval, timeout, true, func(ctx context.Context) (bool, error) {
-		// Fetch the latest version of the resource
-		current := &unstructured.Unstructured{}
-		current.SetGroupVersionKind(obj.GroupVersionKind())
-		err := r.Get(ctx, types.NamespacedName{
-			Name:      obj.GetName(),
-			Namespace: obj.GetNamespace(),
-		}, current)
+		return r.isResourceReady(ctx, obj)
+	})
+}
 
-		if err != nil {
-			if errors.IsNotFound(err) {
-				logger.V(1).Info("Resource not found yet, waiting...", "kind", kind, "name", obj.GetName())
-				return false, nil
-			}
-			return false, err
-		}
+// isResourceReady checks once whether a resource is ready, based on its kind
+func (r *AppBundleReconciler) isResourceReady(ctx context.Context, obj *unstructured.Unstructured) (bool, error) {
+	logger := log.FromContext(ctx)
+	kind := obj.GetKind()
 
-		// Check readiness based on resource kind
-		switch kind {
-		case "Deployment":
-			return r.isDeploymentReady(current)
-		case "StatefulSet":
-			return r.isStatefulSetReady(current)
-		case "DaemonSet":
-			return r.isDaemonSetReady(current)
-		case "Job":
-			return r.isJobComplete(current)
-		case "Pod":
-			return r.isPodReady(current)
-		default:
-			// For unknown types, just check if they exist
-			logger.Info("Unknown resource type, considering ready", "kind", kind, "name", obj.GetName())
-			return true, nil
+	// Fetch the latest version of the resource
+	current := &unstructured.Unstructured{}
+	current.SetGroupVersionKind(obj.GroupVersionKind())
+	err := r.Get(ctx, types.NamespacedName{
+		Name:      obj.GetName(),
+		Namespace: obj.GetNamespace(),
+	}, current)
+
+	if err != nil {
+		if errors.IsNotFound(err) {
+			logger.V(1).Info("Resource not found yet, waiting...", "kind", kind, "name", obj.GetName())
+			return false, nil
 		}
-	})
+		return false, err
+	}
+
+	// Check readiness based on resource kind
+	switch kind {
+	case "Deployment":
+		return r.isDeploymentReady(current)
+	case "StatefulSet":
+		return r.isStatefulSetReady(current)
+	case "DaemonSet":
+		return r.isDaemonSetReady(current)
+	case "Job":
+		return r.isJobComplete(current)
+	case "Pod":
+		return r.isPodReady(current)
+	case render.KustomizationKind:
+		return r.isKustomizationReady(current)
+	default:
+		// For unknown types, just check if they exist
+		logger.Info("Unknown resource type, considering ready", "kind", kind, "name", obj.GetName())
+		return true, nil
+	}
 }
 
 // isDeploymentReady checks if a Deployment is ready
@@ -436,6 +770,20 @@ func (r *AppBundleReconciler) isDeploymentReady(obj *unstructured.Unstructured)
 		availableReplicas = 0
 	}
 
+	conditions, _, err := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	if err != nil {
+		return false, err
+	}
+	for _, condition := range conditions {
+		condMap, ok := condition.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if condMap["type"] == "Progressing" && condMap["status"] == "False" && condMap["reason"] == "ProgressDeadlineExceeded" {
+			return false, fmt.Errorf("deployment exceeded its progress deadline")
+		}
+	}
+
 	ready := readyReplicas == replicas &&
 		updatedReplicas == replicas &&
 		availableReplicas == replicas
@@ -551,7 +899,7 @@ func (r *AppBundleReconciler) isPodReady(obj *unstructured.Unstructured) (bool,
 }
 
 // reconcileComponentWithPorch reconciles a component that uses a Porch package
-func (r *AppBundleReconciler) reconcileComponentWithPorch(ctx context.Context, appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group, component appv1alpha1.Component, baseSyncWave int) (appv1alpha1.ComponentStatus, error) {
+func (r *AppBundleReconciler) reconcileComponentWithPorch(ctx context.Context, appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group, component appv1alpha1.Component, baseSyncWave int, pass *deployPass) (appv1alpha1.ComponentStatus, error) {
 	logger := log.FromContext(ctx)
 
 	componentStatus := appv1alpha1.ComponentStatus{
@@ -565,113 +913,21 @@ func (r *AppBundleReconciler) reconcileComponentWithPorch(ctx context.Context, a
 		return componentStatus, fmt.Errorf("porchPackageRef is nil")
 	}
 
-	// Create PackageVariant name (custom format: appbundle-<package>)
-	packageVariantName := fmt.Sprintf("appbundle-%s", component.PorchPackageRef.PackageName)
-
-	// Determine namespace for PackageVariant (default to "default")
-	pvNamespace := "default"
-	if component.PorchPackageRef.Namespace != "" {
-		pvNamespace = component.PorchPackageRef.Namespace
-	}
-
-	// Determine downstream repo (default to "mgmt" or use from spec)
-	downstreamRepo := "mgmt"
-	if appBundle.Spec.PorchIntegration != nil && appBundle.Spec.PorchIntegration.Repository != "" {
-		downstreamRepo = appBundle.Spec.PorchIntegration.Repository
-	}
-
-	// Determine revision (default to "main")
-	revision := "main"
-	if component.PorchPackageRef.Revision != "" {
-		revision = component.PorchPackageRef.Revision
-	}
-
-	// Create PackageVariant CRD
-	packageVariant := &unstructured.Unstructured{}
-	packageVariant.SetAPIVersion("config.porch.kpt.dev/v1alpha1")
-	packageVariant.SetKind("PackageVariant")
-	packageVariant.SetName(packageVariantName)
-	packageVariant.SetNamespace(pvNamespace)
-
-	// Calculate sync wave
-	syncWave := baseSyncWave + component.Order
-	syncWaveStr := strconv.Itoa(syncWave)
-
-	// Calculate wait job sync wave (between current and next group)
-	// Place it at baseSyncWave + 50 (middle of the group's range)
-	waitSyncWave := baseSyncWave + 50
-	waitSyncWaveStr := strconv.Itoa(waitSyncWave)
-
-	// Build mutators list
-	mutators := []interface{}{
-		// Mutator 1: Set sync wave annotations on all resources
-		map[string]interface{}{
-			"image": "gcr.io/kpt-fn/set-annotations:v0.1.4",
-			"configMap": map[string]interface{}{
-				argoSyncWaveAnnotation: syncWaveStr,
-			},
-		},
-		// Mutator 2: Add AppBundle tracking labels to all resources
-		map[string]interface{}{
-			"image": "gcr.io/kpt-fn/set-labels:v0.2.0",
-			"configMap": map[string]interface{}{
-				"app.example.com/appbundle": appBundle.Name,
-				"app.example.com/group":     group.Name,
-				"app.example.com/component": component.Name,
-			},
-		},
-		// Mutator 3: Inject wait Job using Starlark
-		// This Job waits for all workload resources to be ready before proceeding
-		// Only inject RBAC resources in the first Porch component (baseSyncWave == 0)
-		r.buildWaitJobMutator(appBundle, group, component, packageVariantName, waitSyncWaveStr, baseSyncWave == 0),
-	}
-
-	// Set PackageVariant spec with pipeline mutators
-	spec := map[string]interface{}{
-		"upstream": map[string]interface{}{
-			"repo":     component.PorchPackageRef.Repository,
-			"package":  component.PorchPackageRef.PackageName,
-			"revision": revision,
-		},
-		"downstream": map[string]interface{}{
-			"repo":    downstreamRepo,
-			"package": packageVariantName,
-		},
-		"annotations": map[string]interface{}{
-			"approval.nephio.org/policy": "initial",
-		},
-		"adoptionPolicy": "adoptExisting",
-		"deletionPolicy": "delete",
-		// Pipeline mutators to inject annotations and wait Job
-		"pipeline": map[string]interface{}{
-			"mutators": mutators,
-		},
-	}
-
-	if err := unstructured.SetNestedMap(packageVariant.Object, spec, "spec"); err != nil {
+	packageVariantName := render.PackageVariantName(component)
+	pvNamespace := render.PackageVariantNamespace(component)
+	downstreamRepo := render.DownstreamRepository(appBundle)
+
+	packageVariant, err := render.PackageVariant(appBundle, group, component, baseSyncWave)
+	if err != nil {
 		componentStatus.Phase = appv1alpha1.PhaseFailed
 		componentStatus.Message = fmt.Sprintf("Failed to set PackageVariant spec: %v", err)
 		return componentStatus, err
 	}
 
-	// Add annotations to metadata
-	annotations := map[string]string{
-		argoSyncWaveAnnotation: strconv.Itoa(syncWave),
-	}
-	packageVariant.SetAnnotations(annotations)
-
-	// Add labels
-	labels := map[string]string{
-		"app.example.com/appbundle": appBundle.Name,
-		"app.example.com/group":     group.Name,
-		"app.example.com/component": component.Name,
-	}
-	packageVariant.SetLabels(labels)
-
 	// Create or update PackageVariant
 	existingPV := &unstructured.Unstructured{}
 	existingPV.SetGroupVersionKind(packageVariant.GroupVersionKind())
-	err := r.Get(ctx, types.NamespacedName{
+	err = r.Get(ctx, types.NamespacedName{
 		Name:      packageVariantName,
 		Namespace: pvNamespace,
 	}, existingPV)
@@ -682,8 +938,13 @@ func (r *AppBundleReconciler) reconcileComponentWithPorch(ctx context.Context, a
 			if err := r.Create(ctx, packageVariant); err != nil {
 				componentStatus.Phase = appv1alpha1.PhaseFailed
 				componentStatus.Message = fmt.Sprintf("Failed to create PackageVariant: %v", err)
+				r.countApplyError(appBundle, group.Name, component.Name, packageVariant.GroupVersionKind())
+				r.Recorder.Eventf(appBundle, corev1.EventTypeWarning, reasonComponentFailed,
+					"Failed to create PackageVariant %s for component %s: %v", packageVariantName, component.Name, err)
 				return componentStatus, err
 			}
+			r.Recorder.Eventf(appBundle, corev1.EventTypeNormal, reasonPackageVariantCreated,
+				"Created PackageVariant %s/%s for package %s", pvNamespace, packageVariantName, component.PorchPackageRef.PackageName)
 		} else {
 			componentStatus.Phase = appv1alpha1.PhaseFailed
 			componentStatus.Message = fmt.Sprintf("Failed to get PackageVariant: %v", err)
@@ -692,13 +953,34 @@ func (r *AppBundleReconciler) reconcileComponentWithPorch(ctx context.Context, a
 	} else {
 		logger.Info("PackageVariant already exists", "name", packageVariantName)
 		// Update if needed (for now, skip update to avoid conflicts with Porch)
+
+		// The deletion policy only matters once the PackageVariant is deleted, so it is kept
+		// current without touching the rest of the spec
+		desiredPolicy, _, _ := unstructured.NestedString(packageVariant.Object, "spec", "deletionPolicy")
+		currentPolicy, _, _ := unstructured.NestedString(existingPV.Object, "spec", "deletionPolicy")
+		if desiredPolicy != currentPolicy {
+			if err := unstructured.SetNestedField(existingPV.Object, desiredPolicy, "spec", "deletionPolicy"); err != nil {
+				return componentStatus, err
+			}
+			if err := r.Update(ctx, existingPV); err != nil {
+				componentStatus.Phase = appv1alpha1.PhaseFailed
+				componentStatus.Message = fmt.Sprintf("Failed to update PackageVariant deletion policy: %v", err)
+				return componentStatus, err
+			}
+		}
 	}
+	recordInventory(appBundle, packageVariant, group.Name, component.Name)
+	pass.recordApplied(group.Name, packageVariant)
 
 	// Wait for PackageVariant to be ready
 	logger.Info("Waiting for PackageVariant to be ready", "name", packageVariantName)
-	if err := r.waitForPackageVariantReady(ctx, packageVariantName, pvNamespace); err != nil {
+	pvWaitStarted := time.Now()
+	err = r.waitForPackageVariantReady(ctx, packageVariantName, pvNamespace)
+	r.observePackageVariantWait(appBundle, group.Name, component.Name, pvWaitStarted)
+	if err != nil {
 		componentStatus.Phase = appv1alpha1.PhaseFailed
 		componentStatus.Message = fmt.Sprintf("PackageVariant not ready: %v", err)
+		r.recordReadinessFailure(appBundle, group, component, packageVariant, err)
 		return componentStatus, err
 	}
 
@@ -740,6 +1022,11 @@ func (r *AppBundleReconciler) reconcileComponentWithPorch(ctx context.Context, a
 		}
 	}
 
+	// Resources deployed by Porch carry no owner reference to the bundle
+	for _, obj := range resourcesToMonitor {
+		recordInventory(appBundle, obj, group.Name, component.Name)
+	}
+
 	componentStatus.Phase = appv1alpha1.PhaseDeployed
 	componentStatus.Message = fmt.Sprintf("PackageVariant deployed successfully via Porch (%d resources monitored)", len(resourcesToMonitor))
 	componentStatus.ResourceRef = &appv1alpha1.ResourceReference{
@@ -750,6 +1037,8 @@ func (r *AppBundleReconciler) reconcileComponentWithPorch(ctx context.Context, a
 	}
 
 	logger.Info("PackageVariant deployed and ready", "name", packageVariantName)
+	r.Recorder.Eventf(appBundle, corev1.EventTypeNormal, reasonComponentReady,
+		"PackageVariant %s for component %s/%s is ready", packageVariantName, group.Name, component.Name)
 	return componentStatus, nil
 }
 
@@ -938,211 +1227,6 @@ func isInfrastructureResource(kind string) bool {
 	return infraKinds[kind]
 }
 
-// buildWaitJobMutator creates a Starlark mutator that injects a wait Job
-// The wait Job uses Argo CD hooks to pause deployment until resources are ready
-// includeRBAC determines whether to inject ServiceAccount, ClusterRole, and ClusterRoleBinding (only needed once)
-func (r *AppBundleReconciler) buildWaitJobMutator(appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group, component appv1alpha1.Component, packageName, syncWave string, includeRBAC bool) map[string]interface{} {
-	// Determine namespace for the wait job
-	namespace := appBundle.Namespace
-	if namespace == "" {
-		namespace = "default"
-	}
-
-	// Generate a random suffix for the job name to avoid conflicts
-	randomSuffix := fmt.Sprintf("%d", time.Now().Unix()%100000)
-
-	// Calculate sync waves for RBAC resources (must be before the Job)
-	// RBAC resources need to exist before the Job that uses them
-	// syncWave parameter is the Job's sync wave, RBAC should be 1 less
-	jobSyncWave, _ := strconv.Atoi(syncWave)
-	rbacSyncWave := jobSyncWave - 1 // One wave before the Job
-
-	// Build RBAC resources code (conditionally included)
-	rbacComment := "false"
-	rbacCode := "# RBAC resources not included (already created in first package)"
-	if includeRBAC {
-		rbacComment = "true"
-		rbacCode = fmt.Sprintf(`# Create ServiceAccount
-    sa_yaml = {
-        "apiVersion": "v1",
-        "kind": "ServiceAccount",
-        "metadata": {
-            "name": "appbundle-wait-reader",
-            "namespace": target_namespace,
-            "annotations": {
-                "argocd.argoproj.io/sync-wave": "%s"
-            },
-            "labels": {
-                "app.example.com/appbundle": "%s",
-                "app.example.com/wait-job": "true"
-            }
-        }
-    }
-    resource_list["items"].append(sa_yaml)
-    
-    # Create ClusterRole for reading resource status
-    clusterrole_yaml = {
-        "apiVersion": "rbac.authorization.k8s.io/v1",
-        "kind": "ClusterRole",
-        "metadata": {
-            "name": "appbundle-wait-reader-" + target_namespace,
-            "annotations": {
-                "argocd.argoproj.io/sync-wave": "%s"
-            },
-            "labels": {
-                "app.example.com/appbundle": "%s",
-                "app.example.com/wait-job": "true"
-            }
-        },
-        "rules": [
-            {
-                "apiGroups": ["apps"],
-                "resources": ["deployments", "deployments/status", "statefulsets", "statefulsets/status", "daemonsets", "daemonsets/status"],
-                "verbs": ["get", "list", "watch"]
-            },
-            {
-                "apiGroups": ["batch"],
-                "resources": ["jobs", "jobs/status"],
-                "verbs": ["get", "list", "watch"]
-            },
-            {
-                "apiGroups": [""],
-                "resources": ["pods", "pods/status", "services"],
-                "verbs": ["get", "list", "watch"]
-            }
-        ]
-    }
-    resource_list["items"].append(clusterrole_yaml)
-    
-    # Create ClusterRoleBinding
-    clusterrolebinding_yaml = {
-        "apiVersion": "rbac.authorization.k8s.io/v1",
-        "kind": "ClusterRoleBinding",
-        "metadata": {
-            "name": "appbundle-wait-reader-" + target_namespace,
-            "annotations": {
-                "argocd.argoproj.io/sync-wave": "%s"
-            },
-            "labels": {
-                "app.example.com/appbundle": "%s",
-                "app.example.com/wait-job": "true"
-            }
-        },
-        "roleRef": {
-            "apiGroup": "rbac.authorization.k8s.io",
-            "kind": "ClusterRole",
-            "name": "appbundle-wait-reader-" + target_namespace
-        },
-        "subjects": [
-            {
-                "kind": "ServiceAccount",
-                "name": "appbundle-wait-reader",
-                "namespace": target_namespace
-            }
-        ]
-    }
-    resource_list["items"].append(clusterrolebinding_yaml)
-    `, strconv.Itoa(rbacSyncWave), appBundle.Name,
-			strconv.Itoa(rbacSyncWave), appBundle.Name,
-			strconv.Itoa(rbacSyncWave), appBundle.Name)
-	}
-
-	// Build the Starlark script that injects the wait Job
-	// Note: KPT Starlark doesn't need imports - resource_list is passed directly
-	starlarkScript := fmt.Sprintf(`def transform(resource_list):
-    # Determine the target namespace from resources in the package
-    target_namespace = None
-    specific_wait_commands = []
-    
-    # Scan all resources in the package to determine namespace and specific resources
-    for resource in resource_list["items"]:
-        kind = resource.get("kind", "")
-        metadata = resource.get("metadata", {})
-        name = metadata.get("name", "")
-        ns = metadata.get("namespace", "")
-        
-        # Store the target namespace from the first namespaced resource
-        if ns and not target_namespace:
-            target_namespace = ns
-        
-        # Collect specific wait commands for known workload resources
-        # Note: Starlark doesn't support f-strings, use string concatenation
-        if kind == "Deployment" and name and ns:
-            specific_wait_commands.append("kubectl rollout status deployment/" + name + " -n " + ns + " --timeout=15m")
-        elif kind == "StatefulSet" and name and ns:
-            specific_wait_commands.append("kubectl rollout status statefulset/" + name + " -n " + ns + " --timeout=15m")
-        elif kind == "DaemonSet" and name and ns:
-            specific_wait_commands.append("kubectl rollout status daemonset/" + name + " -n " + ns + " --timeout=15m")
-    
-    # If no specific namespace found, use default
-    if not target_namespace:
-        target_namespace = "%s"
-    
-    # Build wait script - always create a wait job
-    # If we found specific resources, wait for them. Otherwise, use a simple delay
-    if specific_wait_commands:
-        wait_script = " && ".join(specific_wait_commands)
-    else:
-        # Generic wait: just add a delay to ensure resources have time to deploy
-        # This is a fallback when we can't detect specific resources in the package
-        wait_script = "echo 'Waiting for resources to be created in namespace " + target_namespace + "...' && sleep 10 && echo 'Proceeding to next group'"
-    
-    # Always create wait job to ensure sequential deployment
-    # RBAC resources (only added in first package): %s
-%s
-    
-    # Create the wait Job
-    job_yaml = {
-        "apiVersion": "batch/v1",
-        "kind": "Job",
-        "metadata": {
-            "name": "wait-%s-%s-%s",
-            "namespace": target_namespace,
-            "annotations": {
-                "argocd.argoproj.io/hook": "Sync",
-                "argocd.argoproj.io/sync-wave": "%s"
-            },
-            "labels": {
-                "app.example.com/appbundle": "%s",
-                "app.example.com/group": "%s",
-                "app.example.com/component": "%s",
-                "app.example.com/wait-job": "true"
-            }
-        },
-        "spec": {
-            "ttlSecondsAfterFinished": 300,
-            "backoffLimit": 3,
-            "template": {
-                "spec": {
-                    "restartPolicy": "Never",
-                    "serviceAccountName": "appbundle-wait-reader",
-                    "containers": [{
-                        "name": "wait",
-                        "image": "bitnami/kubectl:latest",
-                        "command": ["sh", "-c"],
-                        "args": [wait_script]
-                    }]
-                }
-            }
-        }
-    }
-    resource_list["items"].append(job_yaml)
-    
-    return resource_list
-
-# Call the transform function
-transform(ctx.resource_list)
-`, namespace, rbacComment, rbacCode,
-		group.Name, component.Name, randomSuffix, syncWave, appBundle.Name, group.Name, component.Name) // Job with random suffix (Job wave)
-
-	return map[string]interface{}{
-		"image": "gcr.io/kpt-fn/starlark:v0.4.3",
-		"configMap": map[string]interface{}{
-			"source": starlarkScript,
-		},
-	}
-}
-
 // reconcilePorchPackages handles integration with Porch for package lifecycle management
 // nolint:unparam // This function currently always returns nil as it's a placeholder
 func (r *AppBundleReconciler) reconcilePorchPackages(ctx context.Context, appBundle *appv1alpha1.AppBundle) error {
@@ -1160,83 +1244,6 @@ func (r *AppBundleReconciler) reconcilePorchPackages(ctx context.Context, appBun
 	return nil
 }
 
-// finalizeAppBundle handles cleanup when AppBundle is deleted
-// nolint:unparam // This function currently always returns nil as cleanup is handled by K8s GC
-func (r *AppBundleReconciler) finalizeAppBundle(ctx context.Context, appBundle *appv1alpha1.AppBundle) error {
-	logger := log.FromContext(ctx)
-	logger.Info("Finalizing AppBundle", "name", appBundle.Name)
-
-	// Cleanup logic here
-	// Resources with owner references will be automatically deleted by K8s
-	// We need to manually clean up resources without owner references:
-	// - Cross-namespace resources
-	// - Cluster-scoped resources (Namespaces, ClusterRoles, etc.)
-
-	// Delete resources in reverse order (reverse of groups and components)
-	sortedGroups := make([]appv1alpha1.Group, len(appBundle.Spec.Groups))
-	copy(sortedGroups, appBundle.Spec.Groups)
-	sort.Slice(sortedGroups, func(i, j int) bool {
-		return sortedGroups[i].Order > sortedGroups[j].Order // Reverse order
-	})
-
-	for _, group := range sortedGroups {
-		// Sort components in reverse order
-		sortedComponents := make([]appv1alpha1.Component, len(group.Components))
-		copy(sortedComponents, group.Components)
-		sort.Slice(sortedComponents, func(i, j int) bool {
-			return sortedComponents[i].Order > sortedComponents[j].Order // Reverse order
-		})
-
-		for _, component := range sortedComponents {
-			// If component uses Porch, delete the PackageVariant
-			// The PackageVariant deletion will cascade to deployed resources
-			if component.PorchPackageRef != nil {
-				pvName := fmt.Sprintf("appbundle-%s", component.PorchPackageRef.PackageName)
-				pvNamespace := "default"
-				if component.PorchPackageRef.Namespace != "" {
-					pvNamespace = component.PorchPackageRef.Namespace
-				}
-
-				pv := &unstructured.Unstructured{}
-				pv.SetAPIVersion("config.porch.kpt.dev/v1alpha1")
-				pv.SetKind("PackageVariant")
-				pv.SetName(pvName)
-				pv.SetNamespace(pvNamespace)
-
-				logger.Info("Deleting PackageVariant", "name", pvName, "namespace", pvNamespace)
-				if err := r.Delete(ctx, pv); err != nil && !errors.IsNotFound(err) {
-					logger.Error(err, "Failed to delete PackageVariant", "name", pvName)
-				}
-				continue
-			}
-
-			// For non-Porch components, parse template and delete resource
-			if len(component.Template.Raw) > 0 {
-				obj := &unstructured.Unstructured{}
-				if err := json.Unmarshal(component.Template.Raw, obj); err != nil {
-					logger.Error(err, "Failed to parse template during cleanup", "component", component.Name)
-					continue
-				}
-
-				// Set namespace if not specified in template
-				if obj.GetNamespace() == "" && appBundle.Namespace != "" {
-					obj.SetNamespace(appBundle.Namespace)
-				}
-
-				// Try to delete the resource (ignore NotFound errors)
-				logger.Info("Deleting resource", "kind", obj.GetKind(), "name", obj.GetName(), "namespace", obj.GetNamespace())
-				if err := r.Delete(ctx, obj); err != nil && !errors.IsNotFound(err) {
-					logger.Error(err, "Failed to delete resource", "kind", obj.GetKind(), "name", obj.GetName())
-					// Continue with other resources even if one fails
-				}
-			}
-		}
-	}
-
-	logger.Info("AppBundle finalization complete", "name", appBundle.Name)
-	return nil
-}
-
 // updateStatusWithError updates the AppBundle status with error information
 func (r *AppBundleReconciler) updateStatusWithError(ctx context.Context, appBundle *appv1alpha1.AppBundle, err error) (ctrl.Result, error) {
 	appBundle.Status.Phase = appv1alpha1.PhaseFailed
@@ -1250,17 +1257,51 @@ func (r *AppBundleReconciler) updateStatusWithError(ctx context.Context, appBund
 		ObservedGeneration: appBundle.Generation,
 	})
 
-	if statusErr := r.Status().Update(ctx, appBundle); statusErr != nil {
+	if statusErr := r.updateBundleStatus(ctx, appBundle); statusErr != nil {
 		return ctrl.Result{}, statusErr
 	}
 
 	return ctrl.Result{}, err
 }
 
+// rejectInvalidParameters fails a bundle whose parameters or template expressions cannot be
+// resolved. The error is returned so that fixes to parameter sources are picked up on retry.
+func (r *AppBundleReconciler) rejectInvalidParameters(ctx context.Context, appBundle *appv1alpha1.AppBundle, err error) (ctrl.Result, error) {
+	r.Recorder.Eventf(appBundle, corev1.EventTypeWarning, reasonInvalidParameters, "Invalid parameters: %v", err)
+	appBundle.Status.Phase = appv1alpha1.PhaseFailed
+	appBundle.Status.Message = fmt.Sprintf("Invalid parameters: %v", err)
+	meta.SetStatusCondition(&appBundle.Status.Conditions, metav1.Condition{
+		Type:               "Ready",
+		Status:             metav1.ConditionFalse,
+		Reason:             reasonInvalidParameters,
+		Message:            err.Error(),
+		ObservedGeneration: appBundle.Generation,
+	})
+	if statusErr := r.updateBundleStatus(ctx, appBundle); statusErr != nil {
+		return ctrl.Result{}, statusErr
+	}
+	return ctrl.Result{}, err
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *AppBundleReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	// Changes to common resource kinds owned by a bundle trigger an immediate drift check
+	builder := ctrl.NewControllerManagedBy(mgr).
 		For(&appv1alpha1.AppBundle{}).
-		Named("appbundle").
-		Complete(r)
+		Owns(&appsv1.Deployment{}).
+		Owns(&appsv1.StatefulSet{}).
+		Owns(&appsv1.DaemonSet{}).
+		Owns(&corev1.Service{}).
+		Owns(&corev1.ConfigMap{}).
+		Watches(&appv1alpha1.AppBundleTemplate{}, handler.EnqueueRequestsFromMapFunc(r.templateToAppBundles))
+
+	// Status changes of Argo CD Applications are mirrored as soon as they happen. The watch
+	// is only set up when Argo CD is installed; otherwise conditions refresh with the drift interval.
+	if _, err := mgr.GetRESTMapper().RESTMapping(applicationGVK.GroupKind(), applicationGVK.Version); err == nil {
+		application := &unstructured.Unstructured{}
+		application.SetGroupVersionKind(applicationGVK)
+		builder = builder.Watches(application, handler.EnqueueRequestsFromMapFunc(applicationToAppBundle))
+	}
+
+	return builder.Named("appbundle").Complete(r)
 }
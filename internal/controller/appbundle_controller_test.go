@@ -20,18 +20,36 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+	"github.com/example/appbundle-operator/internal/analysis"
+	"github.com/example/appbundle-operator/internal/render"
+	"github.com/example/appbundle-operator/internal/source"
 )
 
 var _ = Describe("AppBundle Controller", func() {
@@ -138,8 +156,9 @@ var _ = Describe("AppBundle Controller", func() {
 
 				// Trigger reconciliation to process finalizer during deletion
 				controllerReconciler := &AppBundleReconciler{
-					Client: k8sClient,
-					Scheme: k8sClient.Scheme(),
+					Client:   k8sClient,
+					Scheme:   k8sClient.Scheme(),
+					Recorder: record.NewFakeRecorder(100),
 				}
 
 				// Reconcile to process the finalizer
@@ -158,8 +177,9 @@ var _ = Describe("AppBundle Controller", func() {
 		It("should successfully reconcile the resource", func() {
 			By("Reconciling the created resource")
 			controllerReconciler := &AppBundleReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
 			}
 
 			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
@@ -181,11 +201,1114 @@ var _ = Describe("AppBundle Controller", func() {
 			Expect(string(appbundle.Status.Phase)).To(BeElementOf("Pending", "Deploying", "Deployed"))
 		})
 
+		It("should record lifecycle events on the AppBundle", func() {
+			By("Reconciling with a fake event recorder")
+			recorder := record.NewFakeRecorder(100)
+			controllerReconciler := &AppBundleReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Collecting the recorded events")
+			var events []string
+			for len(recorder.Events) > 0 {
+				events = append(events, <-recorder.Events)
+			}
+
+			Expect(events).To(ContainElement(ContainSubstring("Normal GroupStarted Deploying group infrastructure")))
+			Expect(events).To(ContainElement(ContainSubstring("Normal ComponentApplied")))
+			Expect(events).To(ContainElement(ContainSubstring("Normal ComponentReady ConfigMap test-config")))
+			Expect(events).To(ContainElement(ContainSubstring("Normal GroupCompleted Group application deployed successfully")))
+		})
+
+		It("should export bundle and component metrics", func() {
+			By("Reconciling the created resource")
+			controllerReconciler := &AppBundleReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Checking the phase gauge")
+			Expect(testutil.ToFloat64(bundlePhase.WithLabelValues("default", resourceName, "Deployed"))).To(Equal(1.0))
+			Expect(testutil.ToFloat64(bundlePhase.WithLabelValues("default", resourceName, "Failed"))).To(Equal(0.0))
+
+			By("Checking the component readiness histogram")
+			Expect(testutil.CollectAndCount(componentReadinessDuration)).To(BeNumerically(">=", 2))
+		})
+
+		It("should roll back to the last deployed manifests when a group fails", func() {
+			controllerReconciler := &AppBundleReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+			}
+
+			By("Deploying the initial spec")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Updating the spec with a changed ConfigMap and a broken component")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			appbundle.Spec.RollbackPolicy = appv1alpha1.RollbackPolicyBundle
+			updatedConfig, _ := json.Marshal(map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata": map[string]interface{}{
+					"name":      "test-config",
+					"namespace": "default",
+				},
+				"data": map[string]interface{}{
+					"key": "changed",
+				},
+			})
+			appbundle.Spec.Groups[0].Components[0].Template = runtime.RawExtension{Raw: updatedConfig}
+			appbundle.Spec.Groups[1].Components = append(appbundle.Spec.Groups[1].Components,
+				appv1alpha1.Component{Name: "broken", Order: 1})
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Verifying the bundle is rolled back")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(appbundle.Status.Phase).To(Equal(appv1alpha1.PhaseRolledBack))
+
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "test-config", Namespace: "default"}, configMap)).To(Succeed())
+			Expect(configMap.Data).To(HaveKeyWithValue("key", "value"))
+			Expect(appbundle.Status.GroupStatuses).NotTo(BeEmpty())
+			Expect(appbundle.Status.GroupStatuses[0].StartedAt).NotTo(BeNil())
+			Expect(appbundle.Status.GroupStatuses[0].CompletedAt).NotTo(BeNil())
+
+			By("Requesting a retry")
+			recorder := record.NewFakeRecorder(100)
+			controllerReconciler.Recorder = recorder
+			appbundle.Annotations = map[string]string{appv1alpha1.RetryAnnotation: "true"}
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			var events []string
+			for len(recorder.Events) > 0 {
+				events = append(events, <-recorder.Events)
+			}
+			Expect(events).To(ContainElement(ContainSubstring("Normal RetryRequested Retrying deployment of all groups")))
+			Expect(events).To(ContainElement(ContainSubstring("Warning RollbackStarted")))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(appbundle.Annotations).NotTo(HaveKey(appv1alpha1.RetryAnnotation))
+		})
+
+		It("should retry only the requested group and the groups after it", func() {
+			controllerReconciler := &AppBundleReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+			}
+			request := reconcile.Request{NamespacedName: typeNamespacedName}
+
+			By("Deploying the initial spec")
+			_, err := controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Breaking the application group")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			appbundle.Spec.RollbackPolicy = appv1alpha1.RollbackPolicyGroup
+			appbundle.Spec.Groups[1].Components = append(appbundle.Spec.Groups[1].Components,
+				appv1alpha1.Component{Name: "broken", Order: 1})
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(appbundle.Status.Phase).To(Equal(appv1alpha1.PhaseRolledBack))
+
+			By("Retrying the application group")
+			recorder := record.NewFakeRecorder(100)
+			controllerReconciler.Recorder = recorder
+			appbundle.Annotations = map[string]string{appv1alpha1.RetryAnnotation: "application"}
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			var events []string
+			for len(recorder.Events) > 0 {
+				events = append(events, <-recorder.Events)
+			}
+			Expect(events).To(ContainElement(ContainSubstring("Normal RetryRequested Retrying deployment from group application")))
+			Expect(events).To(ContainElement(ContainSubstring("Deploying group application")))
+			Expect(events).NotTo(ContainElement(ContainSubstring("Deploying group infrastructure")))
+		})
+
+		It("should record revisions and roll back to a pinned revision", func() {
+			controllerReconciler := &AppBundleReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+			}
+			request := reconcile.Request{NamespacedName: typeNamespacedName}
+
+			By("Deploying the initial spec")
+			_, err := controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(appbundle.Status.CurrentRevision).To(Equal(int64(1)))
+
+			revision := &appv1alpha1.AppBundleRevision{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Name:      resourceName + "-rev-1",
+				Namespace: "default",
+			}, revision)).To(Succeed())
+			Expect(revision.Spec.BundleName).To(Equal(resourceName))
+			Expect(revision.Spec.Manifests).NotTo(BeEmpty())
+			Expect(revision.Spec.GroupStatuses).To(HaveLen(2))
+
+			By("Deploying a changed spec as revision 2")
+			updatedConfig, _ := json.Marshal(map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata": map[string]interface{}{
+					"name":      "test-config",
+					"namespace": "default",
+				},
+				"data": map[string]interface{}{
+					"key": "changed",
+				},
+			})
+			appbundle.Spec.Groups[0].Components[0].Template = runtime.RawExtension{Raw: updatedConfig}
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(appbundle.Status.CurrentRevision).To(Equal(int64(2)))
+
+			By("Pinning the bundle to revision 1")
+			rollbackTo := int64(1)
+			appbundle.Spec.RollbackTo = &rollbackTo
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(appbundle.Status.Phase).To(Equal(appv1alpha1.PhaseRolledBack))
+			Expect(appbundle.Status.CurrentRevision).To(Equal(int64(1)))
+
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "test-config", Namespace: "default"}, configMap)).To(Succeed())
+			Expect(configMap.Data).To(HaveKeyWithValue("key", "value"))
+			Expect(appbundle.Status.GroupStatuses).NotTo(BeEmpty())
+			Expect(appbundle.Status.GroupStatuses[0].StartedAt).NotTo(BeNil())
+			Expect(appbundle.Status.GroupStatuses[0].CompletedAt).NotTo(BeNil())
+
+			By("Requesting a retry")
+			recorder := record.NewFakeRecorder(100)
+			controllerReconciler.Recorder = recorder
+			appbundle.Annotations = map[string]string{appv1alpha1.RetryAnnotation: "true"}
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			var events []string
+			for len(recorder.Events) > 0 {
+				events = append(events, <-recorder.Events)
+			}
+			Expect(events).To(ContainElement(ContainSubstring("Normal RetryRequested Retrying deployment of all groups")))
+			Expect(events).To(ContainElement(ContainSubstring("Warning RollbackStarted")))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(appbundle.Annotations).NotTo(HaveKey(appv1alpha1.RetryAnnotation))
+		})
+
+		It("should keep Secret values out of revisions and restore them on rollback", func() {
+			controllerReconciler := &AppBundleReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+			}
+			request := reconcile.Request{NamespacedName: typeNamespacedName}
+			secretName := resourceName + "-secret"
+			secretTemplate := func(password string) runtime.RawExtension {
+				raw, _ := json.Marshal(map[string]interface{}{
+					"apiVersion": "v1",
+					"kind":       "Secret",
+					"metadata": map[string]interface{}{
+						"name":      secretName,
+						"namespace": "default",
+					},
+					"stringData": map[string]interface{}{
+						"password": password,
+					},
+				})
+				return runtime.RawExtension{Raw: raw}
+			}
+
+			By("Deploying a bundle with a Secret")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			appbundle.Spec.Groups[0].Components = append(appbundle.Spec.Groups[0].Components, appv1alpha1.Component{
+				Name:     "secret",
+				Order:    1,
+				Template: secretTemplate("hunter2"),
+			})
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+			_, err := controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			revision := &appv1alpha1.AppBundleRevision{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Name:      resourceName + "-rev-1",
+				Namespace: "default",
+			}, revision)).To(Succeed())
+			snapshot, err := decodeSnapshot(revision.Spec.Manifests)
+			Expect(err).NotTo(HaveOccurred())
+			encoded, err := json.Marshal(snapshot)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(encoded)).NotTo(ContainSubstring("hunter2"))
+			Expect(revision.Spec.SecretsRef).NotTo(BeEmpty())
+
+			values := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: revision.Spec.SecretsRef, Namespace: "default"}, values)).To(Succeed())
+			Expect(metav1.IsControlledBy(values, revision)).To(BeTrue())
+
+			By("Deploying a changed Secret and rolling back to revision 1")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			appbundle.Spec.Groups[0].Components[1].Template = secretTemplate("changed")
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			rollbackTo := int64(1)
+			appbundle.Spec.RollbackTo = &rollbackTo
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(appbundle.Status.Phase).To(Equal(appv1alpha1.PhaseRolledBack))
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: secretName, Namespace: "default"}, secret)).To(Succeed())
+			Expect(secret.Data).To(HaveKeyWithValue("password", []byte("hunter2")))
+		})
+
+		It("should record the objects it applied even when their sources change", func() {
+			controllerReconciler := &AppBundleReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+				Fetcher:  &movingFetcher{Fetcher: source.NewFetcher(k8sClient)},
+			}
+
+			By("Deploying a parameter whose source changes on every read")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			appbundle.Spec.Parameters = []appv1alpha1.Parameter{{Name: "version"}}
+			appbundle.Spec.ParameterSources = []appv1alpha1.ParameterSource{{Kind: "ConfigMap", Name: resourceName + "-params"}}
+			appbundle.Spec.Groups[0].Components[0].Template = runtime.RawExtension{Raw: []byte(
+				`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"` + resourceName + `-settings"},` +
+					`"data":{"version":"{{ .params.version }}"}}`)}
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			settings := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-settings", Namespace: "default"}, settings)).To(Succeed())
+			revision := &appv1alpha1.AppBundleRevision{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-rev-1", Namespace: "default"}, revision)).To(Succeed())
+			snapshot, err := decodeSnapshot(revision.Spec.Manifests)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(snapshot.Groups[0].Objects[0]).To(HaveKeyWithValue("data", HaveKeyWithValue("version", settings.Data["version"])))
+		})
+
+		It("should not touch a suspended bundle", func() {
+			controllerReconciler := &AppBundleReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+			}
+
+			By("Suspending the bundle before the first deployment")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			appbundle.Spec.Suspend = true
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(appbundle.Status.GroupStatuses).To(BeEmpty())
+			Expect(appbundle.Status.Conditions).To(ContainElement(HaveField("Type", "Suspended")))
+		})
+
+		It("should stop before a manual group until it is approved", func() {
+			controllerReconciler := &AppBundleReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+			}
+			request := reconcile.Request{NamespacedName: typeNamespacedName}
+
+			By("Marking the application group as manual")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			appbundle.Spec.Groups[1].Approval = appv1alpha1.ApprovalManual
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(appbundle.Status.Phase).To(Equal(appv1alpha1.PhaseAwaitingApproval))
+			Expect(appbundle.Status.GroupStatuses).To(HaveLen(2))
+			Expect(appbundle.Status.GroupStatuses[0].Phase).To(Equal(appv1alpha1.PhaseDeployed))
+			Expect(appbundle.Status.GroupStatuses[1].Phase).To(Equal(appv1alpha1.PhaseAwaitingApproval))
+
+			By("Reconciling again while the group waits")
+			recorder := record.NewFakeRecorder(100)
+			controllerReconciler.Recorder = recorder
+			_, err = controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			var events []string
+			for len(recorder.Events) > 0 {
+				events = append(events, <-recorder.Events)
+			}
+			Expect(events).NotTo(ContainElement(ContainSubstring("Deploying group infrastructure")))
+			Expect(events).NotTo(ContainElement(ContainSubstring("ApprovalRequired")))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(appbundle.Status.Phase).To(Equal(appv1alpha1.PhaseAwaitingApproval))
+
+			By("Approving the group through the annotation")
+			if appbundle.Annotations == nil {
+				appbundle.Annotations = map[string]string{}
+			}
+			appbundle.Annotations[appv1alpha1.ApproveGroupAnnotation] = "application"
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(appbundle.Annotations).NotTo(HaveKey(appv1alpha1.ApproveGroupAnnotation))
+			Expect(appbundle.Status.ApprovedGroups).To(ContainElement(HaveField("Name", "application")))
+			Expect(appbundle.Status.Phase).To(Equal(appv1alpha1.PhaseDeployed))
+		})
+
+		It("should detect and heal drift of deployed resources", func() {
+			controllerReconciler := &AppBundleReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+			}
+			request := reconcile.Request{NamespacedName: typeNamespacedName}
+
+			By("Deploying the bundle")
+			_, err := controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Editing the deployed ConfigMap by hand")
+			configMap := &corev1.ConfigMap{}
+			configMapName := types.NamespacedName{Name: "test-config", Namespace: "default"}
+			Expect(k8sClient.Get(ctx, configMapName, configMap)).To(Succeed())
+			configMap.Data["key"] = "edited"
+			Expect(k8sClient.Update(ctx, configMap)).To(Succeed())
+
+			result, err := controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(appbundle.Status.Conditions, "Drifted")).To(BeTrue())
+			Expect(appbundle.Status.DriftedResources).To(HaveLen(1))
+			Expect(appbundle.Status.DriftedResources[0].Name).To(Equal("test-config"))
+			Expect(appbundle.Status.DriftedResources[0].Fields).To(ConsistOf("data.key"))
+
+			By("Enabling self-heal and redeploying the changed spec")
+			appbundle.Spec.DriftDetection = &appv1alpha1.DriftDetectionSpec{SelfHeal: true}
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Editing the deployed ConfigMap again")
+			Expect(k8sClient.Get(ctx, configMapName, configMap)).To(Succeed())
+			configMap.Data["key"] = "edited-again"
+			Expect(k8sClient.Update(ctx, configMap)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, configMapName, configMap)).To(Succeed())
+			Expect(configMap.Data).To(HaveKeyWithValue("key", "value"))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(appbundle.Status.Conditions, "Drifted")).To(BeFalse())
+		})
+
+		It("should not report quantities normalised by the API server as drift", func() {
+			controllerReconciler := &AppBundleReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+			}
+			request := reconcile.Request{NamespacedName: typeNamespacedName}
+
+			By("Deploying a ResourceQuota whose quantities are stored in canonical form")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			appbundle.Spec.Groups[0].Components = []appv1alpha1.Component{{
+				Name: "quota",
+				Template: runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"ResourceQuota","metadata":{"name":"` +
+					resourceName + `-quota"},"spec":{"hard":{"cpu":"1000m","memory":"1073741824"}}}`)},
+			}}
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+			_, err := controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			quota := &corev1.ResourceQuota{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-quota", Namespace: "default"}, quota)).To(Succeed())
+			defer func() { _ = k8sClient.Delete(ctx, quota) }()
+
+			_, err = controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(appbundle.Status.Conditions, "Drifted")).To(BeFalse())
+			Expect(appbundle.Status.DriftedResources).To(BeEmpty())
+		})
+
+		It("should leave fields covered by ignoreDifferences alone", func() {
+			controllerReconciler := &AppBundleReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+			}
+			request := reconcile.Request{NamespacedName: typeNamespacedName}
+
+			By("Ignoring the ConfigMap data key and deploying the bundle")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			appbundle.Spec.IgnoreDifferences = []appv1alpha1.IgnoreDifference{
+				{Kind: "ConfigMap", JSONPointers: []string{"/data/key"}},
+			}
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+			_, err := controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Editing the ignored field by hand")
+			configMap := &corev1.ConfigMap{}
+			configMapName := types.NamespacedName{Name: "test-config", Namespace: "default"}
+			Expect(k8sClient.Get(ctx, configMapName, configMap)).To(Succeed())
+			configMap.Data["key"] = "managed-elsewhere"
+			Expect(k8sClient.Update(ctx, configMap)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(appbundle.Status.Conditions, "Drifted")).To(BeFalse())
+			Expect(appbundle.Status.DriftedResources).To(BeEmpty())
+
+			By("Redeploying a changed spec")
+			historyLimit := int32(3)
+			appbundle.Spec.RevisionHistoryLimit = &historyLimit
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, configMapName, configMap)).To(Succeed())
+			Expect(configMap.Data).To(HaveKeyWithValue("key", "managed-elsewhere"))
+		})
+
+		It("should report a plan without changing deployed resources", func() {
+			controllerReconciler := &AppBundleReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+			}
+			request := reconcile.Request{NamespacedName: typeNamespacedName}
+
+			By("Deploying the bundle")
+			_, err := controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Adding a ConfigMap key by hand")
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "test-config", Namespace: "default"}, configMap)).To(Succeed())
+			configMap.Data["extra"] = "manual"
+			Expect(k8sClient.Update(ctx, configMap)).To(Succeed())
+
+			By("Switching to Plan mode with a changed ConfigMap and without the application group")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			configMapTemplate := map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata": map[string]interface{}{
+					"name":      "test-config",
+					"namespace": "default",
+				},
+				"data": map[string]interface{}{
+					"key": "planned",
+				},
+			}
+			configMapBytes, _ := json.Marshal(configMapTemplate)
+			appbundle.Spec.Mode = appv1alpha1.ModePlan
+			appbundle.Spec.Groups[0].Components[0].Template = runtime.RawExtension{Raw: configMapBytes}
+			appbundle.Spec.Groups = appbundle.Spec.Groups[:1]
+			endpointBytes, _ := json.Marshal(map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata":   map[string]interface{}{"name": "test-endpoint", "namespace": "default"},
+				"data":       map[string]interface{}{"host": "db.example.com"},
+			})
+			clientBytes, _ := json.Marshal(map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata":   map[string]interface{}{"name": "test-client", "namespace": "default"},
+				"data":       map[string]interface{}{"url": "${'postgres://' + components.endpoint.outputs.host}"},
+			})
+			appbundle.Spec.Groups[0].Components = append(appbundle.Spec.Groups[0].Components,
+				appv1alpha1.Component{
+					Name:     "endpoint",
+					Template: runtime.RawExtension{Raw: endpointBytes},
+					Outputs:  []appv1alpha1.ComponentOutput{{Name: "host", JSONPath: "{.data.host}"}},
+				},
+				appv1alpha1.Component{Name: "client", Order: 1, Template: runtime.RawExtension{Raw: clientBytes}},
+			)
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(appbundle.Status.Phase).To(Equal(appv1alpha1.PhasePlanned))
+			Expect(meta.IsStatusConditionTrue(appbundle.Status.Conditions, "Planned")).To(BeTrue())
+			plan := appbundle.Status.Plan
+			Expect(plan).NotTo(BeNil())
+			Expect(plan.ObservedGeneration).To(Equal(appbundle.Generation))
+			Expect(plan.Update).To(Equal(int32(1)))
+			Expect(plan.Prune).To(Equal(int32(1)))
+			Expect(plan.Objects).To(ContainElement(And(
+				HaveField("Name", "test-config"),
+				HaveField("Action", appv1alpha1.PlanActionUpdate),
+				HaveField("Fields", ConsistOf("data.key", "data.extra")),
+			)))
+			Expect(plan.Objects).To(ContainElement(And(
+				HaveField("Name", "test-service"),
+				HaveField("Action", appv1alpha1.PlanActionPrune),
+			)))
+			Expect(plan.Objects).To(ContainElement(And(
+				HaveField("Name", "test-client"),
+				HaveField("Action", appv1alpha1.PlanActionCreate),
+				HaveField("Unknown", ConsistOf("data.url")),
+			)))
+
+			By("Checking that the diffs were written and nothing was changed")
+			diffs := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: plan.DiffConfigMap, Namespace: "default"}, diffs)).To(Succeed())
+			Expect(diffs.Data).To(HaveKeyWithValue("configmap.default.test-config", ContainSubstring(`+ "planned"`)))
+
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "test-config", Namespace: "default"}, configMap)).To(Succeed())
+			Expect(configMap.Data).To(HaveKeyWithValue("key", "value"))
+			Expect(configMap.Data).To(HaveKeyWithValue("extra", "manual"))
+			service := &corev1.Service{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "test-service", Namespace: "default"}, service)).To(Succeed())
+		})
+
+		It("should create an Argo CD Application and mirror its status", func() {
+			controllerReconciler := &AppBundleReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+			}
+			request := reconcile.Request{NamespacedName: typeNamespacedName}
+
+			By("Registering the Porch downstream repository")
+			repository := &unstructured.Unstructured{}
+			repository.SetAPIVersion("config.porch.kpt.dev/v1alpha1")
+			repository.SetKind("Repository")
+			repository.SetName(resourceName + "-repo")
+			repository.SetNamespace("default")
+			Expect(unstructured.SetNestedMap(repository.Object, map[string]interface{}{
+				"repo":      "https://git.example.com/deployments.git",
+				"branch":    "main",
+				"directory": "clusters/edge",
+			}, "spec", "git")).To(Succeed())
+			Expect(k8sClient.Create(ctx, repository)).To(Succeed())
+			defer func() { Expect(k8sClient.Delete(ctx, repository)).To(Succeed()) }()
+
+			By("Enabling the Argo CD integration")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			appbundle.Spec.PorchIntegration = &appv1alpha1.PorchIntegrationSpec{Enabled: true, Repository: resourceName + "-repo"}
+			appbundle.Spec.ArgoCD = &appv1alpha1.ArgoCDIntegrationSpec{Enabled: true, Namespace: "default", AutomatedSync: true}
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			application := &unstructured.Unstructured{}
+			application.SetGroupVersionKind(applicationGVK)
+			applicationKey := types.NamespacedName{Name: render.ApplicationName(appbundle), Namespace: "default"}
+			Expect(k8sClient.Get(ctx, applicationKey, application)).To(Succeed())
+			Expect(application.GetLabels()).To(HaveKeyWithValue("app.example.com/appbundle", resourceName))
+			source, _, _ := unstructured.NestedMap(application.Object, "spec", "source")
+			Expect(source).To(HaveKeyWithValue("repoURL", "https://git.example.com/deployments.git"))
+			Expect(source).To(HaveKeyWithValue("path", "clusters/edge"))
+			Expect(source).To(HaveKeyWithValue("targetRevision", "main"))
+			selfHeal, _, _ := unstructured.NestedBool(application.Object, "spec", "syncPolicy", "automated", "selfHeal")
+			Expect(selfHeal).To(BeTrue())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			synced := meta.FindStatusCondition(appbundle.Status.Conditions, "ArgoCDSynced")
+			Expect(synced).NotTo(BeNil())
+			Expect(synced.Status).To(Equal(metav1.ConditionUnknown))
+
+			By("Reporting sync and health from Argo CD")
+			Expect(unstructured.SetNestedMap(application.Object, map[string]interface{}{
+				"sync":   map[string]interface{}{"status": "Synced", "revision": "abc123"},
+				"health": map[string]interface{}{"status": "Degraded", "message": "Deployment has failed pods"},
+			}, "status")).To(Succeed())
+			Expect(k8sClient.Update(ctx, application)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(appbundle.Status.Conditions, "ArgoCDSynced")).To(BeTrue())
+			healthy := meta.FindStatusCondition(appbundle.Status.Conditions, "ArgoCDHealthy")
+			Expect(healthy).NotTo(BeNil())
+			Expect(healthy.Status).To(Equal(metav1.ConditionFalse))
+			Expect(healthy.Reason).To(Equal("Degraded"))
+			Expect(healthy.Message).To(ContainSubstring("Deployment has failed pods"))
+
+			By("Disabling the integration")
+			appbundle.Spec.ArgoCD.Enabled = false
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, applicationKey, application))).To(BeTrue())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(meta.FindStatusCondition(appbundle.Status.Conditions, "ArgoCDSynced")).To(BeNil())
+		})
+
+		It("should not take over the Argo CD Application of another bundle", func() {
+			controllerReconciler := &AppBundleReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+			}
+
+			By("Creating an Application of another bundle under the bundle's Application name")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			application := &unstructured.Unstructured{}
+			application.SetGroupVersionKind(applicationGVK)
+			application.SetName(render.ApplicationName(appbundle))
+			application.SetNamespace("default")
+			application.SetLabels(map[string]string{
+				"app.example.com/appbundle":           "other",
+				"app.example.com/appbundle-namespace": "tenant",
+			})
+			Expect(unstructured.SetNestedField(application.Object, "tenant-project", "spec", "project")).To(Succeed())
+			Expect(k8sClient.Create(ctx, application)).To(Succeed())
+			defer func() { Expect(k8sClient.Delete(ctx, application)).To(Succeed()) }()
+
+			By("Enabling the Argo CD integration")
+			appbundle.Spec.ArgoCD = &appv1alpha1.ArgoCDIntegrationSpec{
+				Enabled:   true,
+				Namespace: "default",
+				RepoURL:   "https://git.example.com/deployments.git",
+			}
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(appbundle.Status.Conditions, "OwnershipConflict")).To(BeTrue())
+			Expect(meta.FindStatusCondition(appbundle.Status.Conditions, "ArgoCDSynced")).To(HaveField("Reason", "ApplicationFailed"))
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(application), application)).To(Succeed())
+			project, _, _ := unstructured.NestedString(application.Object, "spec", "project")
+			Expect(project).To(Equal("tenant-project"))
+		})
+
+		It("should deliver groups through Flux Kustomizations", func() {
+			controllerReconciler := &AppBundleReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+			}
+
+			By("Selecting the Flux backend")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			appbundle.Spec.Backend = appv1alpha1.BackendFlux
+			appbundle.Spec.Flux = &appv1alpha1.FluxBackendSpec{
+				SourceRef: appv1alpha1.FluxSourceReference{Name: "deployments"},
+				Path:      "apps",
+				Prune:     true,
+			}
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+			infrastructureName := render.KustomizationName(appbundle, appbundle.Spec.Groups[0])
+			applicationName := render.KustomizationName(appbundle, appbundle.Spec.Groups[1])
+
+			By("Reporting every Kustomization as ready once it is created, standing in for Flux")
+			done := make(chan struct{})
+			defer close(done)
+			go func() {
+				defer GinkgoRecover()
+				for _, name := range []string{infrastructureName, applicationName} {
+					kustomization := &unstructured.Unstructured{}
+					kustomization.SetGroupVersionKind(schema.GroupVersionKind{
+						Group: "kustomize.toolkit.fluxcd.io", Version: "v1", Kind: "Kustomization",
+					})
+					key := types.NamespacedName{Name: name, Namespace: "default"}
+					for k8sClient.Get(ctx, key, kustomization) != nil {
+						select {
+						case <-done:
+							return
+						case <-time.After(200 * time.Millisecond):
+						}
+					}
+					Expect(unstructured.SetNestedField(kustomization.Object, kustomization.GetGeneration(), "status", "observedGeneration")).To(Succeed())
+					Expect(unstructured.SetNestedSlice(kustomization.Object, []interface{}{map[string]interface{}{
+						"type":               "Ready",
+						"status":             "True",
+						"reason":             "ReconciliationSucceeded",
+						"message":            "Applied revision: main@sha1:abc123",
+						"lastTransitionTime": metav1.Now().UTC().Format(time.RFC3339),
+					}}, "status", "conditions")).To(Succeed())
+					Expect(k8sClient.Status().Update(ctx, kustomization)).To(Succeed())
+				}
+			}()
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(appbundle.Status.Phase).To(Equal(appv1alpha1.PhaseDeployed))
+			Expect(appbundle.Status.GroupStatuses).To(HaveLen(2))
+			Expect(appbundle.Status.GroupStatuses[1].ComponentStatuses).To(ContainElement(And(
+				HaveField("Name", "service"),
+				HaveField("ResourceRef.Kind", "Kustomization"),
+				HaveField("ResourceRef.Name", applicationName),
+			)))
+
+			applicationKustomization := &unstructured.Unstructured{}
+			applicationKustomization.SetGroupVersionKind(schema.GroupVersionKind{
+				Group: "kustomize.toolkit.fluxcd.io", Version: "v1", Kind: "Kustomization",
+			})
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: applicationName, Namespace: "default"}, applicationKustomization)).To(Succeed())
+			path, _, _ := unstructured.NestedString(applicationKustomization.Object, "spec", "path")
+			Expect(path).To(Equal("./apps/application"))
+			dependsOn, _, _ := unstructured.NestedSlice(applicationKustomization.Object, "spec", "dependsOn")
+			Expect(dependsOn).To(ConsistOf(HaveKeyWithValue("name", infrastructureName)))
+			Expect(applicationKustomization.GetOwnerReferences()).To(ContainElement(HaveField("Name", resourceName)))
+
+			By("Checking only the Kustomizations for drift, since Flux applies the templates")
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(appbundle.Status.Conditions, "Drifted")).To(BeFalse())
+			Expect(appbundle.Status.DriftedResources).To(BeEmpty())
+		})
+
+		It("should deploy every object rendered from a Helm chart", func() {
+			controllerReconciler := &AppBundleReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+				Fetcher: source.NewFetcher(k8sClient, source.WithLocalRepositories(map[string]string{
+					"https://charts.example.com": filepath.Join("testdata", "charts"),
+				})),
+			}
+
+			By("Providing values through a ConfigMap")
+			valuesConfigMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: resourceName + "-values", Namespace: "default"},
+				Data:       map[string]string{"values.yaml": "region: eu-west-1\nlogLevel: warn\n"},
+			}
+			Expect(k8sClient.Create(ctx, valuesConfigMap)).To(Succeed())
+			defer func() { Expect(k8sClient.Delete(ctx, valuesConfigMap)).To(Succeed()) }()
+
+			By("Adding a Helm chart component served from testdata")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			appbundle.Spec.Groups[1].Components = append(appbundle.Spec.Groups[1].Components, appv1alpha1.Component{
+				Name:  "settings",
+				Order: 1,
+				HelmChart: &appv1alpha1.HelmChartSource{
+					Chart:       "settings",
+					Version:     "0.1.0",
+					RepoURL:     "https://charts.example.com",
+					ReleaseName: resourceName,
+					Values:      runtime.RawExtension{Raw: []byte(`{"logLevel":"debug"}`)},
+					ValuesFrom: []appv1alpha1.ValuesReference{
+						{Kind: "ConfigMap", Name: resourceName + "-values", Key: "values.yaml"},
+					},
+				},
+			})
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(appbundle.Status.Phase).To(Equal(appv1alpha1.PhaseDeployed))
+			Expect(appbundle.Status.GroupStatuses[1].ComponentStatuses).To(ContainElement(And(
+				HaveField("Name", "settings"),
+				HaveField("Resources", HaveLen(2)),
+			)))
+
+			By("Merging inline values over values from the ConfigMap")
+			settings := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-settings", Namespace: "default"}, settings)).To(Succeed())
+			Expect(settings.Data).To(HaveKeyWithValue("logLevel", "debug"))
+			Expect(settings.Data).To(HaveKeyWithValue("region", "eu-west-1"))
+			Expect(settings.Labels).To(HaveKeyWithValue("app.example.com/component", "settings"))
+			Expect(settings.Annotations).To(HaveKeyWithValue("argocd.argoproj.io/sync-wave", "101"))
+		})
+
+		It("should deploy the objects built from a kustomization in a ConfigMap", func() {
+			controllerReconciler := &AppBundleReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+			}
+
+			By("Storing a kustomization in a ConfigMap")
+			kustomization := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: resourceName + "-kustomization", Namespace: "default"},
+				Data: map[string]string{
+					"kustomization.yaml": "namePrefix: " + resourceName + "-\n" +
+						"resources:\n- service.yaml\n" +
+						"configMapGenerator:\n- name: settings\n  literals:\n  - LOG_LEVEL=info\n",
+					"service.yaml": "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n" +
+						"spec:\n  selector:\n    app: web\n  ports:\n  - port: 80\n",
+				},
+			}
+			Expect(k8sClient.Create(ctx, kustomization)).To(Succeed())
+			defer func() { Expect(k8sClient.Delete(ctx, kustomization)).To(Succeed()) }()
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			appbundle.Spec.Groups[1].Components = append(appbundle.Spec.Groups[1].Components, appv1alpha1.Component{
+				Name:  "web",
+				Order: 1,
+				Kustomize: &appv1alpha1.KustomizeSource{
+					ConfigMapRef: &appv1alpha1.LocalObjectReference{Name: resourceName + "-kustomization"},
+				},
+			})
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(appbundle.Status.Phase).To(Equal(appv1alpha1.PhaseDeployed))
+			Expect(appbundle.Status.GroupStatuses[1].ComponentStatuses).To(ContainElement(And(
+				HaveField("Name", "web"),
+				HaveField("Resources", ConsistOf(
+					HaveField("Kind", "ConfigMap"),
+					HaveField("Kind", "Service"),
+				)),
+			)))
+
+			service := &corev1.Service{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-web", Namespace: "default"}, service)).To(Succeed())
+			Expect(service.Labels).To(HaveKeyWithValue("app.example.com/component", "web"))
+			Expect(service.Annotations).To(HaveKeyWithValue("argocd.argoproj.io/sync-wave", "101"))
+		})
+
+		It("should substitute parameters and reject undefined references", func() {
+			controllerReconciler := &AppBundleReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+			}
+
+			By("Referencing a parameter that is not declared")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			appbundle.Spec.Groups[0].Components[0].Template = runtime.RawExtension{Raw: []byte(
+				`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"` + resourceName + `-settings"},` +
+					`"data":{"namespace":"${bundle.namespace}","level":"{{ .params.logLevel }}"}}`)}
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).To(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(appbundle.Status.Phase).To(Equal(appv1alpha1.PhaseFailed))
+			Expect(meta.FindStatusCondition(appbundle.Status.Conditions, "Ready")).To(HaveField("Reason", "InvalidParameters"))
+			settings := &corev1.ConfigMap{}
+			settingsKey := types.NamespacedName{Name: resourceName + "-settings", Namespace: "default"}
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, settingsKey, settings))).To(BeTrue())
+
+			By("Declaring the parameter with a default")
+			appbundle.Spec.Parameters = []appv1alpha1.Parameter{
+				{Name: "logLevel", Default: &apiextensionsv1.JSON{Raw: []byte(`"debug"`)}},
+			}
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, settingsKey, settings)).To(Succeed())
+			Expect(settings.Data).To(HaveKeyWithValue("namespace", "default"))
+			Expect(settings.Data).To(HaveKeyWithValue("level", "debug"))
+		})
+
+		It("should pass a component's outputs to later components", func() {
+			controllerReconciler := &AppBundleReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+			}
+
+			By("Exposing the cluster IP of a Service as an output")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			appbundle.Spec.Groups[0].Components = []appv1alpha1.Component{
+				{
+					Name: "redis",
+					Template: runtime.RawExtension{Raw: []byte(
+						`{"apiVersion":"v1","kind":"Service","metadata":{"name":"` + resourceName + `-redis"},` +
+							`"spec":{"ports":[{"port":6379}]}}`)},
+					Outputs: []appv1alpha1.ComponentOutput{
+						{Name: "host", JSONPath: "{.spec.clusterIP}"},
+						{Name: "port", Expression: "object.spec.ports[0].port"},
+					},
+				},
+				{
+					Name:  "client",
+					Order: 1,
+					Template: runtime.RawExtension{Raw: []byte(
+						`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"` + resourceName + `-client"},` +
+							`"data":{"url":"redis://${components.redis.outputs.host}:${components.redis.outputs.port}"}}`)},
+				},
+			}
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Referencing the resolved values")
+			service := &corev1.Service{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-redis", Namespace: "default"}, service)).To(Succeed())
+			Expect(service.Spec.ClusterIP).NotTo(BeEmpty())
+			client := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-client", Namespace: "default"}, client)).To(Succeed())
+			Expect(client.Data).To(HaveKeyWithValue("url", "redis://"+service.Spec.ClusterIP+":6379"))
+
+			By("Recording the outputs in status")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(appbundle.Status.Outputs).To(HaveLen(1))
+			Expect(appbundle.Status.Outputs[0].Component).To(Equal("redis"))
+			Expect(string(appbundle.Status.Outputs[0].Values["host"].Raw)).To(Equal(`"` + service.Spec.ClusterIP + `"`))
+		})
+
+		It("should render a bundle from its AppBundleTemplate and follow template changes", func() {
+			controllerReconciler := &AppBundleReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+			}
+
+			By("Creating a template with a parameter")
+			template := &appv1alpha1.AppBundleTemplate{
+				ObjectMeta: metav1.ObjectMeta{Name: resourceName},
+				Spec: appv1alpha1.AppBundleTemplateSpec{
+					Parameters: []appv1alpha1.Parameter{
+						{Name: "greeting", Default: &apiextensionsv1.JSON{Raw: []byte(`"hello"`)}},
+					},
+					Groups: []appv1alpha1.Group{{
+						Name: "app",
+						Components: []appv1alpha1.Component{{
+							Name: "settings",
+							Template: runtime.RawExtension{Raw: []byte(
+								`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"` + resourceName + `-greeting"},` +
+									`"data":{"greeting":"${params.greeting}"}}`)},
+						}},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, template)).To(Succeed())
+			defer func() { Expect(k8sClient.Delete(ctx, template)).To(Succeed()) }()
+
+			By("Instantiating the bundle from the template with values")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			appbundle.Spec.Groups = nil
+			appbundle.Spec.TemplateRef = &appv1alpha1.TemplateReference{Name: resourceName}
+			appbundle.Spec.Values = map[string]apiextensionsv1.JSON{"greeting": {Raw: []byte(`"hi"`)}}
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			greeting := &corev1.ConfigMap{}
+			greetingKey := types.NamespacedName{Name: resourceName + "-greeting", Namespace: "default"}
+			Expect(k8sClient.Get(ctx, greetingKey, greeting)).To(Succeed())
+			Expect(greeting.Data).To(HaveKeyWithValue("greeting", "hi"))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(appbundle.Status.Phase).To(Equal(appv1alpha1.PhaseDeployed))
+			Expect(appbundle.Status.Template).To(HaveField("Generation", template.Generation))
+
+			By("Changing the template")
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName}, template)).To(Succeed())
+			template.Spec.Groups[0].Components[0].Template = runtime.RawExtension{Raw: []byte(
+				`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"` + resourceName + `-greeting"},` +
+					`"data":{"greeting":"${params.greeting}","audience":"world"}}`)}
+			Expect(k8sClient.Update(ctx, template)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, greetingKey, greeting)).To(Succeed())
+			Expect(greeting.Data).To(HaveKeyWithValue("audience", "world"))
+
+			By("Recording every rendered generation in an AppBundleTemplateRevision")
+			firstGeneration := template.Generation - 1
+			revision := &appv1alpha1.AppBundleTemplateRevision{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: fmt.Sprintf("%s-%d", resourceName, firstGeneration)}, revision)).To(Succeed())
+			Expect(revision.Spec.TemplateName).To(Equal(resourceName))
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: fmt.Sprintf("%s-%d", resourceName, template.Generation)}, revision)).To(Succeed())
+
+			By("Pinning the first generation after the status was lost")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			appbundle.Spec.TemplateRef.Generation = firstGeneration
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+			appbundle.Status.Template = nil
+			Expect(k8sClient.Status().Update(ctx, appbundle)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(appbundle.Status.Template).To(HaveField("Generation", firstGeneration))
+			Expect(k8sClient.Get(ctx, greetingKey, greeting)).To(Succeed())
+			Expect(greeting.Data).NotTo(HaveKey("audience"))
+
+			By("Referencing a template that does not exist")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			appbundle.Spec.TemplateRef.Name = resourceName + "-missing"
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).To(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(meta.FindStatusCondition(appbundle.Status.Conditions, "Ready")).To(HaveField("Reason", "TemplateUnavailable"))
+		})
+
 		It("should handle deletion with finalizer cleanup", func() {
 			By("Creating and reconciling the resource first")
 			controllerReconciler := &AppBundleReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
 			}
 
 			// First reconciliation to set up finalizers
@@ -213,6 +1336,628 @@ var _ = Describe("AppBundle Controller", func() {
 				err := k8sClient.Get(ctx, typeNamespacedName, appbundle)
 				return errors.IsNotFound(err)
 			}, "10s", "1s").Should(BeTrue())
+
+			By("Verifying the phase series of the deleted bundle are gone")
+			Expect(bundlePhase.DeletePartialMatch(prometheus.Labels{"namespace": "default", "bundle": resourceName})).To(Equal(0))
+		})
+
+		It("should requeue deletion while objects of a group are terminating", func() {
+			recorder := record.NewFakeRecorder(100)
+			controllerReconciler := &AppBundleReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+			}
+
+			By("Deploying a ConfigMap that a finalizer holds on to")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			appbundle.Spec.Groups[0].Components = []appv1alpha1.Component{{Name: "app", Template: runtime.RawExtension{Raw: []byte(
+				`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"` + resourceName + `-held","finalizers":["example.com/hold"]}}`)}}}
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Requeueing rather than waiting while the ConfigMap terminates")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, appbundle)).To(Succeed())
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(appbundle.Status.Message).To(ContainSubstring("ConfigMap default/" + resourceName + "-held"))
+			finalizing := meta.FindStatusCondition(appbundle.Status.Conditions, conditionFinalizing)
+			Expect(finalizing).NotTo(BeNil())
+			Expect(finalizing.Reason).To(Equal(reasonFinalizing))
+
+			By("Announcing the finalization once across passes")
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			var events []string
+			for len(recorder.Events) > 0 {
+				events = append(events, <-recorder.Events)
+			}
+			var announcements []string
+			Expect(events).To(ContainElement(HavePrefix("Normal Finalizing"), &announcements))
+			Expect(announcements).To(HaveLen(1))
+
+			By("Reporting objects still terminating after the deletion timeout")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			appbundle.DeletionTimestamp = &metav1.Time{Time: time.Now().Add(-deletionTimeout - time.Minute)}
+			result, err = controllerReconciler.waitForDeletion(ctx, appbundle, "default", []string{"ConfigMap default/" + resourceName + "-held"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+			Expect(meta.FindStatusCondition(appbundle.Status.Conditions, conditionFinalizing).Reason).To(Equal(reasonDeletionTimeout))
+			Expect(recorder.Events).To(Receive(HavePrefix("Warning DeletionTimeout")))
+
+			By("Completing the deletion once the ConfigMap is gone")
+			held := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-held", Namespace: "default"}, held)).To(Succeed())
+			held.Finalizers = nil
+			Expect(k8sClient.Update(ctx, held)).To(Succeed())
+			result, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.IsZero()).To(BeTrue())
+			Eventually(func() bool {
+				return errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, appbundle))
+			}, "10s", "1s").Should(BeTrue())
+		})
+
+		It("should run hooks around their group and before deletion", func() {
+			recorder := record.NewFakeRecorder(100)
+			controllerReconciler := &AppBundleReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+			}
+			configMap := func(suffix string) runtime.RawExtension {
+				return runtime.RawExtension{Raw: []byte(
+					`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"` + resourceName + `-` + suffix + `"},"data":{"key":"value"}}`)}
+			}
+
+			By("Deploying a group with PreGroup, PostGroup and PreDelete hooks")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			appbundle.Spec.Groups[0].Components = []appv1alpha1.Component{
+				{Name: "app", Template: configMap("app")},
+				{Name: "migrate", Hook: appv1alpha1.HookPreGroup, Template: configMap("migrate"),
+					HookDeletePolicies: []appv1alpha1.HookDeletePolicy{appv1alpha1.HookDeleteSucceeded}},
+				{Name: "smoke-test", Hook: appv1alpha1.HookPostGroup, Template: configMap("smoke-test")},
+				{Name: "backup", Hook: appv1alpha1.HookPreDelete, Template: configMap("backup")},
+			}
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(appbundle.Status.Phase).To(Equal(appv1alpha1.PhaseDeployed))
+			names := []string{}
+			for _, component := range appbundle.Status.GroupStatuses[0].ComponentStatuses {
+				names = append(names, component.Name)
+			}
+			Expect(names).To(Equal([]string{"migrate", "app", "smoke-test"}))
+
+			By("Deleting hooks according to their delete policy")
+			hook := &corev1.ConfigMap{}
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-migrate", Namespace: "default"}, hook))).To(BeTrue())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-smoke-test", Namespace: "default"}, hook)).To(Succeed())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-backup", Namespace: "default"}, hook))).To(BeTrue())
+
+			By("Not running completed hooks again until the spec changes")
+			Expect(appbundle.Status.CompletedHooks).To(HaveLen(2))
+			for len(recorder.Events) > 0 {
+				<-recorder.Events
+			}
+			// A requeued pass, such as after a canary pause, deploys the group again
+			appbundle.Status.Phase = appv1alpha1.PhaseDeploying
+			appbundle.Status.GroupStatuses = nil
+			Expect(k8sClient.Status().Update(ctx, appbundle)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-migrate", Namespace: "default"}, hook))).To(BeTrue())
+			var rerun []string
+			for len(recorder.Events) > 0 {
+				rerun = append(rerun, <-recorder.Events)
+			}
+			Expect(rerun).NotTo(ContainElement(ContainSubstring("HookStarted")))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+
+			By("Running the PreDelete hook on deletion")
+			Expect(k8sClient.Delete(ctx, appbundle)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			var events []string
+			for len(recorder.Events) > 0 {
+				events = append(events, <-recorder.Events)
+			}
+			Expect(events).To(ContainElement(ContainSubstring("Normal HookSucceeded PreDelete hook infrastructure/backup completed")))
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-backup", Namespace: "default"}, hook))).To(BeTrue())
+		})
+
+		It("should skip PreDelete hooks when the skip annotation is set", func() {
+			recorder := record.NewFakeRecorder(100)
+			controllerReconciler := &AppBundleReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+			}
+
+			By("Deploying a bundle with a PreDelete Job that would never complete")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			appbundle.Spec.Groups[0].Components = []appv1alpha1.Component{{
+				Name: "backup",
+				Hook: appv1alpha1.HookPreDelete,
+				Template: runtime.RawExtension{Raw: []byte(`{"apiVersion":"batch/v1","kind":"Job","metadata":{"name":"` + resourceName +
+					`-backup"},"spec":{"template":{"spec":{"restartPolicy":"Never","containers":[{"name":"backup","image":"busybox"}]}}}}`)},
+			}}
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Deleting the bundle with the skip annotation")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			appbundle.Annotations = map[string]string{appv1alpha1.SkipPreDeleteHooksAnnotation: "true"}
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, appbundle)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(func() bool {
+				return errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, appbundle))
+			}, "10s", "1s").Should(BeTrue())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-backup", Namespace: "default"}, &batchv1.Job{}))).To(BeTrue())
+			var events []string
+			for len(recorder.Events) > 0 {
+				events = append(events, <-recorder.Events)
+			}
+			Expect(events).To(ContainElement(ContainSubstring("Warning HookSkipped PreDelete hooks skipped by the")))
+		})
+
+		It("should roll out Deployments in canary steps and abort on a failed gate", func() {
+			recorder := record.NewFakeRecorder(100)
+			standIn := analysis.NewStandIn(map[string]float64{"error_ratio": 0.5})
+			prometheus := httptest.NewServer(standIn)
+			defer prometheus.Close()
+			controllerReconciler := &AppBundleReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+			}
+
+			// envtest runs no Deployment controller, so report every Deployment as ready
+			readyCtx, stopReady := context.WithCancel(ctx)
+			defer stopReady()
+			go func() {
+				for readyCtx.Err() == nil {
+					deployments := &appsv1.DeploymentList{}
+					if err := k8sClient.List(readyCtx, deployments, client.InNamespace("default")); err == nil {
+						for _, deployment := range deployments.Items {
+							replicas := *deployment.Spec.Replicas
+							if deployment.Status.ReadyReplicas == replicas && deployment.Status.ObservedGeneration == deployment.Generation {
+								continue
+							}
+							deployment.Status.ObservedGeneration = deployment.Generation
+							deployment.Status.Replicas = replicas
+							deployment.Status.ReadyReplicas = replicas
+							deployment.Status.UpdatedReplicas = replicas
+							deployment.Status.AvailableReplicas = replicas
+							_ = k8sClient.Status().Update(readyCtx, &deployment)
+						}
+					}
+					time.Sleep(200 * time.Millisecond)
+				}
+			}()
+			deployment := func(image string) runtime.RawExtension {
+				return runtime.RawExtension{Raw: []byte(`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"` + resourceName +
+					`-web"},"spec":{"replicas":4,"selector":{"matchLabels":{"app":"web"}},"template":{"metadata":{"labels":{"app":"web"}},` +
+					`"spec":{"containers":[{"name":"web","image":"` + image + `"}]}}}}`)}
+			}
+			var pause *metav1.Duration
+			setImage := func(image string) {
+				Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+				appbundle.Spec.Groups[0].Components = []appv1alpha1.Component{{
+					Name:     "web",
+					Template: deployment(image),
+					RolloutStrategy: &appv1alpha1.RolloutStrategy{Canary: &appv1alpha1.CanaryStrategy{
+						Steps: []appv1alpha1.CanaryStep{{Weight: ptr.To[int32](25), Pause: pause}, {Replicas: ptr.To[int32](2)}},
+						Analysis: &appv1alpha1.CanaryAnalysis{
+							Provider: appv1alpha1.MetricProvider{Address: prometheus.URL},
+							Metrics:  []appv1alpha1.AnalysisMetric{{Name: "errors", Query: "error_ratio", SuccessCondition: "result < 0.05"}},
+						},
+					}},
+				}}
+				Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+			}
+			stable := &appsv1.Deployment{}
+			canary := &appsv1.Deployment{}
+			stableName := types.NamespacedName{Name: resourceName + "-web", Namespace: "default"}
+			canaryName := types.NamespacedName{Name: resourceName + "-web-canary", Namespace: "default"}
+
+			// Canary steps requeue until both of their Deployments are ready
+			reconcileCanary := func() (reconcile.Result, error) {
+				for {
+					result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+					if err != nil || result.RequeueAfter == 0 || result.RequeueAfter > canaryReadyPollInterval {
+						return result, err
+					}
+					time.Sleep(200 * time.Millisecond)
+				}
+			}
+
+			By("Creating the Deployment without canary steps")
+			setImage("nginx:1")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, stableName, stable)).To(Succeed())
+			Expect(stable.Annotations).To(HaveKey("app.example.com/template-hash"))
+
+			By("Aborting an update whose metrics miss the success condition")
+			setImage("nginx:2")
+			_, err = reconcileCanary()
+			Expect(err).To(HaveOccurred())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, canaryName, canary))).To(BeTrue())
+			Expect(k8sClient.Get(ctx, stableName, stable)).To(Succeed())
+			Expect(*stable.Spec.Replicas).To(Equal(int32(4)))
+			Expect(stable.Spec.Template.Spec.Containers[0].Image).To(Equal("nginx:1"))
+
+			By("Promoting an update whose metrics succeed")
+			standIn.Set("error_ratio", 0.01)
+			setImage("nginx:3")
+			_, err = reconcileCanary()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, canaryName, canary))).To(BeTrue())
+			Expect(k8sClient.Get(ctx, stableName, stable)).To(Succeed())
+			Expect(*stable.Spec.Replicas).To(Equal(int32(4)))
+			Expect(stable.Spec.Template.Spec.Containers[0].Image).To(Equal("nginx:3"))
+
+			By("Requeueing a pausing step instead of waiting for it")
+			pause = &metav1.Duration{Duration: time.Hour}
+			setImage("nginx:4")
+			result, err := reconcileCanary()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically(">", 59*time.Minute))
+			Expect(k8sClient.Get(ctx, canaryName, canary)).To(Succeed())
+			Expect(*canary.Spec.Replicas).To(Equal(int32(1)))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(appbundle.Status.Phase).To(Equal(appv1alpha1.PhaseDeploying))
+			Expect(appbundle.Status.Canaries).To(HaveLen(1))
+			Expect(appbundle.Status.Canaries[0].Step).To(Equal(int32(0)))
+			Expect(appbundle.Status.Canaries[0].StableReplicas).To(Equal(int64(4)))
+			Expect(appbundle.Status.Canaries[0].StepScaledAt).NotTo(BeNil())
+			Expect(appbundle.Status.Canaries[0].StepStartedAt).NotTo(BeNil())
+
+			By("Resuming the rollout from the status once the pause is over")
+			started := metav1.NewTime(time.Now().Add(-2 * time.Hour))
+			appbundle.Status.Canaries[0].StepStartedAt = &started
+			Expect(k8sClient.Status().Update(ctx, appbundle)).To(Succeed())
+			_, err = reconcileCanary()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, canaryName, canary))).To(BeTrue())
+			Expect(k8sClient.Get(ctx, stableName, stable)).To(Succeed())
+			Expect(*stable.Spec.Replicas).To(Equal(int32(4)))
+			Expect(stable.Spec.Template.Spec.Containers[0].Image).To(Equal("nginx:4"))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(appbundle.Status.Phase).To(Equal(appv1alpha1.PhaseDeployed))
+			Expect(appbundle.Status.Canaries).To(BeEmpty())
+
+			var events []string
+			for len(recorder.Events) > 0 {
+				events = append(events, <-recorder.Events)
+			}
+			Expect(events).To(ContainElement(ContainSubstring("Warning CanaryAborted canary step 1 of Deployment " + resourceName + "-web failed: metric errors is 0.5")))
+			Expect(events).To(ContainElement(ContainSubstring("Normal CanaryStep Canary step 2/2 of Deployment " + resourceName + "-web: 2 of 4 replicas")))
+			Expect(events).To(ContainElement(ContainSubstring("Normal CanaryPromoted")))
+		})
+
+		It("should restore the stable replicas when the first canary step never becomes ready", func() {
+			recorder := record.NewFakeRecorder(100)
+			controllerReconciler := &AppBundleReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+			}
+			canaryName := types.NamespacedName{Name: resourceName + "-web-canary", Namespace: "default"}
+
+			// envtest runs no Deployment controller: the stable Deployment is reported as ready,
+			// while the canary exceeds its progress deadline
+			readyCtx, stopReady := context.WithCancel(ctx)
+			defer stopReady()
+			go func() {
+				for readyCtx.Err() == nil {
+					deployments := &appsv1.DeploymentList{}
+					if err := k8sClient.List(readyCtx, deployments, client.InNamespace("default")); err == nil {
+						for _, deployment := range deployments.Items {
+							replicas := *deployment.Spec.Replicas
+							deployment.Status.ObservedGeneration = deployment.Generation
+							deployment.Status.Replicas = replicas
+							deployment.Status.UpdatedReplicas = replicas
+							deployment.Status.ReadyReplicas = replicas
+							deployment.Status.AvailableReplicas = replicas
+							if deployment.Name == canaryName.Name {
+								deployment.Status.ReadyReplicas = 0
+								deployment.Status.AvailableReplicas = 0
+								deployment.Status.Conditions = []appsv1.DeploymentCondition{{
+									Type:   appsv1.DeploymentProgressing,
+									Status: corev1.ConditionFalse,
+									Reason: "ProgressDeadlineExceeded",
+								}}
+							}
+							_ = k8sClient.Status().Update(readyCtx, &deployment)
+						}
+					}
+					time.Sleep(200 * time.Millisecond)
+				}
+			}()
+			setImage := func(image string) {
+				Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+				appbundle.Spec.Groups[0].Components = []appv1alpha1.Component{{
+					Name: "web",
+					Template: runtime.RawExtension{Raw: []byte(`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"` + resourceName +
+						`-web"},"spec":{"replicas":4,"selector":{"matchLabels":{"app":"web"}},"template":{"metadata":{"labels":{"app":"web"}},` +
+						`"spec":{"containers":[{"name":"web","image":"` + image + `"}]}}}}`)},
+					RolloutStrategy: &appv1alpha1.RolloutStrategy{Canary: &appv1alpha1.CanaryStrategy{
+						Steps: []appv1alpha1.CanaryStep{{Weight: ptr.To[int32](25)}},
+					}},
+				}}
+				Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+			}
+			stable := &appsv1.Deployment{}
+			stableName := types.NamespacedName{Name: resourceName + "-web", Namespace: "default"}
+
+			// Canary steps requeue until both of their Deployments are ready
+			reconcileCanary := func() (reconcile.Result, error) {
+				for {
+					result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+					if err != nil || result.RequeueAfter == 0 || result.RequeueAfter > canaryReadyPollInterval {
+						return result, err
+					}
+					time.Sleep(200 * time.Millisecond)
+				}
+			}
+
+			By("Creating the Deployment without canary steps")
+			setImage("nginx:1")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Aborting an update whose canary never becomes ready, twice")
+			setImage("nginx:2")
+			for range 2 {
+				_, err = reconcileCanary()
+				Expect(err).To(HaveOccurred())
+				Expect(errors.IsNotFound(k8sClient.Get(ctx, canaryName, &appsv1.Deployment{}))).To(BeTrue())
+				Expect(k8sClient.Get(ctx, stableName, stable)).To(Succeed())
+				Expect(*stable.Spec.Replicas).To(Equal(int32(4)))
+				Expect(stable.Spec.Template.Spec.Containers[0].Image).To(Equal("nginx:1"))
+				Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+				Expect(appbundle.Status.Canaries).To(BeEmpty())
+			}
+
+			var events []string
+			for len(recorder.Events) > 0 {
+				events = append(events, <-recorder.Events)
+			}
+			Expect(events).To(ContainElement(ContainSubstring("Warning CanaryAborted canary step 1 of Deployment " + resourceName +
+				"-web failed: canary did not become ready: deployment exceeded its progress deadline")))
+		})
+
+		It("should refuse objects owned by others unless adoption is forced", func() {
+			controllerReconciler := &AppBundleReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+			}
+
+			By("Creating a ConfigMap that belongs to another AppBundle")
+			existing := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName + "-shared",
+					Namespace: "default",
+					Labels:    map[string]string{"app.example.com/appbundle": "other"},
+				},
+				Data: map[string]string{"owner": "other"},
+			}
+			Expect(k8sClient.Create(ctx, existing)).To(Succeed())
+			defer func() { _ = k8sClient.Delete(ctx, existing) }()
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			appbundle.Spec.Groups[0].Components = []appv1alpha1.Component{{
+				Name: "shared",
+				Template: runtime.RawExtension{Raw: []byte(
+					`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"` + resourceName + `-shared"},"data":{"owner":"` + resourceName + `"}}`)},
+			}}
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).To(HaveOccurred())
+
+			By("Reporting the conflict without touching the object")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			conflict := meta.FindStatusCondition(appbundle.Status.Conditions, "OwnershipConflict")
+			Expect(conflict).NotTo(BeNil())
+			Expect(conflict.Message).To(ContainSubstring("AppBundle default/other"))
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-shared", Namespace: "default"}, existing)).To(Succeed())
+			Expect(existing.Data).To(HaveKeyWithValue("owner", "other"))
+
+			By("Telling apart an AppBundle of the same name in another namespace")
+			existing.Labels = map[string]string{
+				"app.example.com/appbundle":           resourceName,
+				"app.example.com/appbundle-namespace": "elsewhere",
+			}
+			Expect(k8sClient.Update(ctx, existing)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).To(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			conflict = meta.FindStatusCondition(appbundle.Status.Conditions, "OwnershipConflict")
+			Expect(conflict).NotTo(BeNil())
+			Expect(conflict.Message).To(ContainSubstring("AppBundle elsewhere/" + resourceName))
+
+			By("Forcing the adoption")
+			appbundle.Spec.AdoptionPolicy = appv1alpha1.AdoptionPolicyForce
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-shared", Namespace: "default"}, existing)).To(Succeed())
+			Expect(existing.Data).To(HaveKeyWithValue("owner", resourceName))
+			Expect(existing.Labels).To(HaveKeyWithValue("app.example.com/appbundle", resourceName))
+			Expect(existing.Labels).To(HaveKeyWithValue("app.example.com/appbundle-namespace", "default"))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(meta.FindStatusCondition(appbundle.Status.Conditions, "OwnershipConflict")).To(BeNil())
+		})
+
+		It("should keep objects in other namespaces labelled before the bundle namespace was recorded", func() {
+			controllerReconciler := &AppBundleReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+			}
+
+			By("Creating a ConfigMap in another namespace labelled with the bundle name only")
+			namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: resourceName + "-legacy"}}
+			Expect(k8sClient.Create(ctx, namespace)).To(Succeed())
+			existing := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName + "-legacy",
+					Namespace: namespace.Name,
+					Labels:    map[string]string{"app.example.com/appbundle": resourceName},
+				},
+			}
+			Expect(k8sClient.Create(ctx, existing)).To(Succeed())
+			defer func() { _ = k8sClient.Delete(ctx, existing) }()
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			appbundle.Spec.Groups[0].Components = []appv1alpha1.Component{{
+				Name: "legacy",
+				Template: runtime.RawExtension{Raw: []byte(
+					`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"` + resourceName + `-legacy","namespace":"` + namespace.Name + `"},"data":{"key":"value"}}`)},
+			}}
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Keeping the object without a conflict and recording the bundle namespace")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(meta.FindStatusCondition(appbundle.Status.Conditions, "OwnershipConflict")).To(BeNil())
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(existing), existing)).To(Succeed())
+			Expect(existing.Data).To(HaveKeyWithValue("key", "value"))
+			Expect(existing.Labels).To(HaveKeyWithValue("app.example.com/appbundle-namespace", "default"))
+		})
+
+		It("should keep orphaned and retained objects when the bundle is deleted", func() {
+			controllerReconciler := &AppBundleReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+			}
+			configMap := func(suffix string) runtime.RawExtension {
+				return runtime.RawExtension{Raw: []byte(
+					`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"` + resourceName + `-` + suffix + `"},"data":{"key":"value"}}`)}
+			}
+
+			By("Deploying a component per deletion policy")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			appbundle.Spec.DeletionPolicy = appv1alpha1.DeletionPolicyOrphan
+			appbundle.Spec.Groups[0].Components = []appv1alpha1.Component{
+				{Name: "orphaned", Template: configMap("orphaned")},
+				{Name: "retained", Order: 1, Template: configMap("retained"), DeletionPolicy: appv1alpha1.DeletionPolicyRetain},
+				{Name: "deleted", Order: 2, Template: configMap("deleted"), DeletionPolicy: appv1alpha1.DeletionPolicyDelete},
+			}
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Deleting the bundle")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, appbundle)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			orphaned := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-orphaned", Namespace: "default"}, orphaned)).To(Succeed())
+			Expect(orphaned.OwnerReferences).To(BeEmpty())
+			Expect(orphaned.Labels).To(HaveKeyWithValue("app.example.com/component", "orphaned"))
+			retained := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-retained", Namespace: "default"}, retained)).To(Succeed())
+			Expect(retained.OwnerReferences).To(BeEmpty())
+			Expect(retained.Labels).NotTo(HaveKey("app.example.com/component"))
+			deleted := &corev1.ConfigMap{}
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-deleted", Namespace: "default"}, deleted))).To(BeTrue())
+
+			Expect(k8sClient.Delete(ctx, orphaned)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, retained)).To(Succeed())
+		})
+
+		It("should delete cluster-scoped and cross-namespace objects from the inventory", func() {
+			controllerReconciler := &AppBundleReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+			}
+
+			By("Deploying a ClusterRole and a ConfigMap in another namespace")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			appbundle.Spec.Groups = []appv1alpha1.Group{
+				{
+					Name: "rbac",
+					Components: []appv1alpha1.Component{{
+						Name: "role",
+						Template: runtime.RawExtension{Raw: []byte(
+							`{"apiVersion":"rbac.authorization.k8s.io/v1","kind":"ClusterRole","metadata":{"name":"` + resourceName + `"},` +
+								`"rules":[{"apiGroups":[""],"resources":["configmaps"],"verbs":["get"]}]}`)},
+					}},
+				},
+				{
+					Name:  "config",
+					Order: 1,
+					Components: []appv1alpha1.Component{{
+						Name: "settings",
+						Template: runtime.RawExtension{Raw: []byte(
+							`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"` + resourceName + `","namespace":"kube-public"},` +
+								`"data":{"tier":"shared"}}`)},
+					}},
+				},
+			}
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(appbundle.Status.Inventory).To(ConsistOf(
+				HaveField("Group", "rbac"),
+				HaveField("Group", "config"),
+			))
+
+			By("Dropping the rbac group from the spec")
+			appbundle.Spec.Groups = appbundle.Spec.Groups[1:]
+			Expect(k8sClient.Update(ctx, appbundle)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Deleting the bundle")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, appbundle)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, appbundle)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			role := &unstructured.Unstructured{}
+			role.SetGroupVersionKind(schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"})
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName}, role))).To(BeTrue())
+			settings := &corev1.ConfigMap{}
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName, Namespace: "kube-public"}, settings))).To(BeTrue())
+			Eventually(func() bool {
+				return errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, appbundle))
+			}, "10s", "1s").Should(BeTrue())
 		})
 	})
 })
+
+// movingFetcher reads a new value for every parameter on each read, like a source that
+// changes while a bundle is deployed
+type movingFetcher struct {
+	render.Fetcher
+	reads int
+}
+
+func (f *movingFetcher) Parameters(ctx context.Context, namespace string, sources []appv1alpha1.ParameterSource) (map[string]string, error) {
+	f.reads++
+	return map[string]string{"version": strconv.Itoa(f.reads)}, nil
+}
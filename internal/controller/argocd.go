@@ -0,0 +1,320 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+	"github.com/example/appbundle-operator/internal/render"
+)
+
+const (
+	// conditionArgoCDSynced mirrors the sync status of the bundle's Argo CD Application
+	conditionArgoCDSynced = "ArgoCDSynced"
+	// conditionArgoCDHealthy mirrors the health status of the bundle's Argo CD Application
+	conditionArgoCDHealthy = "ArgoCDHealthy"
+)
+
+// applicationGVK is the GroupVersionKind of Argo CD Applications
+var applicationGVK = schema.FromAPIVersionAndKind(render.ApplicationAPIVersion, render.ApplicationKind)
+
+// reconcileArgoCD creates or updates the bundle's Argo CD Application and mirrors its sync
+// and health status into the bundle's conditions. Failures to manage the Application are
+// reported through the conditions and do not stop the deployment.
+func (r *AppBundleReconciler) reconcileArgoCD(ctx context.Context, appBundle *appv1alpha1.AppBundle) error {
+	if appBundle.Spec.ArgoCD == nil || !appBundle.Spec.ArgoCD.Enabled {
+		return r.removeArgoCDApplication(ctx, appBundle)
+	}
+
+	application, err := r.applyApplication(ctx, appBundle)
+	var changed bool
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to reconcile Argo CD Application")
+		changed = setArgoCDConditions(appBundle, metav1.ConditionFalse, "ApplicationFailed", err.Error())
+		if changed {
+			r.Recorder.Eventf(appBundle, corev1.EventTypeWarning, reasonArgoCDApplicationFailed,
+				"Failed to reconcile Argo CD Application: %v", err)
+		}
+	} else {
+		changed = mirrorApplicationStatus(appBundle, application)
+	}
+
+	if !changed {
+		return nil
+	}
+	return r.updateBundleStatus(ctx, appBundle)
+}
+
+// applyApplication creates or updates the Argo CD Application of the bundle and returns
+// its live state. Only spec, labels and the owner reference are managed; Argo CD owns the status.
+func (r *AppBundleReconciler) applyApplication(ctx context.Context, appBundle *appv1alpha1.AppBundle) (*unstructured.Unstructured, error) {
+	source, err := r.applicationSource(ctx, appBundle)
+	if err != nil {
+		return nil, err
+	}
+	desired := render.Application(appBundle, source)
+
+	application := &unstructured.Unstructured{}
+	application.SetGroupVersionKind(applicationGVK)
+	application.SetName(desired.GetName())
+	application.SetNamespace(desired.GetNamespace())
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, application, func() error {
+		// The Argo CD namespace is shared, so an Application of another bundle is never taken over
+		if application.GetResourceVersion() != "" {
+			if err := r.checkAdoption(ctx, appBundle, desired, application.DeepCopy()); err != nil {
+				return err
+			}
+		}
+		application.Object["spec"] = desired.Object["spec"]
+		labels := application.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		for key, value := range desired.GetLabels() {
+			labels[key] = value
+		}
+		application.SetLabels(labels)
+		// Owner references cannot cross namespaces, so an Application in the Argo CD
+		// namespace is deleted by the finalizer instead
+		if application.GetNamespace() == appBundle.Namespace {
+			return controllerutil.SetControllerReference(bundleOwner(appBundle), application, r.Scheme)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply Application %s/%s: %w", desired.GetNamespace(), desired.GetName(), err)
+	}
+	if result == controllerutil.OperationResultCreated {
+		r.Recorder.Eventf(appBundle, corev1.EventTypeNormal, reasonArgoCDApplicationCreated,
+			"Created Argo CD Application %s/%s", application.GetNamespace(), application.GetName())
+	}
+	return application, nil
+}
+
+// applicationSource resolves the Git location of the bundle's manifests. Without an explicit
+// repoURL, the repository, directory and branch of the Porch downstream Repository are used.
+func (r *AppBundleReconciler) applicationSource(ctx context.Context, appBundle *appv1alpha1.AppBundle) (render.ApplicationSource, error) {
+	spec := appBundle.Spec.ArgoCD
+	source := render.ApplicationSource{
+		RepoURL:        spec.RepoURL,
+		Path:           spec.Path,
+		TargetRevision: spec.TargetRevision,
+	}
+	if source.RepoURL != "" {
+		return source, nil
+	}
+	if appBundle.Spec.PorchIntegration == nil || !appBundle.Spec.PorchIntegration.Enabled {
+		return source, fmt.Errorf("argocd.repoURL is required unless Porch integration is enabled")
+	}
+
+	repository := &unstructured.Unstructured{}
+	repository.SetAPIVersion("config.porch.kpt.dev/v1alpha1")
+	repository.SetKind("Repository")
+	key := types.NamespacedName{Name: render.DownstreamRepository(appBundle), Namespace: porchRepositoryNamespace(appBundle)}
+	if err := r.Get(ctx, key, repository); err != nil {
+		return source, fmt.Errorf("failed to get Porch Repository %s: %w", key, err)
+	}
+	repoURL, _, _ := unstructured.NestedString(repository.Object, "spec", "git", "repo")
+	if repoURL == "" {
+		return source, fmt.Errorf("porch Repository %s is not a Git repository", key)
+	}
+	source.RepoURL = repoURL
+
+	// Porch writes every package into its own directory below the repository directory
+	if source.Path == "" {
+		source.Path, _, _ = unstructured.NestedString(repository.Object, "spec", "git", "directory")
+		source.Recurse = true
+	}
+	if source.TargetRevision == "" {
+		source.TargetRevision, _, _ = unstructured.NestedString(repository.Object, "spec", "git", "branch")
+	}
+	return source, nil
+}
+
+// porchRepositoryNamespace returns the namespace of the bundle's PackageVariants, where the
+// downstream Repository is registered
+func porchRepositoryNamespace(appBundle *appv1alpha1.AppBundle) string {
+	for _, group := range render.Groups(appBundle) {
+		for _, component := range group.Components {
+			if component.PorchPackageRef != nil {
+				return render.PackageVariantNamespace(component)
+			}
+		}
+	}
+	return "default"
+}
+
+// mirrorApplicationStatus copies the sync and health status of the Application into the
+// bundle's conditions and reports whether they changed
+func mirrorApplicationStatus(appBundle *appv1alpha1.AppBundle, application *unstructured.Unstructured) bool {
+	name := application.GetNamespace() + "/" + application.GetName()
+	syncStatus, _, _ := unstructured.NestedString(application.Object, "status", "sync", "status")
+	revision, _, _ := unstructured.NestedString(application.Object, "status", "sync", "revision")
+	healthStatus, _, _ := unstructured.NestedString(application.Object, "status", "health", "status")
+	healthMessage, _, _ := unstructured.NestedString(application.Object, "status", "health", "message")
+
+	synced := metav1.Condition{
+		Type:               conditionArgoCDSynced,
+		Status:             metav1.ConditionUnknown,
+		Reason:             "Pending",
+		Message:            fmt.Sprintf("Argo CD has not reported the sync status of Application %s yet", name),
+		ObservedGeneration: appBundle.Generation,
+	}
+	switch syncStatus {
+	case "":
+	case "Synced":
+		synced.Status = metav1.ConditionTrue
+		synced.Reason = syncStatus
+		synced.Message = fmt.Sprintf("Application %s is synced to revision %s", name, revision)
+	case "OutOfSync":
+		synced.Status = metav1.ConditionFalse
+		synced.Reason = syncStatus
+		synced.Message = fmt.Sprintf("Application %s is out of sync", name)
+	default:
+		synced.Reason = syncStatus
+		synced.Message = fmt.Sprintf("Application %s sync status is %s", name, syncStatus)
+	}
+
+	healthy := metav1.Condition{
+		Type:               conditionArgoCDHealthy,
+		Status:             metav1.ConditionUnknown,
+		Reason:             "Pending",
+		Message:            fmt.Sprintf("Argo CD has not reported the health of Application %s yet", name),
+		ObservedGeneration: appBundle.Generation,
+	}
+	switch healthStatus {
+	case "":
+	case "Unknown":
+		healthy.Reason = healthStatus
+		healthy.Message = fmt.Sprintf("Application %s health is unknown", name)
+	default:
+		healthy.Status = metav1.ConditionFalse
+		if healthStatus == "Healthy" {
+			healthy.Status = metav1.ConditionTrue
+		}
+		healthy.Reason = healthStatus
+		healthy.Message = fmt.Sprintf("Application %s is %s", name, healthStatus)
+	}
+	if healthMessage != "" {
+		healthy.Message += ": " + healthMessage
+	}
+
+	syncChanged := meta.SetStatusCondition(&appBundle.Status.Conditions, synced)
+	healthChanged := meta.SetStatusCondition(&appBundle.Status.Conditions, healthy)
+	return syncChanged || healthChanged
+}
+
+// setArgoCDConditions sets both Argo CD conditions to the same status and reports whether they changed
+func setArgoCDConditions(appBundle *appv1alpha1.AppBundle, status metav1.ConditionStatus, reason, message string) bool {
+	changed := false
+	for _, conditionType := range []string{conditionArgoCDSynced, conditionArgoCDHealthy} {
+		if meta.SetStatusCondition(&appBundle.Status.Conditions, metav1.Condition{
+			Type:               conditionType,
+			Status:             status,
+			Reason:             reason,
+			Message:            message,
+			ObservedGeneration: appBundle.Generation,
+		}) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// removeArgoCDApplication deletes the Application of a bundle whose Argo CD integration was
+// disabled and clears the mirrored conditions. Bundles that never had an Application are
+// left alone, so clusters without Argo CD are not queried.
+func (r *AppBundleReconciler) removeArgoCDApplication(ctx context.Context, appBundle *appv1alpha1.AppBundle) error {
+	if meta.FindStatusCondition(appBundle.Status.Conditions, conditionArgoCDSynced) == nil {
+		return nil
+	}
+	if err := r.deleteArgoCDApplication(ctx, appBundle); err != nil {
+		return err
+	}
+	meta.RemoveStatusCondition(&appBundle.Status.Conditions, conditionArgoCDSynced)
+	meta.RemoveStatusCondition(&appBundle.Status.Conditions, conditionArgoCDHealthy)
+	return r.updateBundleStatus(ctx, appBundle)
+}
+
+// deleteArgoCDApplication deletes the Argo CD Application of a bundle if it exists and
+// belongs to the bundle
+func (r *AppBundleReconciler) deleteArgoCDApplication(ctx context.Context, appBundle *appv1alpha1.AppBundle) error {
+	application := &unstructured.Unstructured{}
+	application.SetGroupVersionKind(applicationGVK)
+	err := r.Get(ctx, types.NamespacedName{Name: render.ApplicationName(appBundle), Namespace: render.ApplicationNamespace(appBundle)}, application)
+	if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if owned, _, err := r.objectOwnership(ctx, appBundle, application); err != nil || !owned {
+		return err
+	}
+	err = r.Delete(ctx, application)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete Application %s/%s: %w", application.GetNamespace(), application.GetName(), err)
+	}
+	r.Recorder.Eventf(appBundle, corev1.EventTypeNormal, reasonResourceDeleted,
+		"Deleted Argo CD Application %s/%s", application.GetNamespace(), application.GetName())
+	return nil
+}
+
+// applicationToAppBundle maps an Argo CD Application to the AppBundle it was created for
+func applicationToAppBundle(_ context.Context, obj client.Object) []reconcile.Request {
+	name, namespace, ok := applicationBundle(obj)
+	if !ok || namespace == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}}
+}
+
+// applicationToClusterAppBundle maps an Argo CD Application to the ClusterAppBundle it was
+// created for, whose namespace label is empty
+func applicationToClusterAppBundle(_ context.Context, obj client.Object) []reconcile.Request {
+	name, namespace, ok := applicationBundle(obj)
+	if !ok || namespace != "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: name}}}
+}
+
+// applicationBundle returns the name and namespace of the bundle an Application was created
+// for, and false if its labels do not name one
+func applicationBundle(obj client.Object) (string, string, bool) {
+	labels := obj.GetLabels()
+	name := labels[render.AppBundleLabel]
+	namespace, ok := labels[render.AppBundleNamespaceLabel]
+	return name, namespace, ok && name != ""
+}
@@ -0,0 +1,223 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+	"github.com/example/appbundle-operator/internal/render"
+)
+
+// deliveryBackend delivers the components of a group to the cluster
+type deliveryBackend interface {
+	// deployGroup deploys every component of the group and waits for them to become ready
+	deployGroup(ctx context.Context, appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group) (appv1alpha1.GroupStatus, error)
+}
+
+// componentDeployer deploys a single component of a group
+type componentDeployer func(ctx context.Context, appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group, component appv1alpha1.Component, baseSyncWave int) (appv1alpha1.ComponentStatus, error)
+
+// deployPass holds what the groups deployed by one reconciliation share
+type deployPass struct {
+	// configs holds the configuration hashes of the bundle, computed on first use
+	configs *render.ConfigHashes
+	// applied holds the objects applied for each group, in order, which the revision of
+	// the rollout records
+	applied map[string][]*unstructured.Unstructured
+}
+
+// newDeployPass returns the state of a new reconciliation
+func newDeployPass() *deployPass {
+	return &deployPass{applied: map[string][]*unstructured.Unstructured{}}
+}
+
+// configHashes returns the configuration hashes of the bundle
+func (p *deployPass) configHashes(ctx context.Context, appBundle *appv1alpha1.AppBundle, fetcher render.Fetcher) *render.ConfigHashes {
+	if p.configs == nil {
+		p.configs = render.BundleConfigHashes(ctx, appBundle, fetcher)
+	}
+	return p.configs
+}
+
+// recordApplied records an object applied for a group. Passes that are not recorded as a
+// revision, such as the PreDelete hooks, are nil.
+func (p *deployPass) recordApplied(group string, obj *unstructured.Unstructured) {
+	if p == nil {
+		return
+	}
+	p.applied[group] = append(p.applied[group], obj)
+}
+
+// backendFor returns the delivery backend selected by the AppBundle
+func (r *AppBundleReconciler) backendFor(appBundle *appv1alpha1.AppBundle, pass *deployPass) deliveryBackend {
+	switch render.Backend(appBundle) {
+	case appv1alpha1.BackendFlux:
+		return &fluxBackend{r: r, pass: pass}
+	case appv1alpha1.BackendPorch:
+		return &porchBackend{r: r, pass: pass}
+	default:
+		return &directBackend{r: r, pass: pass}
+	}
+}
+
+// directBackend creates and updates the rendered templates
+type directBackend struct {
+	r    *AppBundleReconciler
+	pass *deployPass
+}
+
+func (b *directBackend) deployGroup(ctx context.Context, appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group) (appv1alpha1.GroupStatus, error) {
+	return b.r.reconcileGroup(ctx, appBundle, group, b.deployComponent, b.pass)
+}
+
+func (b *directBackend) deployComponent(ctx context.Context, appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group, component appv1alpha1.Component, baseSyncWave int) (appv1alpha1.ComponentStatus, error) {
+	if component.PorchPackageRef != nil {
+		err := fmt.Errorf("component %s references a Porch package, which the Direct backend cannot deliver", component.Name)
+		return appv1alpha1.ComponentStatus{Name: component.Name, Phase: appv1alpha1.PhaseFailed, Message: err.Error()}, err
+	}
+	return b.r.reconcileComponent(ctx, appBundle, group, component, baseSyncWave, b.pass)
+}
+
+// porchBackend delivers components that reference a Porch package through PackageVariants
+// and applies the templates of all other components
+type porchBackend struct {
+	r    *AppBundleReconciler
+	pass *deployPass
+}
+
+func (b *porchBackend) deployGroup(ctx context.Context, appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group) (appv1alpha1.GroupStatus, error) {
+	return b.r.reconcileGroup(ctx, appBundle, group, b.deployComponent, b.pass)
+}
+
+func (b *porchBackend) deployComponent(ctx context.Context, appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group, component appv1alpha1.Component, baseSyncWave int) (appv1alpha1.ComponentStatus, error) {
+	if component.PorchPackageRef != nil {
+		return b.r.reconcileComponentWithPorch(ctx, appBundle, group, component, baseSyncWave, b.pass)
+	}
+	return b.r.reconcileComponent(ctx, appBundle, group, component, baseSyncWave, b.pass)
+}
+
+// fluxBackend creates a Flux Kustomization per group; Flux applies the group's manifests
+// from the configured source once the Kustomization of the previous group is ready
+type fluxBackend struct {
+	r    *AppBundleReconciler
+	pass *deployPass
+}
+
+func (b *fluxBackend) deployGroup(ctx context.Context, appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group) (appv1alpha1.GroupStatus, error) {
+	logger := log.FromContext(ctx)
+	r := b.r
+
+	groupStarted := metav1.Now()
+	groupStatus := appv1alpha1.GroupStatus{
+		Name:      group.Name,
+		Phase:     appv1alpha1.PhaseDeploying,
+		StartedAt: &groupStarted,
+	}
+	fail := func(err error) (appv1alpha1.GroupStatus, error) {
+		groupCompleted := metav1.Now()
+		groupStatus.Phase = appv1alpha1.PhaseFailed
+		groupStatus.Message = err.Error()
+		groupStatus.CompletedAt = &groupCompleted
+		r.Recorder.Eventf(appBundle, corev1.EventTypeWarning, reasonGroupFailed, "Group %s failed: %v", group.Name, err)
+		return groupStatus, err
+	}
+
+	r.Recorder.Eventf(appBundle, corev1.EventTypeNormal, reasonGroupStarted,
+		"Deploying group %s (order %d, %d components)", group.Name, group.Order, len(group.Components))
+
+	kustomization, err := fluxKustomizationFor(appBundle, group)
+	if err != nil {
+		return fail(err)
+	}
+	r.setOwnerReference(ctx, appBundle, kustomization)
+
+	logger.Info("Applying Flux Kustomization", "group", group.Name, "name", kustomization.GetName())
+	applied := kustomization.DeepCopy()
+	operation, err := r.applyObject(ctx, appBundle, kustomization)
+	if err != nil {
+		r.countApplyError(appBundle, group.Name, "", kustomization.GroupVersionKind())
+		return fail(fmt.Errorf("failed to apply Kustomization %s: %w", kustomization.GetName(), err))
+	}
+	b.pass.recordApplied(group.Name, applied)
+	r.Recorder.Eventf(appBundle, corev1.EventTypeNormal, reasonKustomizationApplied,
+		"%s Kustomization %s/%s for group %s", operationVerb(operation),
+		kustomization.GetNamespace(), kustomization.GetName(), group.Name)
+
+	if err := r.waitForResourceReady(ctx, kustomization); err != nil {
+		return fail(fmt.Errorf("kustomization %s not ready: %w", kustomization.GetName(), err))
+	}
+
+	// Flux applies every component of the group at once, so they share the group's timing
+	groupCompleted := metav1.Now()
+	ref := &appv1alpha1.ResourceReference{
+		APIVersion: kustomization.GetAPIVersion(),
+		Kind:       kustomization.GetKind(),
+		Name:       kustomization.GetName(),
+		Namespace:  kustomization.GetNamespace(),
+	}
+	for _, component := range render.SortedComponents(group) {
+		groupStatus.ComponentStatuses = append(groupStatus.ComponentStatuses, appv1alpha1.ComponentStatus{
+			Name:        component.Name,
+			Phase:       appv1alpha1.PhaseDeployed,
+			Message:     fmt.Sprintf("Delivered by Flux Kustomization %s", kustomization.GetName()),
+			ResourceRef: ref,
+			StartedAt:   &groupStarted,
+			CompletedAt: &groupCompleted,
+		})
+	}
+
+	groupStatus.Phase = appv1alpha1.PhaseDeployed
+	groupStatus.Message = "All components deployed successfully"
+	groupStatus.CompletedAt = &groupCompleted
+	r.Recorder.Eventf(appBundle, corev1.EventTypeNormal, reasonGroupCompleted,
+		"Group %s deployed successfully", group.Name)
+	return groupStatus, nil
+}
+
+// fluxKustomizationFor renders the Kustomization of a group, depending on the previous group
+func fluxKustomizationFor(appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group) (*unstructured.Unstructured, error) {
+	groups, err := render.FluxBundle(appBundle)
+	if err != nil {
+		return nil, err
+	}
+	for _, rendered := range groups {
+		if rendered.Name == group.Name {
+			return rendered.Objects[0], nil
+		}
+	}
+	return nil, fmt.Errorf("group %s not found", group.Name)
+}
+
+// isKustomizationReady checks if a Flux Kustomization has applied its current spec and
+// reports Ready
+func (r *AppBundleReconciler) isKustomizationReady(obj *unstructured.Unstructured) (bool, error) {
+	observedGeneration, _, err := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+	if err != nil {
+		return false, err
+	}
+	if observedGeneration < obj.GetGeneration() {
+		return false, nil
+	}
+	return r.isPodReady(obj)
+}
@@ -0,0 +1,501 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+	"github.com/example/appbundle-operator/internal/analysis"
+)
+
+const (
+	// canaryLabel marks a canary Deployment and its pods, keeping them apart from the
+	// stable Deployment's selector
+	canaryLabel = "app.example.com/canary"
+	// canarySuffix is appended to the name of a Deployment to name its canary
+	canarySuffix = "-canary"
+	// templateHashAnnotation records the pod template a Deployment was last rolled out with
+	templateHashAnnotation = "app.example.com/template-hash"
+	// canaryReadyTimeout bounds how long the Deployments of a canary step may take to become
+	// ready before the step fails
+	canaryReadyTimeout = 5 * time.Minute
+	// canaryReadyPollInterval is how often a canary step checks whether its Deployments are
+	// ready
+	canaryReadyPollInterval = 5 * time.Second
+)
+
+// usesCanary reports whether obj is rolled out through the canary steps of its component
+func usesCanary(component appv1alpha1.Component, obj *unstructured.Unstructured) bool {
+	return component.RolloutStrategy != nil && component.RolloutStrategy.Canary != nil && obj.GetKind() == "Deployment"
+}
+
+// canaryPause is returned while a canary step pauses or waits for its Deployments to
+// become ready. Instead of blocking, the reconciliation is requeued once the pause is over,
+// or to check on the Deployments again.
+type canaryPause struct {
+	kind, name string
+	step       int
+	until      time.Time
+	// unready is set while the step waits for its Deployments rather than its pause
+	unready bool
+}
+
+func (e *canaryPause) Error() string {
+	if e.unready {
+		return fmt.Sprintf("canary step %d of %s %s waits for its Deployments to become ready", e.step, e.kind, e.name)
+	}
+	return fmt.Sprintf("canary step %d of %s %s pauses until %s", e.step, e.kind, e.name, e.until.UTC().Format(time.RFC3339))
+}
+
+// remaining returns how long the pause still runs
+func (e *canaryPause) remaining() time.Duration {
+	return max(time.Until(e.until), time.Second)
+}
+
+// asCanaryPause reports whether err is a pausing canary step
+func asCanaryPause(err error) (*canaryPause, bool) {
+	var pause *canaryPause
+	return pause, goerrors.As(err, &pause)
+}
+
+// runCanarySteps moves the replicas of a changed Deployment to its new pod template in the
+// steps of the component's canary strategy. The new template runs in a canary Deployment
+// next to the stable one, which is scaled down by as many replicas. Every step waits for
+// both to become ready, pauses, and then checks the analysis metrics. A failed step, or one
+// whose Deployments are not ready within canaryReadyTimeout, removes the canary and
+// restores the stable replicas.
+//
+// The progress is recorded in the bundle's status. A step that waits for its Deployments or
+// pauses returns a canaryPause, and the next reconciliation resumes the rollout where it
+// stopped.
+//
+// Deployments created for the first time, or whose pod template did not change, skip the
+// steps. Afterwards obj is applied as usual, which moves the stable Deployment to the new
+// template, and the canary is removed once it is ready.
+func (r *AppBundleReconciler) runCanarySteps(ctx context.Context, appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group, component appv1alpha1.Component, obj *unstructured.Unstructured) error {
+	logger := log.FromContext(ctx)
+
+	hash, err := podTemplateHash(obj)
+	if err != nil {
+		return err
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[templateHashAnnotation] = hash
+	obj.SetAnnotations(annotations)
+
+	stable := &unstructured.Unstructured{}
+	stable.SetGroupVersionKind(obj.GroupVersionKind())
+	if err := r.Get(ctx, types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}, stable); err != nil {
+		if errors.IsNotFound(err) {
+			forgetCanary(appBundle, group, component, obj)
+			return nil
+		}
+		return fmt.Errorf("failed to get %s %s: %w", obj.GetKind(), obj.GetName(), err)
+	}
+	// Deployments rolled out before the annotation existed are updated in place once
+	previous, found := stable.GetAnnotations()[templateHashAnnotation]
+	if !found || previous == hash {
+		forgetCanary(appBundle, group, component, obj)
+		return nil
+	}
+	if err := r.checkAdoption(ctx, appBundle, obj, stable); err != nil {
+		return err
+	}
+
+	canary := component.RolloutStrategy.Canary
+	var provider analysis.Provider
+	if canary.Analysis != nil {
+		if provider, err = r.metricProvider()(canary.Analysis.Provider); err != nil {
+			return err
+		}
+	}
+
+	// A changed template starts over, but the stable replicas are kept from the rollout it
+	// replaces, since the stable Deployment is scaled down by now
+	state, found := canaryState(appBundle, group, component, obj)
+	if !found || state.TemplateHash != hash {
+		if !found {
+			state.StableReplicas = deploymentReplicas(stable)
+		}
+		state.TemplateHash = hash
+		state.Step = 0
+		state.StepScaledAt = nil
+		state.StepStartedAt = nil
+	}
+
+	total := deploymentReplicas(obj)
+	for int(state.Step) < len(canary.Steps) {
+		i := int(state.Step)
+		step := canary.Steps[i]
+		if state.StepStartedAt == nil {
+			if state.StepScaledAt == nil {
+				canaryReplicas := canaryStepReplicas(step, total)
+				logger.Info("Running canary step", "group", group.Name, "component", component.Name, "name", obj.GetName(),
+					"step", i+1, "canaryReplicas", canaryReplicas)
+				r.Recorder.Eventf(appBundle, corev1.EventTypeNormal, reasonCanaryStep,
+					"Canary step %d/%d of %s %s: %d of %d replicas on the new version",
+					i+1, len(canary.Steps), obj.GetKind(), obj.GetName(), canaryReplicas, total)
+
+				// The stable replicas are recorded before the first scale-down, so that a failed
+				// step, or a later pass after a crash, restores them
+				if _, recorded := canaryState(appBundle, group, component, obj); !recorded {
+					setCanaryState(appBundle, state)
+					if err := r.updateBundleStatus(ctx, appBundle); err != nil {
+						return err
+					}
+				}
+				if err := r.canaryStep(ctx, appBundle, obj, canaryReplicas, total-canaryReplicas); err != nil {
+					return r.failCanary(ctx, appBundle, group, component, obj, state, err)
+				}
+				scaled := metav1.Now()
+				state.StepScaledAt = &scaled
+				setCanaryState(appBundle, state)
+				if err := r.updateBundleStatus(ctx, appBundle); err != nil {
+					return err
+				}
+			}
+
+			// Both Deployments are checked once per pass rather than waited for
+			ready, err := r.canaryStepReady(ctx, obj)
+			if err != nil {
+				return r.failCanary(ctx, appBundle, group, component, obj, state, err)
+			}
+			if !ready {
+				if time.Since(state.StepScaledAt.Time) > canaryReadyTimeout {
+					return r.failCanary(ctx, appBundle, group, component, obj, state,
+						fmt.Errorf("canary and stable Deployment did not become ready within %s", canaryReadyTimeout))
+				}
+				return &canaryPause{kind: obj.GetKind(), name: obj.GetName(), step: i + 1,
+					until: time.Now().Add(canaryReadyPollInterval), unready: true}
+			}
+			started := metav1.Now()
+			state.StepStartedAt = &started
+			setCanaryState(appBundle, state)
+			if err := r.updateBundleStatus(ctx, appBundle); err != nil {
+				return err
+			}
+		}
+
+		if step.Pause != nil {
+			if until := state.StepStartedAt.Add(step.Pause.Duration); time.Now().Before(until) {
+				return &canaryPause{kind: obj.GetKind(), name: obj.GetName(), step: i + 1, until: until}
+			}
+		}
+		if err := evaluateCanary(ctx, canary.Analysis, provider); err != nil {
+			return r.failCanary(ctx, appBundle, group, component, obj, state, err)
+		}
+		state.Step++
+		state.StepScaledAt = nil
+		state.StepStartedAt = nil
+		setCanaryState(appBundle, state)
+	}
+	return nil
+}
+
+// waitForCanaryPause records a pausing canary step and requeues the bundle once the pause
+// is over, or a step waiting for its Deployments and requeues the bundle to check on them
+// again. The status is only written when the pause or the wait starts, so the update does
+// not trigger a reconciliation on every pass.
+func (r *AppBundleReconciler) waitForCanaryPause(ctx context.Context, appBundle *appv1alpha1.AppBundle, pause *canaryPause) (ctrl.Result, error) {
+	log.FromContext(ctx).Info("Waiting for canary step pause", "kind", pause.kind, "name", pause.name,
+		"step", pause.step, "unready", pause.unready, "remaining", pause.remaining())
+
+	message := fmt.Sprintf("Canary step %d of %s %s pauses until %s", pause.step, pause.kind, pause.name,
+		pause.until.UTC().Format(time.RFC3339))
+	if pause.unready {
+		message = fmt.Sprintf("Canary step %d of %s %s waits for its Deployments to become ready", pause.step, pause.kind, pause.name)
+	}
+	if appBundle.Status.Message != message {
+		appBundle.Status.Phase = appv1alpha1.PhaseDeploying
+		appBundle.Status.Message = message
+		meta.SetStatusCondition(&appBundle.Status.Conditions, metav1.Condition{
+			Type:               "Ready",
+			Status:             metav1.ConditionFalse,
+			Reason:             "CanaryPaused",
+			Message:            message,
+			ObservedGeneration: appBundle.Generation,
+		})
+		if err := r.updateBundleStatus(ctx, appBundle); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{RequeueAfter: pause.remaining()}, nil
+}
+
+// failCanary aborts the rollout of a Deployment after its current step failed
+func (r *AppBundleReconciler) failCanary(ctx context.Context, appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group, component appv1alpha1.Component, obj *unstructured.Unstructured, state appv1alpha1.CanaryStatus, err error) error {
+	err = fmt.Errorf("canary step %d of %s %s failed: %w", state.Step+1, obj.GetKind(), obj.GetName(), err)
+	r.Recorder.Eventf(appBundle, corev1.EventTypeWarning, reasonCanaryAborted, "%v", err)
+	if revertErr := r.abortCanary(ctx, appBundle, group, component, obj); revertErr != nil {
+		return fmt.Errorf("%w; failed to revert: %v", err, revertErr)
+	}
+	return err
+}
+
+// canaryStep splits the replicas between the canary and the stable Deployment
+func (r *AppBundleReconciler) canaryStep(ctx context.Context, appBundle *appv1alpha1.AppBundle, obj *unstructured.Unstructured, canaryReplicas, stableReplicas int64) error {
+	canary, err := canaryDeployment(obj, canaryReplicas)
+	if err != nil {
+		return err
+	}
+	if _, err := r.applyObject(ctx, appBundle, canary); err != nil {
+		return fmt.Errorf("failed to apply canary: %w", err)
+	}
+	return r.scaleDeployment(ctx, obj, stableReplicas)
+}
+
+// canaryStepReady reports whether both the canary and the stable Deployment of a step are
+// ready. A Deployment that cannot become ready, such as one past its progress deadline, is
+// an error.
+func (r *AppBundleReconciler) canaryStepReady(ctx context.Context, obj *unstructured.Unstructured) (bool, error) {
+	canary := &unstructured.Unstructured{}
+	canary.SetGroupVersionKind(obj.GroupVersionKind())
+	canary.SetName(obj.GetName() + canarySuffix)
+	canary.SetNamespace(obj.GetNamespace())
+	ready, err := r.isResourceReady(ctx, canary)
+	if err != nil {
+		return false, fmt.Errorf("canary did not become ready: %w", err)
+	}
+	if !ready {
+		return false, nil
+	}
+	if ready, err = r.isResourceReady(ctx, obj); err != nil {
+		return false, fmt.Errorf("stable Deployment did not become ready: %w", err)
+	}
+	return ready, nil
+}
+
+// evaluateCanary checks the analysis metrics of a canary step
+func evaluateCanary(ctx context.Context, canaryAnalysis *appv1alpha1.CanaryAnalysis, provider analysis.Provider) error {
+	if canaryAnalysis == nil {
+		return nil
+	}
+	results, err := analysis.Evaluate(ctx, provider, canaryAnalysis.Metrics)
+	if err != nil {
+		return err
+	}
+	if failed, ok := analysis.Failed(results); ok {
+		condition := ""
+		for _, metric := range canaryAnalysis.Metrics {
+			if metric.Name == failed.Metric {
+				condition = metric.SuccessCondition
+			}
+		}
+		return fmt.Errorf("metric %s is %v, expected %s", failed.Metric, failed.Value, condition)
+	}
+	return nil
+}
+
+// abortCanary removes the canary of a Deployment and scales the stable Deployment back to
+// the replicas recorded when the rollout started
+func (r *AppBundleReconciler) abortCanary(ctx context.Context, appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group, component appv1alpha1.Component, obj *unstructured.Unstructured) error {
+	state, found := canaryState(appBundle, group, component, obj)
+	if _, err := r.deleteCanary(ctx, appBundle, obj); err != nil {
+		return err
+	}
+	forgetCanary(appBundle, group, component, obj)
+	if !found {
+		return nil
+	}
+	return r.scaleDeployment(ctx, obj, state.StableReplicas)
+}
+
+// promoteCanary removes the canary once the stable Deployment runs the new pod template
+func (r *AppBundleReconciler) promoteCanary(ctx context.Context, appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group, component appv1alpha1.Component, obj *unstructured.Unstructured) error {
+	deleted, err := r.deleteCanary(ctx, appBundle, obj)
+	if err != nil {
+		return err
+	}
+	forgetCanary(appBundle, group, component, obj)
+	if deleted {
+		r.Recorder.Eventf(appBundle, corev1.EventTypeNormal, reasonCanaryPromoted,
+			"Promoted the canary of %s %s", obj.GetKind(), obj.GetName())
+	}
+	return nil
+}
+
+// deleteCanary deletes the canary of a Deployment, reporting whether there was one
+func (r *AppBundleReconciler) deleteCanary(ctx context.Context, appBundle *appv1alpha1.AppBundle, obj *unstructured.Unstructured) (bool, error) {
+	canary := &unstructured.Unstructured{}
+	canary.SetGroupVersionKind(obj.GroupVersionKind())
+	canary.SetName(obj.GetName() + canarySuffix)
+	canary.SetNamespace(obj.GetNamespace())
+	err := r.Delete(ctx, canary, client.PropagationPolicy(metav1.DeletePropagationBackground))
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to delete canary %s: %w", canary.GetName(), err)
+	}
+	forgetInventory(appBundle, canary)
+	return true, nil
+}
+
+// scaleDeployment sets the replicas of a live Deployment
+func (r *AppBundleReconciler) scaleDeployment(ctx context.Context, obj *unstructured.Unstructured, replicas int64) error {
+	deployment := &unstructured.Unstructured{}
+	deployment.SetGroupVersionKind(obj.GroupVersionKind())
+	if err := r.Get(ctx, types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}, deployment); err != nil {
+		return err
+	}
+	if err := unstructured.SetNestedField(deployment.Object, replicas, "spec", "replicas"); err != nil {
+		return err
+	}
+	if err := r.Update(ctx, deployment); err != nil {
+		return fmt.Errorf("failed to scale %s to %d replicas: %w", deployment.GetName(), replicas, err)
+	}
+	return nil
+}
+
+// canaryState returns the recorded rollout of a Deployment. Without one, it returns a new
+// state for the Deployment.
+func canaryState(appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group, component appv1alpha1.Component, obj *unstructured.Unstructured) (appv1alpha1.CanaryStatus, bool) {
+	for _, state := range appBundle.Status.Canaries {
+		if isCanaryOf(state, group, component, obj) {
+			return state, true
+		}
+	}
+	return appv1alpha1.CanaryStatus{
+		Group:     group.Name,
+		Component: component.Name,
+		Name:      obj.GetName(),
+		Namespace: obj.GetNamespace(),
+	}, false
+}
+
+// setCanaryState records the rollout of a Deployment in the bundle's status
+func setCanaryState(appBundle *appv1alpha1.AppBundle, state appv1alpha1.CanaryStatus) {
+	for i := range appBundle.Status.Canaries {
+		if existing := appBundle.Status.Canaries[i]; existing.Group == state.Group && existing.Component == state.Component &&
+			existing.Name == state.Name && existing.Namespace == state.Namespace {
+			appBundle.Status.Canaries[i] = state
+			return
+		}
+	}
+	appBundle.Status.Canaries = append(appBundle.Status.Canaries, state)
+}
+
+// forgetCanary drops the recorded rollout of a Deployment
+func forgetCanary(appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group, component appv1alpha1.Component, obj *unstructured.Unstructured) {
+	appBundle.Status.Canaries = slices.DeleteFunc(appBundle.Status.Canaries, func(state appv1alpha1.CanaryStatus) bool {
+		return isCanaryOf(state, group, component, obj)
+	})
+	if len(appBundle.Status.Canaries) == 0 {
+		appBundle.Status.Canaries = nil
+	}
+}
+
+// isCanaryOf reports whether state records the rollout of obj
+func isCanaryOf(state appv1alpha1.CanaryStatus, group appv1alpha1.Group, component appv1alpha1.Component, obj *unstructured.Unstructured) bool {
+	return state.Group == group.Name && state.Component == component.Name &&
+		state.Name == obj.GetName() && state.Namespace == obj.GetNamespace()
+}
+
+// metricProvider returns the function creating metric providers, defaulting to
+// analysis.NewProvider
+func (r *AppBundleReconciler) metricProvider() analysis.ProviderFunc {
+	if r.MetricProvider == nil {
+		return analysis.NewProvider
+	}
+	return r.MetricProvider
+}
+
+// canaryDeployment copies a Deployment into its canary. The canary label is added to the
+// selector, so the Deployments do not claim each other's pods, while the pods keep the
+// labels a Service selects them by.
+func canaryDeployment(obj *unstructured.Unstructured, replicas int64) (*unstructured.Unstructured, error) {
+	canary := obj.DeepCopy()
+	canary.SetName(obj.GetName() + canarySuffix)
+	canary.SetResourceVersion("")
+	labels := canary.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[canaryLabel] = "true"
+	canary.SetLabels(labels)
+	annotations := canary.GetAnnotations()
+	delete(annotations, templateHashAnnotation)
+	canary.SetAnnotations(annotations)
+
+	if err := unstructured.SetNestedField(canary.Object, replicas, "spec", "replicas"); err != nil {
+		return nil, err
+	}
+	if err := unstructured.SetNestedField(canary.Object, "true", "spec", "selector", "matchLabels", canaryLabel); err != nil {
+		return nil, err
+	}
+	if err := unstructured.SetNestedField(canary.Object, "true", "spec", "template", "metadata", "labels", canaryLabel); err != nil {
+		return nil, err
+	}
+	return canary, nil
+}
+
+// canaryStepReplicas returns how many of a Deployment's replicas a step moves to the canary.
+// Weights are rounded up, so every step runs at least one canary replica.
+func canaryStepReplicas(step appv1alpha1.CanaryStep, total int64) int64 {
+	var replicas int64
+	switch {
+	case step.Replicas != nil:
+		replicas = int64(*step.Replicas)
+	case step.Weight != nil:
+		replicas = (total*int64(*step.Weight) + 99) / 100
+	}
+	return max(1, min(replicas, total))
+}
+
+// deploymentReplicas returns the replicas of a Deployment, which default to one
+func deploymentReplicas(obj *unstructured.Unstructured) int64 {
+	replicas, found, err := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	if err != nil || !found {
+		return 1
+	}
+	return replicas
+}
+
+// podTemplateHash hashes the pod template of a workload
+func podTemplateHash(obj *unstructured.Unstructured) (string, error) {
+	template, _, err := unstructured.NestedFieldNoCopy(obj.Object, "spec", "template")
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(template)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
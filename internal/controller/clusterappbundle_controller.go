@@ -0,0 +1,261 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+	"github.com/example/appbundle-operator/internal/render"
+)
+
+// ClusterAppBundleReconciler reconciles ClusterAppBundles with the AppBundle machinery. A
+// ClusterAppBundle is reconciled as an AppBundle without a namespace; writes, owner
+// references and events are directed back to the ClusterAppBundle.
+type ClusterAppBundleReconciler struct {
+	AppBundleReconciler
+}
+
+// +kubebuilder:rbac:groups=app.example.com,resources=clusterappbundles,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=app.example.com,resources=clusterappbundles/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=app.example.com,resources=clusterappbundles/finalizers,verbs=update
+
+// Reconcile deploys the groups of a ClusterAppBundle
+func (r *ClusterAppBundleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	clusterAppBundle := &appv1alpha1.ClusterAppBundle{}
+	if err := r.Get(ctx, req.NamespacedName, clusterAppBundle); err != nil {
+		if errors.IsNotFound(err) {
+			logger.Info("ClusterAppBundle resource not found. Ignoring since object must be deleted")
+			forgetBundleMetrics("", req.Name)
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "Failed to get ClusterAppBundle")
+		return ctrl.Result{}, err
+	}
+	return r.reconcileBundle(ctx, asAppBundle(clusterAppBundle))
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ClusterAppBundleReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Recorder = clusterEventRecorder{EventRecorder: r.Recorder}
+	r.Fetcher = namespacedFetcher{Fetcher: r.fetcher(), namespace: r.ClusterNamespace}
+
+	builder := ctrl.NewControllerManagedBy(mgr).
+		For(&appv1alpha1.ClusterAppBundle{}).
+		Owns(&appsv1.Deployment{}).
+		Owns(&appsv1.StatefulSet{}).
+		Owns(&appsv1.DaemonSet{}).
+		Owns(&corev1.Service{}).
+		Owns(&corev1.ConfigMap{}).
+		Watches(&appv1alpha1.AppBundleTemplate{}, handler.EnqueueRequestsFromMapFunc(r.templateToClusterAppBundles))
+
+	// Argo CD Applications of ClusterAppBundles are watched like those of AppBundles
+	if _, err := mgr.GetRESTMapper().RESTMapping(applicationGVK.GroupKind(), applicationGVK.Version); err == nil {
+		application := &unstructured.Unstructured{}
+		application.SetGroupVersionKind(applicationGVK)
+		builder = builder.Watches(application, handler.EnqueueRequestsFromMapFunc(applicationToClusterAppBundle))
+	}
+
+	return builder.Named("clusterappbundle").Complete(r)
+}
+
+// templateToClusterAppBundles maps an AppBundleTemplate to the ClusterAppBundles
+// instantiated from it
+func (r *ClusterAppBundleReconciler) templateToClusterAppBundles(ctx context.Context, obj client.Object) []reconcile.Request {
+	clusterAppBundles := &appv1alpha1.ClusterAppBundleList{}
+	if err := r.List(ctx, clusterAppBundles); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list ClusterAppBundles for template", "template", obj.GetName())
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, clusterAppBundle := range clusterAppBundles.Items {
+		if ref := clusterAppBundle.Spec.TemplateRef; ref != nil && ref.Name == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&clusterAppBundle)})
+		}
+	}
+	return requests
+}
+
+// isClusterBundle reports whether an AppBundle stands for a ClusterAppBundle
+func isClusterBundle(appBundle *appv1alpha1.AppBundle) bool {
+	return appBundle.Namespace == ""
+}
+
+// asAppBundle returns the AppBundle a ClusterAppBundle is reconciled as
+func asAppBundle(clusterAppBundle *appv1alpha1.ClusterAppBundle) *appv1alpha1.AppBundle {
+	return &appv1alpha1.AppBundle{
+		ObjectMeta: clusterAppBundle.ObjectMeta,
+		Spec:       clusterAppBundle.Spec,
+		Status:     clusterAppBundle.Status,
+	}
+}
+
+// asClusterAppBundle returns the ClusterAppBundle an AppBundle stands for
+func asClusterAppBundle(appBundle *appv1alpha1.AppBundle) *appv1alpha1.ClusterAppBundle {
+	return &appv1alpha1.ClusterAppBundle{
+		ObjectMeta: appBundle.ObjectMeta,
+		Spec:       appBundle.Spec,
+		Status:     appBundle.Status,
+	}
+}
+
+// rejectUnsupported fails a ClusterAppBundle that uses what only AppBundles support. The
+// bundle is not retried until its spec or template changes.
+func (r *AppBundleReconciler) rejectUnsupported(ctx context.Context, appBundle *appv1alpha1.AppBundle, err error) (ctrl.Result, error) {
+	r.Recorder.Eventf(appBundle, corev1.EventTypeWarning, reasonUnsupported, "%v", err)
+	appBundle.Status.Phase = appv1alpha1.PhaseFailed
+	appBundle.Status.Message = fmt.Sprintf("Unsupported: %v", err)
+	meta.SetStatusCondition(&appBundle.Status.Conditions, metav1.Condition{
+		Type:               "Ready",
+		Status:             metav1.ConditionFalse,
+		Reason:             reasonUnsupported,
+		Message:            err.Error(),
+		ObservedGeneration: appBundle.Generation,
+	})
+	return ctrl.Result{}, r.updateBundleStatus(ctx, appBundle)
+}
+
+// bundleOwner returns the object that owns what a bundle creates
+func bundleOwner(appBundle *appv1alpha1.AppBundle) client.Object {
+	if isClusterBundle(appBundle) {
+		return asClusterAppBundle(appBundle)
+	}
+	return appBundle
+}
+
+// bundleNamespace returns the namespace of the revisions and plan of a bundle
+func (r *AppBundleReconciler) bundleNamespace(appBundle *appv1alpha1.AppBundle) string {
+	if isClusterBundle(appBundle) {
+		return r.ClusterNamespace
+	}
+	return appBundle.Namespace
+}
+
+// bundleObjectPrefix returns the prefix of the names of a bundle's revisions and plan, which
+// keeps those of a ClusterAppBundle apart from an AppBundle of the same name
+func bundleObjectPrefix(appBundle *appv1alpha1.AppBundle) string {
+	if isClusterBundle(appBundle) {
+		return "cluster-" + appBundle.Name
+	}
+	return appBundle.Name
+}
+
+// updateBundle updates the metadata and spec of a bundle
+func (r *AppBundleReconciler) updateBundle(ctx context.Context, appBundle *appv1alpha1.AppBundle) error {
+	defer keepTemplate(appBundle)()
+	if !isClusterBundle(appBundle) {
+		return r.Update(ctx, appBundle)
+	}
+	clusterAppBundle := asClusterAppBundle(appBundle)
+	if err := r.Update(ctx, clusterAppBundle); err != nil {
+		return err
+	}
+	*appBundle = *asAppBundle(clusterAppBundle)
+	return nil
+}
+
+// updateBundleStatus updates the status of a bundle
+func (r *AppBundleReconciler) updateBundleStatus(ctx context.Context, appBundle *appv1alpha1.AppBundle) error {
+	defer keepTemplate(appBundle)()
+	if !isClusterBundle(appBundle) {
+		return r.Status().Update(ctx, appBundle)
+	}
+	clusterAppBundle := asClusterAppBundle(appBundle)
+	if err := r.Status().Update(ctx, clusterAppBundle); err != nil {
+		return err
+	}
+	*appBundle = *asAppBundle(clusterAppBundle)
+	return nil
+}
+
+// keepTemplate returns a function that restores the resolved template of a bundle after
+// a write replaced its status with the stored one, which does not hold the template's spec
+func keepTemplate(appBundle *appv1alpha1.AppBundle) func() {
+	resolved := appBundle.Status.Template
+	return func() {
+		if stored := appBundle.Status.Template; resolved != nil && resolved.Spec != nil && (stored == nil || stored.Spec == nil) {
+			appBundle.Status.Template = resolved
+		}
+	}
+}
+
+// clusterEventRecorder records the events of an AppBundle that stands for a
+// ClusterAppBundle on the ClusterAppBundle
+type clusterEventRecorder struct {
+	record.EventRecorder
+}
+
+func (r clusterEventRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	r.EventRecorder.Event(eventObject(object), eventtype, reason, message)
+}
+
+func (r clusterEventRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.EventRecorder.Eventf(eventObject(object), eventtype, reason, messageFmt, args...)
+}
+
+func (r clusterEventRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.EventRecorder.AnnotatedEventf(eventObject(object), annotations, eventtype, reason, messageFmt, args...)
+}
+
+// eventObject returns the object an event about object is recorded on
+func eventObject(object runtime.Object) runtime.Object {
+	if appBundle, ok := object.(*appv1alpha1.AppBundle); ok && isClusterBundle(appBundle) {
+		return asClusterAppBundle(appBundle)
+	}
+	return object
+}
+
+// namespacedFetcher reads the ConfigMaps, Secrets and Flux sources that a ClusterAppBundle
+// references from a fixed namespace
+type namespacedFetcher struct {
+	render.Fetcher
+	namespace string
+}
+
+func (f namespacedFetcher) Values(ctx context.Context, _ string, refs []appv1alpha1.ValuesReference) (map[string]interface{}, error) {
+	return f.Fetcher.Values(ctx, f.namespace, refs)
+}
+
+func (f namespacedFetcher) Kustomization(ctx context.Context, _ string, source *appv1alpha1.KustomizeSource) (filesys.FileSystem, error) {
+	return f.Fetcher.Kustomization(ctx, f.namespace, source)
+}
+
+func (f namespacedFetcher) Source(ctx context.Context, _ string, source *appv1alpha1.ComponentSource) (filesys.FileSystem, error) {
+	return f.Fetcher.Source(ctx, f.namespace, source)
+}
+
+func (f namespacedFetcher) Parameters(ctx context.Context, _ string, sources []appv1alpha1.ParameterSource) (map[string]string, error) {
+	return f.Fetcher.Parameters(ctx, f.namespace, sources)
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+	"github.com/example/appbundle-operator/internal/render"
+)
+
+var _ = Describe("ClusterAppBundle Controller", func() {
+	Context("When reconciling a resource", func() {
+		ctx := context.Background()
+
+		It("should own the cluster-scoped and namespaced objects it deploys", func() {
+			name := fmt.Sprintf("platform-%d", time.Now().UnixNano())
+			key := types.NamespacedName{Name: name}
+			controllerReconciler := &ClusterAppBundleReconciler{AppBundleReconciler: AppBundleReconciler{
+				Client:           k8sClient,
+				Scheme:           k8sClient.Scheme(),
+				Recorder:         record.NewFakeRecorder(100),
+				ClusterNamespace: "default",
+			}}
+
+			By("Creating a ClusterAppBundle with a ClusterRole and a ConfigMap")
+			clusterAppBundle := &appv1alpha1.ClusterAppBundle{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Spec: appv1alpha1.AppBundleSpec{
+					Groups: []appv1alpha1.Group{{
+						Name: "platform",
+						Components: []appv1alpha1.Component{
+							{
+								Name: "role",
+								Template: runtime.RawExtension{Raw: []byte(
+									`{"apiVersion":"rbac.authorization.k8s.io/v1","kind":"ClusterRole","metadata":{"name":"` + name + `"},` +
+										`"rules":[{"apiGroups":[""],"resources":["configmaps"],"verbs":["get"]}]}`)},
+							},
+							{
+								Name:  "settings",
+								Order: 1,
+								Template: runtime.RawExtension{Raw: []byte(
+									`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"` + name + `","namespace":"default"},` +
+										`"data":{"tier":"platform"}}`)},
+							},
+						},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, clusterAppBundle)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Checking the owner references")
+			Expect(k8sClient.Get(ctx, key, clusterAppBundle)).To(Succeed())
+			Expect(clusterAppBundle.Status.Phase).To(Equal(appv1alpha1.PhaseDeployed))
+			role := &rbacv1.ClusterRole{}
+			Expect(k8sClient.Get(ctx, key, role)).To(Succeed())
+			Expect(metav1.IsControlledBy(role, clusterAppBundle)).To(BeTrue())
+			settings := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: "default"}, settings)).To(Succeed())
+			Expect(metav1.IsControlledBy(settings, clusterAppBundle)).To(BeTrue())
+
+			By("Recording the revision in the cluster namespace")
+			revision := &appv1alpha1.AppBundleRevision{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "cluster-" + name + "-rev-1", Namespace: "default"}, revision)).To(Succeed())
+			Expect(metav1.IsControlledBy(revision, clusterAppBundle)).To(BeTrue())
+
+			By("Deleting the ClusterAppBundle")
+			Expect(k8sClient.Delete(ctx, clusterAppBundle)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(func() bool {
+				return errors.IsNotFound(k8sClient.Get(ctx, key, clusterAppBundle))
+			}, "10s", "1s").Should(BeTrue())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, key, role))).To(BeTrue())
+		})
+
+		It("should reject what only AppBundles support", func() {
+			name := fmt.Sprintf("platform-%d", time.Now().UnixNano())
+			key := types.NamespacedName{Name: name}
+			controllerReconciler := &ClusterAppBundleReconciler{AppBundleReconciler: AppBundleReconciler{
+				Client:           k8sClient,
+				Scheme:           k8sClient.Scheme(),
+				Recorder:         record.NewFakeRecorder(100),
+				ClusterNamespace: "default",
+			}}
+			settings := appv1alpha1.Component{
+				Name: "settings",
+				Template: runtime.RawExtension{Raw: []byte(
+					`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"` + name + `","namespace":"default"}}`)},
+			}
+
+			By("Rejecting the Porch backend and the Flux backend without a namespace")
+			clusterAppBundle := &appv1alpha1.ClusterAppBundle{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Spec: appv1alpha1.AppBundleSpec{
+					Backend: appv1alpha1.BackendPorch,
+					Groups:  []appv1alpha1.Group{{Name: "platform", Components: []appv1alpha1.Component{settings}}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, clusterAppBundle)).NotTo(Succeed())
+			clusterAppBundle.Spec.Backend = appv1alpha1.BackendFlux
+			clusterAppBundle.Spec.Flux = &appv1alpha1.FluxBackendSpec{SourceRef: appv1alpha1.FluxSourceReference{Name: "platform"}}
+			Expect(k8sClient.Create(ctx, clusterAppBundle)).NotTo(Succeed())
+
+			By("Failing a bundle whose template references a Porch package")
+			template := &appv1alpha1.AppBundleTemplate{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Spec: appv1alpha1.AppBundleTemplateSpec{
+					Groups: []appv1alpha1.Group{{Name: "platform", Components: []appv1alpha1.Component{{
+						Name:            "network",
+						PorchPackageRef: &appv1alpha1.PorchPackageReference{PackageName: "network", Repository: "catalog"},
+					}}}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, template)).To(Succeed())
+			defer func() { Expect(k8sClient.Delete(ctx, template)).To(Succeed()) }()
+			clusterAppBundle = &appv1alpha1.ClusterAppBundle{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Spec:       appv1alpha1.AppBundleSpec{TemplateRef: &appv1alpha1.TemplateReference{Name: name}},
+			}
+			Expect(k8sClient.Create(ctx, clusterAppBundle)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, key, clusterAppBundle)).To(Succeed())
+			Expect(clusterAppBundle.Status.Phase).To(Equal(appv1alpha1.PhaseFailed))
+			Expect(meta.FindStatusCondition(clusterAppBundle.Status.Conditions, "Ready")).To(HaveField("Reason", "Unsupported"))
+
+			Expect(k8sClient.Delete(ctx, clusterAppBundle)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should route the Argo CD Applications of ClusterAppBundles to their controller", func() {
+			application := &unstructured.Unstructured{}
+			application.SetGroupVersionKind(applicationGVK)
+			application.SetLabels(map[string]string{
+				render.AppBundleLabel:          "platform",
+				render.AppBundleNamespaceLabel: "",
+			})
+			Expect(applicationToAppBundle(ctx, application)).To(BeEmpty())
+			Expect(applicationToClusterAppBundle(ctx, application)).To(ConsistOf(
+				reconcile.Request{NamespacedName: types.NamespacedName{Name: "platform"}}))
+
+			application.SetLabels(map[string]string{
+				render.AppBundleLabel:          "my-app",
+				render.AppBundleNamespaceLabel: "default",
+			})
+			Expect(applicationToClusterAppBundle(ctx, application)).To(BeEmpty())
+			Expect(applicationToAppBundle(ctx, application)).To(ConsistOf(
+				reconcile.Request{NamespacedName: types.NamespacedName{Name: "my-app", Namespace: "default"}}))
+		})
+	})
+})
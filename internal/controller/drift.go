@@ -0,0 +1,213 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+	"github.com/example/appbundle-operator/internal/render"
+)
+
+const (
+	// conditionDrifted reports whether deployed resources differ from their templates
+	conditionDrifted = "Drifted"
+	// defaultDriftInterval is used when spec.driftDetection.interval is not set
+	defaultDriftInterval = 5 * time.Minute
+	// missingField marks a drifted resource that no longer exists
+	missingField = "<missing>"
+)
+
+// driftInterval returns how often a deployed bundle is checked for drift (0 disables)
+func driftInterval(appBundle *appv1alpha1.AppBundle) time.Duration {
+	if appBundle.Spec.DriftDetection != nil && appBundle.Spec.DriftDetection.Interval != nil {
+		return appBundle.Spec.DriftDetection.Interval.Duration
+	}
+	return defaultDriftInterval
+}
+
+// reconcileDrift compares the deployed resources of an up-to-date bundle with their
+// rendered templates, reports the result and optionally re-applies drifted resources.
+// Bundles delivered by Flux only have their Kustomizations checked.
+func (r *AppBundleReconciler) reconcileDrift(ctx context.Context, appBundle *appv1alpha1.AppBundle) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var drifted []appv1alpha1.DriftedResource
+	desiredObjects := map[string]*unstructured.Unstructured{}
+	check := func(group, component string, desired *unstructured.Unstructured) error {
+		fields, err := r.detectDrift(ctx, appBundle, desired)
+		if err != nil || len(fields) == 0 {
+			return err
+		}
+		drifted = append(drifted, appv1alpha1.DriftedResource{
+			ResourceReference: appv1alpha1.ResourceReference{
+				APIVersion: desired.GetAPIVersion(),
+				Kind:       desired.GetKind(),
+				Name:       desired.GetName(),
+				Namespace:  desired.GetNamespace(),
+			},
+			Group:     group,
+			Component: component,
+			Fields:    fields,
+		})
+		desiredObjects[objectKey(desired)] = desired
+		return nil
+	}
+
+	if render.Backend(appBundle) == appv1alpha1.BackendFlux {
+		// Flux applies the manifests of the groups and corrects their drift itself, so only
+		// the Kustomizations the controller created are checked
+		groups, err := render.FluxBundle(appBundle)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		for _, group := range groups {
+			for _, desired := range group.Objects {
+				if err := check(group.Name, "", desired); err != nil {
+					return ctrl.Result{}, err
+				}
+			}
+		}
+	} else {
+		configs := render.BundleConfigHashes(ctx, appBundle, r.fetcher())
+		for _, group := range render.SortedGroups(appBundle) {
+			baseSyncWave := render.BaseSyncWave(group)
+			for _, component := range render.SortedComponents(group) {
+				// Resources deployed through Porch are reconciled by Porch itself, and hooks only
+				// run at their point of the rollout
+				if component.PorchPackageRef != nil || component.Hook != "" {
+					continue
+				}
+				objects, err := render.Component(ctx, appBundle, group, component, baseSyncWave, r.fetcher(), configs)
+				if err != nil {
+					return ctrl.Result{}, err
+				}
+				for _, desired := range objects {
+					// Helm hooks run once per deployment and may delete themselves afterwards
+					if render.IsHelmHook(desired) {
+						continue
+					}
+					if err := check(group.Name, component.Name, desired); err != nil {
+						return ctrl.Result{}, err
+					}
+				}
+			}
+		}
+	}
+
+	if len(drifted) > 0 && appBundle.Spec.DriftDetection != nil && appBundle.Spec.DriftDetection.SelfHeal {
+		for _, resource := range drifted {
+			desired := desiredObjects[objectKey(resourceObject(resource.ResourceReference))]
+			r.setOwnerReference(ctx, appBundle, desired)
+			if _, err := r.applyObject(ctx, appBundle, desired); err != nil {
+				r.Recorder.Eventf(appBundle, corev1.EventTypeWarning, reasonSelfHealFailed,
+					"Failed to re-apply drifted %s %s: %v", resource.Kind, resource.Name, err)
+				return ctrl.Result{}, err
+			}
+			logger.Info("Re-applied drifted resource", "kind", resource.Kind, "name", resource.Name, "fields", resource.Fields)
+			r.Recorder.Eventf(appBundle, corev1.EventTypeNormal, reasonSelfHealed,
+				"Re-applied drifted %s %s (%s)", resource.Kind, resource.Name, strings.Join(resource.Fields, ", "))
+		}
+		drifted = nil
+	}
+
+	wasDrifted := meta.IsStatusConditionTrue(appBundle.Status.Conditions, conditionDrifted)
+	appBundle.Status.DriftedResources = drifted
+	if len(drifted) > 0 {
+		names := make([]string, 0, len(drifted))
+		for _, resource := range drifted {
+			names = append(names, fmt.Sprintf("%s/%s", resource.Kind, resource.Name))
+		}
+		message := fmt.Sprintf("%d resource(s) differ from their templates: %s", len(drifted), strings.Join(names, ", "))
+		meta.SetStatusCondition(&appBundle.Status.Conditions, metav1.Condition{
+			Type:               conditionDrifted,
+			Status:             metav1.ConditionTrue,
+			Reason:             "DriftDetected",
+			Message:            message,
+			ObservedGeneration: appBundle.Generation,
+		})
+		if !wasDrifted {
+			r.Recorder.Event(appBundle, corev1.EventTypeWarning, reasonDriftDetected, message)
+		}
+	} else {
+		meta.SetStatusCondition(&appBundle.Status.Conditions, metav1.Condition{
+			Type:               conditionDrifted,
+			Status:             metav1.ConditionFalse,
+			Reason:             "InSync",
+			Message:            "All resources match their templates",
+			ObservedGeneration: appBundle.Generation,
+		})
+	}
+
+	if err := r.updateBundleStatus(ctx, appBundle); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: driftInterval(appBundle)}, nil
+}
+
+// detectDrift returns the paths of the fields in which the live object differs from desired,
+// skipping fields covered by ignoreDifferences rules
+func (r *AppBundleReconciler) detectDrift(ctx context.Context, appBundle *appv1alpha1.AppBundle, desired *unstructured.Unstructured) ([]string, error) {
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(desired.GroupVersionKind())
+	err := r.Get(ctx, types.NamespacedName{Name: desired.GetName(), Namespace: desired.GetNamespace()}, live)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return []string{missingField}, nil
+		}
+		return nil, err
+	}
+
+	compared := desired.DeepCopy()
+	if err := render.ApplyIgnoreDifferences(appBundle, compared, live); err != nil {
+		return nil, err
+	}
+	return driftedFields(compared.Object, live.Object), nil
+}
+
+// driftedFields compares the fields set in desired with the live object. Fields only present
+// in the live object (defaults, status, server-managed metadata) are not considered drift.
+func driftedFields(desired, live map[string]interface{}) []string {
+	changes := render.ChangedFields(desired, live)
+	fields := make([]string, 0, len(changes))
+	for _, change := range changes {
+		fields = append(fields, change.Path)
+	}
+	return fields
+}
+
+// resourceObject builds an empty object identified by a resource reference
+func resourceObject(ref appv1alpha1.ResourceReference) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(ref.APIVersion)
+	obj.SetKind(ref.Kind)
+	obj.SetName(ref.Name)
+	obj.SetNamespace(ref.Namespace)
+	return obj
+}
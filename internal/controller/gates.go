@@ -0,0 +1,220 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+	"github.com/example/appbundle-operator/internal/render"
+)
+
+// conditionSuspended is set while spec.suspend is true
+const conditionSuspended = "Suspended"
+
+// reconcileSuspend reports whether the AppBundle is suspended, keeping the Suspended
+// condition in sync with spec.suspend
+func (r *AppBundleReconciler) reconcileSuspend(ctx context.Context, appBundle *appv1alpha1.AppBundle) (bool, error) {
+	if !appBundle.Spec.Suspend {
+		if meta.FindStatusCondition(appBundle.Status.Conditions, conditionSuspended) == nil {
+			return false, nil
+		}
+		meta.RemoveStatusCondition(&appBundle.Status.Conditions, conditionSuspended)
+		r.Recorder.Event(appBundle, corev1.EventTypeNormal, reasonResumed, "Reconciliation resumed")
+		return false, r.updateBundleStatus(ctx, appBundle)
+	}
+
+	if meta.IsStatusConditionTrue(appBundle.Status.Conditions, conditionSuspended) {
+		return true, nil
+	}
+
+	log.FromContext(ctx).Info("AppBundle is suspended, skipping reconciliation")
+	meta.SetStatusCondition(&appBundle.Status.Conditions, metav1.Condition{
+		Type:               conditionSuspended,
+		Status:             metav1.ConditionTrue,
+		Reason:             "SuspendedBySpec",
+		Message:            "Reconciliation is suspended by spec.suspend",
+		ObservedGeneration: appBundle.Generation,
+	})
+	r.Recorder.Event(appBundle, corev1.EventTypeNormal, reasonSuspended, "Reconciliation suspended")
+	return true, r.updateBundleStatus(ctx, appBundle)
+}
+
+// recordGroupApprovals moves approvals given through the approve-group annotation
+// into status.approvedGroups for the current generation
+func (r *AppBundleReconciler) recordGroupApprovals(ctx context.Context, appBundle *appv1alpha1.AppBundle) error {
+	value, ok := appBundle.Annotations[appv1alpha1.ApproveGroupAnnotation]
+	if !ok {
+		return nil
+	}
+
+	// Consume the annotation first: updating the object refreshes its status from the server
+	delete(appBundle.Annotations, appv1alpha1.ApproveGroupAnnotation)
+	if err := r.updateBundle(ctx, appBundle); err != nil {
+		return err
+	}
+
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		setGroupApproval(appBundle, name)
+		r.Recorder.Eventf(appBundle, corev1.EventTypeNormal, reasonGroupApproved,
+			"Group %s approved for generation %d", name, appBundle.Generation)
+	}
+	return r.updateBundleStatus(ctx, appBundle)
+}
+
+// recordRetry consumes the retry annotation and clears the failure of a failed or rolled
+// back AppBundle so that its groups are deployed again. It returns the group to retry when
+// the annotation names one, and an empty string when every group is retried.
+func (r *AppBundleReconciler) recordRetry(ctx context.Context, appBundle *appv1alpha1.AppBundle) (string, error) {
+	logger := log.FromContext(ctx)
+	value, ok := appBundle.Annotations[appv1alpha1.RetryAnnotation]
+	if !ok {
+		return "", nil
+	}
+
+	delete(appBundle.Annotations, appv1alpha1.RetryAnnotation)
+	if err := r.updateBundle(ctx, appBundle); err != nil {
+		return "", err
+	}
+
+	if appBundle.Status.Phase != appv1alpha1.PhaseFailed && appBundle.Status.Phase != appv1alpha1.PhaseRolledBack {
+		logger.Info("Ignoring retry request, AppBundle has not failed", "phase", appBundle.Status.Phase)
+		return "", nil
+	}
+
+	retryGroup := ""
+	for _, group := range render.Groups(appBundle) {
+		if group.Name == value {
+			retryGroup = value
+		}
+	}
+	// Earlier groups were restored to the last revision when the whole bundle was rolled back
+	if retryGroup != "" && appBundle.Status.Phase == appv1alpha1.PhaseRolledBack &&
+		appBundle.Spec.RollbackPolicy == appv1alpha1.RollbackPolicyBundle {
+		logger.Info("Retrying every group, the whole bundle was rolled back", "group", retryGroup)
+		retryGroup = ""
+	}
+
+	if retryGroup != "" {
+		r.Recorder.Eventf(appBundle, corev1.EventTypeNormal, reasonRetryRequested, "Retrying deployment from group %s", retryGroup)
+	} else {
+		r.Recorder.Event(appBundle, corev1.EventTypeNormal, reasonRetryRequested, "Retrying deployment of all groups")
+	}
+	appBundle.Status.Phase = appv1alpha1.PhasePending
+	appBundle.Status.Message = "Retry requested"
+	return retryGroup, r.updateBundleStatus(ctx, appBundle)
+}
+
+// retriedGroupStatus returns the status of a group deployed before the group being retried,
+// which the retry keeps, or nil when the group has to be deployed
+func retriedGroupStatus(previous []appv1alpha1.GroupStatus, group appv1alpha1.Group, retryGroup string) *appv1alpha1.GroupStatus {
+	if retryGroup == "" || group.Name == retryGroup {
+		return nil
+	}
+	for i := range previous {
+		if previous[i].Name == group.Name && previous[i].Phase == appv1alpha1.PhaseDeployed {
+			return &previous[i]
+		}
+	}
+	return nil
+}
+
+// deployedGroupStatus returns the status of a group that was deployed from the spec with
+// the given hash, or nil if the group must be deployed
+func deployedGroupStatus(previous []appv1alpha1.GroupStatus, group appv1alpha1.Group, hash string) *appv1alpha1.GroupStatus {
+	for i := range previous {
+		if previous[i].Name == group.Name && previous[i].Phase == appv1alpha1.PhaseDeployed && previous[i].SpecHash == hash {
+			return &previous[i]
+		}
+	}
+	return nil
+}
+
+// setGroupApproval records an approval for the current generation and drops
+// approvals given for earlier generations
+func setGroupApproval(appBundle *appv1alpha1.AppBundle, name string) {
+	approvals := make([]appv1alpha1.GroupApproval, 0, len(appBundle.Status.ApprovedGroups)+1)
+	for _, approval := range appBundle.Status.ApprovedGroups {
+		if approval.Generation == appBundle.Generation && approval.Name != name {
+			approvals = append(approvals, approval)
+		}
+	}
+	approvals = append(approvals, appv1alpha1.GroupApproval{
+		Name:       name,
+		Generation: appBundle.Generation,
+		ApprovedAt: metav1.Now(),
+	})
+	appBundle.Status.ApprovedGroups = approvals
+}
+
+// isGroupApproved reports whether a group may be deployed for the current generation
+func isGroupApproved(appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group) bool {
+	if group.Approval != appv1alpha1.ApprovalManual {
+		return true
+	}
+	for _, approval := range appBundle.Status.ApprovedGroups {
+		if approval.Name == group.Name && approval.Generation == appBundle.Generation {
+			return true
+		}
+	}
+	return false
+}
+
+// waitForApproval stops the rollout before a manual group that has not been approved yet.
+// The event is only emitted when the bundle starts waiting for the group.
+func (r *AppBundleReconciler) waitForApproval(ctx context.Context, appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group) (ctrl.Result, error) {
+	log.FromContext(ctx).Info("Waiting for manual approval", "group", group.Name)
+
+	message := fmt.Sprintf("Group %s requires approval: annotate with %s=%s", group.Name, appv1alpha1.ApproveGroupAnnotation, group.Name)
+	if appBundle.Status.Message != message {
+		r.Recorder.Eventf(appBundle, corev1.EventTypeNormal, reasonApprovalRequired,
+			"Group %s is waiting for manual approval", group.Name)
+	}
+
+	appBundle.Status.GroupStatuses = append(appBundle.Status.GroupStatuses, appv1alpha1.GroupStatus{
+		Name:    group.Name,
+		Phase:   appv1alpha1.PhaseAwaitingApproval,
+		Message: "Waiting for manual approval",
+	})
+	appBundle.Status.Phase = appv1alpha1.PhaseAwaitingApproval
+	appBundle.Status.Message = message
+	meta.SetStatusCondition(&appBundle.Status.Conditions, metav1.Condition{
+		Type:               "Ready",
+		Status:             metav1.ConditionFalse,
+		Reason:             "AwaitingApproval",
+		Message:            message,
+		ObservedGeneration: appBundle.Generation,
+	})
+
+	if err := r.updateBundleStatus(ctx, appBundle); err != nil {
+		return ctrl.Result{}, err
+	}
+	// The approval annotation or a status change triggers the next reconciliation
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+	"github.com/example/appbundle-operator/internal/render"
+)
+
+// helmHookDeleteTimeout bounds how long a previous run of a Helm hook may take to go away
+const helmHookDeleteTimeout = 1 * time.Minute
+
+// deleteHelmHook removes a previous run of a Helm hook or hook component and waits until it
+// is gone, so the hook runs again instead of failing on immutable fields like a Job's pod
+// template. Hooks the bundle may not adopt are left alone.
+func (r *AppBundleReconciler) deleteHelmHook(ctx context.Context, appBundle *appv1alpha1.AppBundle, obj *unstructured.Unstructured) error {
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(obj.GroupVersionKind())
+	key := types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}
+	if err := r.Get(ctx, key, existing); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if err := r.checkAdoption(ctx, appBundle, obj, existing); err != nil {
+		return err
+	}
+	if err := r.Delete(ctx, existing, client.PropagationPolicy("Background")); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("failed to delete previous run of hook %s %s: %w", obj.GetKind(), obj.GetName(), err)
+	}
+	return wait.PollUntilContextTimeout(ctx, time.Second, helmHookDeleteTimeout, true, func(ctx context.Context) (bool, error) {
+		err := r.Get(ctx, key, existing)
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+}
+
+// cleanUpHelmHook deletes a Helm hook that has run when its delete policy asks for it, as
+// runHook does for hook components. A hook that cannot be deleted is only logged.
+func (r *AppBundleReconciler) cleanUpHelmHook(ctx context.Context, appBundle *appv1alpha1.AppBundle, obj *unstructured.Unstructured, succeeded bool) {
+	if !render.DeleteHelmHook(obj, succeeded) {
+		return
+	}
+	err := r.Delete(ctx, obj, client.PropagationPolicy(metav1.DeletePropagationBackground))
+	if err != nil && !errors.IsNotFound(err) {
+		log.FromContext(ctx).Error(err, "Failed to delete Helm hook", "kind", obj.GetKind(), "name", obj.GetName())
+		return
+	}
+	forgetInventory(appBundle, obj)
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+	"github.com/example/appbundle-operator/internal/render"
+)
+
+const (
+	// conditionPreDeleteHooks records that the PreDelete hooks of a deleted bundle have run
+	// or were skipped, so that a retried finalization does not run them again
+	conditionPreDeleteHooks = "PreDeleteHooksCompleted"
+	// preDeleteHookTimeout bounds how long after a bundle's deletion failed PreDelete hooks
+	// are retried before the deletion goes on without them
+	preDeleteHookTimeout = 10 * time.Minute
+)
+
+// runHook runs a hook component: its objects are applied in order and each must become
+// ready, so Jobs must complete, before the next. The hook's delete policies decide whether
+// the previous run is removed first and whether the objects are removed afterwards. The
+// applied objects are recorded in pass. A hook that already completed for the current spec,
+// before a canary pause, an approval or a retry requeued the bundle, is not run again.
+func (r *AppBundleReconciler) runHook(ctx context.Context, appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group, component appv1alpha1.Component, baseSyncWave int, pass *deployPass) (appv1alpha1.ComponentStatus, error) {
+	logger := log.FromContext(ctx)
+
+	componentStatus := appv1alpha1.ComponentStatus{
+		Name:  component.Name,
+		Phase: appv1alpha1.PhaseDeploying,
+	}
+
+	hash, err := specHash(appBundle)
+	if err != nil {
+		componentStatus.Phase = appv1alpha1.PhaseFailed
+		componentStatus.Message = err.Error()
+		return componentStatus, err
+	}
+	if hookCompleted(appBundle, group.Name, component.Name, hash) {
+		logger.Info("Hook already completed for this spec", "group", group.Name, "component", component.Name)
+		componentStatus.Phase = appv1alpha1.PhaseDeployed
+		componentStatus.Message = "Hook completed successfully"
+		return componentStatus, nil
+	}
+
+	objects, err := render.Component(ctx, appBundle, group, component, baseSyncWave, r.fetcher(), nil)
+	if err != nil {
+		componentStatus.Phase = appv1alpha1.PhaseFailed
+		componentStatus.Message = err.Error()
+		return componentStatus, err
+	}
+
+	r.Recorder.Eventf(appBundle, corev1.EventTypeNormal, reasonHookStarted,
+		"Running %s hook %s/%s", component.Hook, group.Name, component.Name)
+	var runErr error
+	for _, obj := range objects {
+		r.setOwnerReference(ctx, appBundle, obj)
+		if render.HasHookDeletePolicy(component, appv1alpha1.HookDeleteBeforeCreation) {
+			if runErr = r.deleteHelmHook(ctx, appBundle, obj); runErr != nil {
+				break
+			}
+		}
+
+		logger.Info("Running hook", "group", group.Name, "component", component.Name, "kind", obj.GetKind(), "name", obj.GetName())
+		applied := obj.DeepCopy()
+		if _, err := r.applyObject(ctx, appBundle, obj); err != nil {
+			runErr = fmt.Errorf("failed to apply %s %s: %w", obj.GetKind(), obj.GetName(), err)
+			break
+		}
+		pass.recordApplied(group.Name, applied)
+		if err := r.waitForResourceReady(ctx, obj); err != nil {
+			runErr = fmt.Errorf("%s %s did not complete: %w", obj.GetKind(), obj.GetName(), err)
+			break
+		}
+	}
+
+	cleanup := appv1alpha1.HookDeleteSucceeded
+	if runErr != nil {
+		cleanup = appv1alpha1.HookDeleteFailed
+	}
+	if render.HasHookDeletePolicy(component, cleanup) {
+		for _, obj := range objects {
+			err := r.Delete(ctx, obj, client.PropagationPolicy(metav1.DeletePropagationBackground))
+			if err != nil && !errors.IsNotFound(err) {
+				logger.Error(err, "Failed to delete hook", "kind", obj.GetKind(), "name", obj.GetName())
+				continue
+			}
+			forgetInventory(appBundle, obj)
+		}
+	}
+
+	if runErr != nil {
+		componentStatus.Phase = appv1alpha1.PhaseFailed
+		componentStatus.Message = fmt.Sprintf("Hook failed: %v", runErr)
+		r.Recorder.Eventf(appBundle, corev1.EventTypeWarning, reasonHookFailed,
+			"%s hook %s/%s failed: %v", component.Hook, group.Name, component.Name, runErr)
+		return componentStatus, runErr
+	}
+	componentStatus.Phase = appv1alpha1.PhaseDeployed
+	componentStatus.Message = "Hook completed successfully"
+	r.Recorder.Eventf(appBundle, corev1.EventTypeNormal, reasonHookSucceeded,
+		"%s hook %s/%s completed", component.Hook, group.Name, component.Name)
+	// PreDelete hooks are tracked by their condition
+	if component.Hook != appv1alpha1.HookPreDelete {
+		recordCompletedHook(appBundle, group.Name, component.Name, hash)
+	}
+	return componentStatus, nil
+}
+
+// hookCompleted reports whether a hook completed for the spec with the given hash
+func hookCompleted(appBundle *appv1alpha1.AppBundle, group, component, hash string) bool {
+	return slices.Contains(appBundle.Status.CompletedHooks, appv1alpha1.CompletedHook{
+		Group:     group,
+		Component: component,
+		SpecHash:  hash,
+	})
+}
+
+// recordCompletedHook records that a hook completed for the spec with the given hash. The
+// hooks recorded for earlier specs are dropped, as they run again anyway.
+func recordCompletedHook(appBundle *appv1alpha1.AppBundle, group, component, hash string) {
+	completed := slices.DeleteFunc(appBundle.Status.CompletedHooks, func(hook appv1alpha1.CompletedHook) bool {
+		return hook.SpecHash != hash || (hook.Group == group && hook.Component == component)
+	})
+	appBundle.Status.CompletedHooks = append(completed, appv1alpha1.CompletedHook{
+		Group:     group,
+		Component: component,
+		SpecHash:  hash,
+	})
+}
+
+// runBundleHooks runs the hooks of a type of every group in deployment order, stopping at
+// the first that fails
+func (r *AppBundleReconciler) runBundleHooks(ctx context.Context, appBundle *appv1alpha1.AppBundle, hook appv1alpha1.HookType, pass *deployPass) error {
+	for _, h := range render.BundleHooks(appBundle, hook) {
+		if _, err := r.runHook(ctx, appBundle, h.Group, h.Component, render.BaseSyncWave(h.Group), pass); err != nil {
+			return fmt.Errorf("%s hook %s/%s failed: %w", hook, h.Group.Name, h.Component.Name, err)
+		}
+	}
+	return nil
+}
+
+// runPreDeleteHooks runs the PreDelete hooks of a deleted bundle once. The outcome is
+// recorded in the PreDeleteHooksCompleted condition; a failure keeps the bundle and its
+// objects until the hooks succeed on a later attempt. The hooks are skipped, and deletion
+// goes on, when the skip annotation is set, when they are forbidden, such as in a terminating
+// namespace, and when they still fail preDeleteHookTimeout after the deletion.
+func (r *AppBundleReconciler) runPreDeleteHooks(ctx context.Context, appBundle *appv1alpha1.AppBundle) error {
+	if len(render.BundleHooks(appBundle, appv1alpha1.HookPreDelete)) == 0 || preDeleteHooksDone(appBundle) {
+		return nil
+	}
+	if appBundle.Annotations[appv1alpha1.SkipPreDeleteHooksAnnotation] == "true" {
+		return r.skipPreDeleteHooks(ctx, appBundle,
+			fmt.Sprintf("PreDelete hooks skipped by the %s annotation", appv1alpha1.SkipPreDeleteHooksAnnotation))
+	}
+
+	hookErr := r.runBundleHooks(ctx, appBundle, appv1alpha1.HookPreDelete, nil)
+	switch {
+	case hookErr == nil:
+	case errors.IsForbidden(hookErr):
+		return r.skipPreDeleteHooks(ctx, appBundle, fmt.Sprintf("PreDelete hooks skipped, they cannot run: %v", hookErr))
+	case time.Since(appBundle.DeletionTimestamp.Time) > preDeleteHookTimeout:
+		return r.skipPreDeleteHooks(ctx, appBundle,
+			fmt.Sprintf("PreDelete hooks skipped, they did not succeed within %s of the deletion: %v", preDeleteHookTimeout, hookErr))
+	}
+
+	condition := metav1.Condition{
+		Type:               conditionPreDeleteHooks,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonHookSucceeded,
+		Message:            "PreDelete hooks completed",
+		ObservedGeneration: appBundle.Generation,
+	}
+	if hookErr != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = reasonHookFailed
+		condition.Message = hookErr.Error()
+	}
+	meta.SetStatusCondition(&appBundle.Status.Conditions, condition)
+	if err := r.updateBundleStatus(ctx, appBundle); err != nil {
+		return err
+	}
+	return hookErr
+}
+
+// preDeleteHooksDone reports whether the PreDelete hooks of a deleted bundle succeeded or
+// were skipped
+func preDeleteHooksDone(appBundle *appv1alpha1.AppBundle) bool {
+	condition := meta.FindStatusCondition(appBundle.Status.Conditions, conditionPreDeleteHooks)
+	return condition != nil && (condition.Status == metav1.ConditionTrue || condition.Reason == reasonHookSkipped)
+}
+
+// skipPreDeleteHooks gives up on the PreDelete hooks of a deleted bundle, so that its
+// deletion goes on. The PreDeleteHooksCompleted condition is set to False.
+func (r *AppBundleReconciler) skipPreDeleteHooks(ctx context.Context, appBundle *appv1alpha1.AppBundle, message string) error {
+	log.FromContext(ctx).Info("Skipping PreDelete hooks", "reason", message)
+	r.Recorder.Event(appBundle, corev1.EventTypeWarning, reasonHookSkipped, message)
+	meta.SetStatusCondition(&appBundle.Status.Conditions, metav1.Condition{
+		Type:               conditionPreDeleteHooks,
+		Status:             metav1.ConditionFalse,
+		Reason:             reasonHookSkipped,
+		Message:            message,
+		ObservedGeneration: appBundle.Generation,
+	})
+	return r.updateBundleStatus(ctx, appBundle)
+}
@@ -0,0 +1,399 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+	"github.com/example/appbundle-operator/internal/render"
+)
+
+const (
+	// deletionPollInterval is how often finalization checks whether the objects of a group are gone
+	deletionPollInterval = 5 * time.Second
+	// deletionTimeout is how long after the deletion of a bundle finalization waits for its
+	// objects before reporting the ones still terminating as stuck
+	deletionTimeout = 5 * time.Minute
+	// conditionFinalizing reports the progress of the deletion of a bundle
+	conditionFinalizing = "Finalizing"
+)
+
+// recordInventory adds an object to the bundle's inventory unless it is already listed
+func recordInventory(appBundle *appv1alpha1.AppBundle, obj *unstructured.Unstructured, group, component string) {
+	if inInventory(appBundle, obj) {
+		return
+	}
+	appBundle.Status.Inventory = append(appBundle.Status.Inventory, appv1alpha1.InventoryEntry{
+		ResourceReference: appv1alpha1.ResourceReference{
+			APIVersion: obj.GetAPIVersion(),
+			Kind:       obj.GetKind(),
+			Name:       obj.GetName(),
+			Namespace:  obj.GetNamespace(),
+		},
+		Group:     group,
+		Component: component,
+	})
+}
+
+// inInventory reports whether an object is listed in the bundle's inventory
+func inInventory(appBundle *appv1alpha1.AppBundle, obj *unstructured.Unstructured) bool {
+	key := objectKey(obj)
+	return slices.ContainsFunc(appBundle.Status.Inventory, func(entry appv1alpha1.InventoryEntry) bool {
+		return objectKey(resourceObject(entry.ResourceReference)) == key
+	})
+}
+
+// forgetInventory removes a deleted object from the bundle's inventory
+func forgetInventory(appBundle *appv1alpha1.AppBundle, obj *unstructured.Unstructured) {
+	key := objectKey(obj)
+	appBundle.Status.Inventory = slices.DeleteFunc(appBundle.Status.Inventory, func(entry appv1alpha1.InventoryEntry) bool {
+		return objectKey(resourceObject(entry.ResourceReference)) == key
+	})
+}
+
+// finalizeAppBundle deletes everything the bundle created. The objects of the inventory are
+// deleted group by group in reverse deployment order, and each group must be gone before the
+// next is deleted. While objects of a group are still terminating, the bundle is requeued
+// rather than waited for; an error or a requeue keeps the finalizer until every object is
+// verified to be deleted. The Finalizing condition marks the start of the finalization, so
+// that it is announced once rather than on every pass.
+func (r *AppBundleReconciler) finalizeAppBundle(ctx context.Context, appBundle *appv1alpha1.AppBundle) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	if meta.FindStatusCondition(appBundle.Status.Conditions, conditionFinalizing) == nil {
+		logger.Info("Finalizing AppBundle", "name", appBundle.Name)
+		r.Recorder.Event(appBundle, corev1.EventTypeNormal, reasonFinalizing, "Cleaning up resources deployed by this AppBundle")
+		meta.SetStatusCondition(&appBundle.Status.Conditions, metav1.Condition{
+			Type:               conditionFinalizing,
+			Status:             metav1.ConditionTrue,
+			Reason:             reasonFinalizing,
+			Message:            "Cleaning up resources deployed by this AppBundle",
+			ObservedGeneration: appBundle.Generation,
+		})
+		if err := r.updateBundleStatus(ctx, appBundle); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// PreDelete hooks run while everything they might need is still in place
+	if err := r.runPreDeleteHooks(ctx, appBundle); err != nil {
+		r.Recorder.Eventf(appBundle, corev1.EventTypeWarning, reasonDeleteFailed, "%v", err)
+		return ctrl.Result{}, err
+	}
+
+	// Bundles deployed before the inventory was recorded fall back to their current spec
+	inventory := appBundle.Status.Inventory
+	if len(inventory) == 0 {
+		inventory = r.renderedInventory(ctx, appBundle)
+	}
+
+	for _, group := range deletionOrder(appBundle, inventory) {
+		var entries []appv1alpha1.InventoryEntry
+		for _, entry := range inventory {
+			if entry.Group != group {
+				continue
+			}
+			// PackageVariants and Kustomizations carry the policy for the objects they deploy
+			policy := entryDeletionPolicy(appBundle, entry)
+			if policy == appv1alpha1.DeletionPolicyDelete || isDeliveryObject(entry) {
+				entries = append(entries, entry)
+				continue
+			}
+			if err := r.releaseObject(ctx, appBundle, entry, policy); err != nil {
+				r.Recorder.Eventf(appBundle, corev1.EventTypeWarning, reasonDeleteFailed, "Failed to release group %s: %v", group, err)
+				return ctrl.Result{}, err
+			}
+		}
+
+		logger.Info("Deleting group", "group", group, "objects", len(entries))
+		remaining, err := r.deleteInventoryGroup(ctx, appBundle, group, entries)
+		if err != nil {
+			r.Recorder.Eventf(appBundle, corev1.EventTypeWarning, reasonDeleteFailed, "Failed to delete group %s: %v", group, err)
+			return ctrl.Result{}, err
+		}
+		// Objects with finalizers of their own, such as Namespaces, take a while to go away
+		if len(remaining) > 0 {
+			return r.waitForDeletion(ctx, appBundle, group, remaining)
+		}
+
+		// Persist the progress, so that a retry starts with the remaining groups
+		if len(appBundle.Status.Inventory) > 0 {
+			appBundle.Status.Inventory = slices.DeleteFunc(appBundle.Status.Inventory, func(entry appv1alpha1.InventoryEntry) bool {
+				return entry.Group == group
+			})
+			if err := r.updateBundleStatus(ctx, appBundle); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
+	// The Application is usually in the Argo CD namespace, out of reach of owner references
+	if appBundle.Spec.ArgoCD != nil && appBundle.Spec.ArgoCD.Enabled {
+		if err := r.deleteArgoCDApplication(ctx, appBundle); err != nil {
+			r.Recorder.Eventf(appBundle, corev1.EventTypeWarning, reasonDeleteFailed, "%v", err)
+			return ctrl.Result{}, err
+		}
+	}
+
+	logger.Info("AppBundle finalization complete", "name", appBundle.Name)
+	r.Recorder.Event(appBundle, corev1.EventTypeNormal, reasonFinalized, "AppBundle finalization complete")
+	return ctrl.Result{}, nil
+}
+
+// waitForDeletion records the objects of a group that are still terminating and requeues the
+// bundle to check on them again. The status is only written when the remaining objects
+// change, so the update does not trigger a reconciliation on every pass. Objects still
+// terminating deletionTimeout after the deletion of the bundle are reported as stuck with a
+// DeletionTimeout reason and a Warning event; they are still waited for, so that nothing is
+// left behind, but the finalizers holding on to them usually need attention.
+func (r *AppBundleReconciler) waitForDeletion(ctx context.Context, appBundle *appv1alpha1.AppBundle, group string, remaining []string) (ctrl.Result, error) {
+	log.FromContext(ctx).Info("Waiting for deletion", "group", group, "remaining", remaining)
+
+	condition := metav1.Condition{
+		Type:               conditionFinalizing,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonFinalizing,
+		Message:            fmt.Sprintf("Waiting for deletion of group %s: %s", group, strings.Join(remaining, ", ")),
+		ObservedGeneration: appBundle.Generation,
+	}
+	if time.Since(appBundle.DeletionTimestamp.Time) > deletionTimeout {
+		condition.Reason = reasonDeletionTimeout
+		condition.Message = fmt.Sprintf("Objects of group %s are still terminating %s after the deletion: %s",
+			group, deletionTimeout, strings.Join(remaining, ", "))
+	}
+	if appBundle.Status.Message == condition.Message {
+		return ctrl.Result{RequeueAfter: deletionPollInterval}, nil
+	}
+	if condition.Reason == reasonDeletionTimeout {
+		r.Recorder.Event(appBundle, corev1.EventTypeWarning, reasonDeletionTimeout, condition.Message)
+	}
+	appBundle.Status.Message = condition.Message
+	meta.SetStatusCondition(&appBundle.Status.Conditions, condition)
+	if err := r.updateBundleStatus(ctx, appBundle); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: deletionPollInterval}, nil
+}
+
+// deletionOrder returns the groups of an inventory in the order they are deleted: groups no
+// longer in the spec first, then the spec's groups from the last to the first
+func deletionOrder(appBundle *appv1alpha1.AppBundle, inventory []appv1alpha1.InventoryEntry) []string {
+	recorded := map[string]bool{}
+	for _, entry := range inventory {
+		recorded[entry.Group] = true
+	}
+	inSpec := map[string]bool{}
+	for _, group := range render.Groups(appBundle) {
+		inSpec[group.Name] = true
+	}
+
+	var order []string
+	seen := map[string]bool{}
+	for i := len(inventory) - 1; i >= 0; i-- {
+		group := inventory[i].Group
+		if !inSpec[group] && !seen[group] {
+			seen[group] = true
+			order = append(order, group)
+		}
+	}
+	sortedGroups := render.SortedGroups(appBundle)
+	for i := len(sortedGroups) - 1; i >= 0; i-- {
+		if recorded[sortedGroups[i].Name] {
+			order = append(order, sortedGroups[i].Name)
+		}
+	}
+	return order
+}
+
+// entryDeletionPolicy returns the deletion policy of the component an inventory entry was
+// deployed by. Objects of components no longer in the spec follow the bundle's policy.
+func entryDeletionPolicy(appBundle *appv1alpha1.AppBundle, entry appv1alpha1.InventoryEntry) appv1alpha1.DeletionPolicy {
+	component, _ := specComponent(appBundle, entry.Group, entry.Component)
+	return render.DeletionPolicy(appBundle, component)
+}
+
+// specComponent looks up a component of the bundle's spec by group and name
+func specComponent(appBundle *appv1alpha1.AppBundle, groupName, componentName string) (appv1alpha1.Component, bool) {
+	for _, group := range render.Groups(appBundle) {
+		if group.Name != groupName {
+			continue
+		}
+		for _, component := range group.Components {
+			if component.Name == componentName {
+				return component, true
+			}
+		}
+	}
+	return appv1alpha1.Component{}, false
+}
+
+// isDeliveryObject reports whether an inventory entry is a PackageVariant or Flux
+// Kustomization, which are always deleted and pass the deletion policy on to their objects
+func isDeliveryObject(entry appv1alpha1.InventoryEntry) bool {
+	return entry.Kind == "PackageVariant" || entry.Kind == render.KustomizationKind
+}
+
+// releaseObject keeps an object of a deleted bundle by removing the bundle's owner reference,
+// so that garbage collection leaves it alone. Retained objects also lose their tracking
+// labels.
+func (r *AppBundleReconciler) releaseObject(ctx context.Context, appBundle *appv1alpha1.AppBundle, entry appv1alpha1.InventoryEntry, policy appv1alpha1.DeletionPolicy) error {
+	live := resourceObject(entry.ResourceReference)
+	err := r.Get(ctx, types.NamespacedName{Name: live.GetName(), Namespace: live.GetNamespace()}, live)
+	if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	owner := bundleOwner(appBundle)
+	live.SetOwnerReferences(slices.DeleteFunc(live.GetOwnerReferences(), func(ref metav1.OwnerReference) bool {
+		return ref.UID == owner.GetUID()
+	}))
+	verb := "Orphaned"
+	if policy == appv1alpha1.DeletionPolicyRetain {
+		verb = "Retained"
+		labels := live.GetLabels()
+		delete(labels, render.AppBundleLabel)
+		delete(labels, render.AppBundleNamespaceLabel)
+		delete(labels, render.GroupLabel)
+		delete(labels, render.ComponentLabel)
+		live.SetLabels(labels)
+	}
+	if err := r.Update(ctx, live); err != nil {
+		return fmt.Errorf("failed to release %s %s: %w", entry.Kind, inventoryName(entry), err)
+	}
+	r.Recorder.Eventf(appBundle, corev1.EventTypeNormal, reasonResourceRetained,
+		"%s %s %s", verb, entry.Kind, inventoryName(entry))
+	return nil
+}
+
+// deleteInventoryGroup deletes the objects of a group in reverse order and returns those
+// that are not gone yet. Objects that are already terminating are not deleted again.
+func (r *AppBundleReconciler) deleteInventoryGroup(ctx context.Context, appBundle *appv1alpha1.AppBundle, group string, entries []appv1alpha1.InventoryEntry) ([]string, error) {
+	logger := log.FromContext(ctx)
+
+	var errs []error
+	for i := len(entries) - 1; i >= 0; i-- {
+		obj := resourceObject(entries[i].ResourceReference)
+		err := r.Get(ctx, types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}, obj)
+		switch {
+		case errors.IsNotFound(err) || meta.IsNoMatchError(err):
+			continue
+		case err != nil:
+			errs = append(errs, fmt.Errorf("failed to read %s %s: %w", obj.GetKind(), obj.GetName(), err))
+			continue
+		case !obj.GetDeletionTimestamp().IsZero():
+			continue
+		}
+
+		logger.Info("Deleting resource", "group", group, "kind", obj.GetKind(), "name", obj.GetName(), "namespace", obj.GetNamespace())
+		err = r.Delete(ctx, obj, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		switch {
+		case errors.IsNotFound(err) || meta.IsNoMatchError(err):
+		case err != nil:
+			errs = append(errs, fmt.Errorf("failed to delete %s %s: %w", obj.GetKind(), obj.GetName(), err))
+		default:
+			r.Recorder.Eventf(appBundle, corev1.EventTypeNormal, reasonResourceDeleted,
+				"Deleted %s %s", obj.GetKind(), inventoryName(entries[i]))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, utilerrors.NewAggregate(errs)
+	}
+
+	var remaining []string
+	for _, entry := range entries {
+		live := resourceObject(entry.ResourceReference)
+		err := r.Get(ctx, types.NamespacedName{Name: live.GetName(), Namespace: live.GetNamespace()}, live)
+		if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		remaining = append(remaining, fmt.Sprintf("%s %s", entry.Kind, inventoryName(entry)))
+	}
+	return remaining, nil
+}
+
+// inventoryName formats the namespaced name of an inventory entry
+func inventoryName(entry appv1alpha1.InventoryEntry) string {
+	if entry.Namespace == "" {
+		return entry.Name
+	}
+	return entry.Namespace + "/" + entry.Name
+}
+
+// renderedInventory derives an inventory from the current spec for bundles that have none
+// recorded. Components that cannot be rendered are skipped.
+func (r *AppBundleReconciler) renderedInventory(ctx context.Context, appBundle *appv1alpha1.AppBundle) []appv1alpha1.InventoryEntry {
+	logger := log.FromContext(ctx)
+
+	inventoryOf := &appv1alpha1.AppBundle{}
+	configs := render.BundleConfigHashes(ctx, appBundle, r.fetcher())
+	for _, group := range render.SortedGroups(appBundle) {
+		// Flux removes the resources it applied when their Kustomizations are deleted
+		if render.Backend(appBundle) == appv1alpha1.BackendFlux {
+			kustomization := &unstructured.Unstructured{}
+			kustomization.SetAPIVersion(render.KustomizationAPIVersion)
+			kustomization.SetKind(render.KustomizationKind)
+			kustomization.SetName(render.KustomizationName(appBundle, group))
+			kustomization.SetNamespace(render.KustomizationNamespace(appBundle))
+			recordInventory(inventoryOf, kustomization, group.Name, "")
+			continue
+		}
+
+		for _, component := range render.SortedComponents(group) {
+			// Porch removes the resources of a package when its PackageVariant is deleted
+			if component.PorchPackageRef != nil {
+				packageVariant := &unstructured.Unstructured{}
+				packageVariant.SetAPIVersion("config.porch.kpt.dev/v1alpha1")
+				packageVariant.SetKind("PackageVariant")
+				packageVariant.SetName(render.PackageVariantName(component))
+				packageVariant.SetNamespace(render.PackageVariantNamespace(component))
+				recordInventory(inventoryOf, packageVariant, group.Name, component.Name)
+				continue
+			}
+
+			objects, err := render.Component(ctx, appBundle, group, component, render.BaseSyncWave(group), r.fetcher(), configs)
+			if err != nil {
+				logger.Error(err, "Failed to render component during cleanup", "component", component.Name)
+				continue
+			}
+			for _, obj := range objects {
+				recordInventory(inventoryOf, obj, group.Name, component.Name)
+			}
+		}
+	}
+	return inventoryOf.Status.Inventory
+}
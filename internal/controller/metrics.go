@@ -0,0 +1,170 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+)
+
+// MetricsLabelDetail controls how many labels the AppBundle metrics carry.
+// Lower detail levels collapse group and component labels to keep the
+// number of time series bounded on clusters with many bundles.
+type MetricsLabelDetail string
+
+const (
+	// MetricsLabelDetailComponent labels metrics by bundle, group and component
+	MetricsLabelDetailComponent MetricsLabelDetail = "component"
+	// MetricsLabelDetailGroup labels metrics by bundle and group only
+	MetricsLabelDetailGroup MetricsLabelDetail = "group"
+	// MetricsLabelDetailBundle labels metrics by bundle only
+	MetricsLabelDetailBundle MetricsLabelDetail = "bundle"
+)
+
+// ParseMetricsLabelDetail validates a metrics label detail level
+func ParseMetricsLabelDetail(value string) (MetricsLabelDetail, error) {
+	switch detail := MetricsLabelDetail(value); detail {
+	case MetricsLabelDetailComponent, MetricsLabelDetailGroup, MetricsLabelDetailBundle:
+		return detail, nil
+	default:
+		return "", fmt.Errorf("unknown metrics label detail %q (expected component, group or bundle)", value)
+	}
+}
+
+var (
+	// allPhases lists the phases exported by the bundle phase gauge
+	allPhases = []appv1alpha1.DeploymentPhase{
+		appv1alpha1.PhasePending,
+		appv1alpha1.PhaseDeploying,
+		appv1alpha1.PhaseDeployed,
+		appv1alpha1.PhaseFailed,
+		appv1alpha1.PhaseAwaitingApproval,
+		appv1alpha1.PhaseRolledBack,
+		appv1alpha1.PhasePlanned,
+	}
+
+	bundlePhase = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "appbundle_phase",
+		Help: "Current phase of each AppBundle; the series for the active phase is 1, all others are 0.",
+	}, []string{"namespace", "bundle", "phase"})
+
+	componentReadinessDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "appbundle_component_readiness_duration_seconds",
+		Help:    "Time from applying a component until its resource became ready.",
+		Buckets: []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"namespace", "bundle", "group", "component"})
+
+	applyErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "appbundle_apply_errors_total",
+		Help: "Number of failed create or update calls for component resources, by GroupVersionKind.",
+	}, []string{"namespace", "bundle", "group", "component", "api_group", "version", "kind"})
+
+	readinessTimeouts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "appbundle_readiness_timeouts_total",
+		Help: "Number of components that did not become ready before the readiness timeout.",
+	}, []string{"namespace", "bundle", "group", "component"})
+
+	packageVariantWaitDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "appbundle_packagevariant_wait_duration_seconds",
+		Help:    "Time spent waiting for a Porch PackageVariant to become ready.",
+		Buckets: []float64{1, 5, 10, 30, 60, 120, 300},
+	}, []string{"namespace", "bundle", "group", "component"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		bundlePhase,
+		componentReadinessDuration,
+		applyErrors,
+		readinessTimeouts,
+		packageVariantWaitDuration,
+	)
+}
+
+// componentLabels returns the bundle, group and component label values,
+// collapsed according to the configured label detail
+func (r *AppBundleReconciler) componentLabels(appBundle *appv1alpha1.AppBundle, groupName, componentName string) prometheus.Labels {
+	labels := prometheus.Labels{
+		"namespace": appBundle.Namespace,
+		"bundle":    appBundle.Name,
+		"group":     groupName,
+		"component": componentName,
+	}
+	switch r.MetricsLabelDetail {
+	case MetricsLabelDetailBundle:
+		labels["group"] = ""
+		labels["component"] = ""
+	case MetricsLabelDetailGroup:
+		labels["component"] = ""
+	}
+	return labels
+}
+
+// recordBundlePhase exports the current phase of an AppBundle
+func recordBundlePhase(appBundle *appv1alpha1.AppBundle) {
+	// A deleted bundle's series are removed by forgetBundleMetrics and must not come back
+	if appBundle.Status.Phase == "" || !appBundle.DeletionTimestamp.IsZero() {
+		return
+	}
+	for _, phase := range allPhases {
+		value := 0.0
+		if phase == appBundle.Status.Phase {
+			value = 1
+		}
+		bundlePhase.WithLabelValues(appBundle.Namespace, appBundle.Name, string(phase)).Set(value)
+	}
+}
+
+// forgetBundleMetrics removes all series belonging to a deleted AppBundle
+func forgetBundleMetrics(namespace, name string) {
+	labels := prometheus.Labels{"namespace": namespace, "bundle": name}
+	bundlePhase.DeletePartialMatch(labels)
+	componentReadinessDuration.DeletePartialMatch(labels)
+	applyErrors.DeletePartialMatch(labels)
+	readinessTimeouts.DeletePartialMatch(labels)
+	packageVariantWaitDuration.DeletePartialMatch(labels)
+}
+
+// observeComponentReadiness records how long a component took to become ready
+func (r *AppBundleReconciler) observeComponentReadiness(appBundle *appv1alpha1.AppBundle, groupName, componentName string, started time.Time) {
+	componentReadinessDuration.With(r.componentLabels(appBundle, groupName, componentName)).Observe(time.Since(started).Seconds())
+}
+
+// observePackageVariantWait records how long a PackageVariant took to become ready
+func (r *AppBundleReconciler) observePackageVariantWait(appBundle *appv1alpha1.AppBundle, groupName, componentName string, started time.Time) {
+	packageVariantWaitDuration.With(r.componentLabels(appBundle, groupName, componentName)).Observe(time.Since(started).Seconds())
+}
+
+// countApplyError records a failed create or update for a component resource
+func (r *AppBundleReconciler) countApplyError(appBundle *appv1alpha1.AppBundle, groupName, componentName string, gvk schema.GroupVersionKind) {
+	labels := r.componentLabels(appBundle, groupName, componentName)
+	labels["api_group"] = gvk.Group
+	labels["version"] = gvk.Version
+	labels["kind"] = gvk.Kind
+	applyErrors.With(labels).Inc()
+}
+
+// countReadinessTimeout records a component that timed out waiting for readiness
+func (r *AppBundleReconciler) countReadinessTimeout(appBundle *appv1alpha1.AppBundle, groupName, componentName string) {
+	readinessTimeouts.With(r.componentLabels(appBundle, groupName, componentName)).Inc()
+}
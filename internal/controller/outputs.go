@@ -0,0 +1,111 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+	"github.com/example/appbundle-operator/internal/render"
+)
+
+// outputTimeout bounds how long an output may take to appear on a ready object, such as
+// the address of a LoadBalancer Service
+const outputTimeout = 5 * time.Minute
+
+// resolveOutputs extracts the outputs of a deployed component from its live objects and
+// records them in the bundle's status, where later components' templates read them
+func (r *AppBundleReconciler) resolveOutputs(ctx context.Context, appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group, component appv1alpha1.Component, objects []*unstructured.Unstructured) error {
+	logger := log.FromContext(ctx)
+
+	values := make(map[string]apiextensionsv1.JSON, len(component.Outputs))
+	for _, output := range component.Outputs {
+		obj, err := render.OutputObject(objects, output)
+		if err != nil {
+			return err
+		}
+
+		// Poll until the value is set, since status fields are filled in after readiness
+		var value interface{}
+		var lastErr error
+		err = wait.PollUntilContextTimeout(ctx, 2*time.Second, outputTimeout, true, func(ctx context.Context) (bool, error) {
+			live := &unstructured.Unstructured{}
+			live.SetGroupVersionKind(obj.GroupVersionKind())
+			key := types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}
+			if err := r.Get(ctx, key, live); err != nil {
+				return false, client.IgnoreNotFound(err)
+			}
+			value, lastErr = render.EvaluateOutput(output, live)
+			return lastErr == nil, nil
+		})
+		if err != nil {
+			if lastErr != nil {
+				err = lastErr
+			}
+			return fmt.Errorf("failed to resolve output %s of %s %s: %w", output.Name, obj.GetKind(), obj.GetName(), err)
+		}
+
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("output %s: %w", output.Name, err)
+		}
+		values[output.Name] = apiextensionsv1.JSON{Raw: raw}
+		logger.Info("Resolved component output", "group", group.Name, "component", component.Name, "output", output.Name)
+	}
+
+	resolved := appv1alpha1.ComponentOutputs{Group: group.Name, Component: component.Name, Values: values}
+	for i := range appBundle.Status.Outputs {
+		if appBundle.Status.Outputs[i].Group == group.Name && appBundle.Status.Outputs[i].Component == component.Name {
+			appBundle.Status.Outputs[i] = resolved
+			return nil
+		}
+	}
+	appBundle.Status.Outputs = append(appBundle.Status.Outputs, resolved)
+	return nil
+}
+
+// pruneOutputs drops the recorded outputs of components that no longer declare them
+func pruneOutputs(appBundle *appv1alpha1.AppBundle) {
+	declared := map[string]bool{}
+	for _, group := range render.Groups(appBundle) {
+		for _, component := range group.Components {
+			if len(component.Outputs) > 0 {
+				declared[group.Name+"/"+component.Name] = true
+			}
+		}
+	}
+	outputs := appBundle.Status.Outputs[:0]
+	for _, resolved := range appBundle.Status.Outputs {
+		if declared[resolved.Group+"/"+resolved.Component] {
+			outputs = append(outputs, resolved)
+		}
+	}
+	appBundle.Status.Outputs = outputs
+	if len(outputs) == 0 {
+		appBundle.Status.Outputs = nil
+	}
+}
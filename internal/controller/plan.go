@@ -0,0 +1,328 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+	"github.com/example/appbundle-operator/internal/render"
+)
+
+const (
+	// conditionPlanned reports whether the last plan passed the server-side dry-run
+	conditionPlanned = "Planned"
+)
+
+// planConfigMapName returns the name of the ConfigMap holding the diffs of a plan
+func planConfigMapName(appBundle *appv1alpha1.AppBundle) string {
+	return bundleObjectPrefix(appBundle) + "-plan"
+}
+
+// reconcilePlan renders every component, validates it with a server-side dry-run and
+// reports the changes a deployment would make, without modifying any deployed resource
+func (r *AppBundleReconciler) reconcilePlan(ctx context.Context, appBundle *appv1alpha1.AppBundle) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	// The plan is computed once per generation
+	if appBundle.Status.Phase == appv1alpha1.PhasePlanned && appBundle.Status.Plan != nil &&
+		appBundle.Status.Plan.ObservedGeneration == appBundle.Generation {
+		return ctrl.Result{}, nil
+	}
+
+	// Outputs of components that are not deployed yet are stood in for, as in validation
+	snapshot, err := r.renderBundle(ctx, render.WithPendingOutputs(appBundle))
+	if err != nil {
+		return r.updateStatusWithError(ctx, appBundle, fmt.Errorf("failed to render bundle: %w", err))
+	}
+
+	plan := &appv1alpha1.PlanStatus{
+		ObservedGeneration: appBundle.Generation,
+		PlannedAt:          metav1.Now(),
+	}
+	diffs := map[string]string{}
+	rendered := map[string]bool{}
+	for _, group := range snapshot.Groups {
+		for _, content := range group.Objects {
+			obj := &unstructured.Unstructured{Object: content}
+			rendered[objectKey(obj)] = true
+
+			planned, diff, err := r.planObject(ctx, appBundle, obj)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			plan.Objects = append(plan.Objects, planned)
+			if diff != "" {
+				diffs[diffKey(obj)] = diff
+			}
+		}
+	}
+
+	// Objects deployed by the current revision that the spec no longer renders would be pruned
+	revision, err := r.latestRevision(ctx, appBundle)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to load current revision: %w", err)
+	}
+	if revision != nil {
+		previous, err := decodeSnapshot(revision.Spec.Manifests)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to decode revision %d: %w", revision.Spec.Revision, err)
+		}
+		for _, group := range previous.Groups {
+			for _, content := range group.Objects {
+				obj := &unstructured.Unstructured{Object: content}
+				if rendered[objectKey(obj)] {
+					continue
+				}
+				planned, diff, err := r.planPrune(ctx, obj)
+				if err != nil {
+					return ctrl.Result{}, err
+				}
+				if planned != nil {
+					plan.Objects = append(plan.Objects, *planned)
+					diffs[diffKey(obj)] = diff
+				}
+			}
+		}
+	}
+
+	var rejected []string
+	for _, planned := range plan.Objects {
+		switch planned.Action {
+		case appv1alpha1.PlanActionCreate:
+			plan.Create++
+		case appv1alpha1.PlanActionUpdate:
+			plan.Update++
+		case appv1alpha1.PlanActionNoOp:
+			plan.NoOp++
+		case appv1alpha1.PlanActionPrune:
+			plan.Prune++
+		}
+		if planned.Message != "" {
+			rejected = append(rejected, fmt.Sprintf("%s/%s", planned.Kind, planned.Name))
+		}
+	}
+
+	if err := r.writePlanDiffs(ctx, appBundle, diffs); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to write plan diffs: %w", err)
+	}
+	plan.DiffConfigMap = planConfigMapName(appBundle)
+
+	summary := fmt.Sprintf("Plan: %d to create, %d to update, %d unchanged, %d to prune",
+		plan.Create, plan.Update, plan.NoOp, plan.Prune)
+	logger.Info("Computed plan", "create", plan.Create, "update", plan.Update, "noOp", plan.NoOp, "prune", plan.Prune)
+
+	appBundle.Status.Plan = plan
+	appBundle.Status.Phase = appv1alpha1.PhasePlanned
+	appBundle.Status.Message = summary
+	if len(rejected) > 0 {
+		message := fmt.Sprintf("Server-side dry-run rejected %d object(s): %s", len(rejected), strings.Join(rejected, ", "))
+		meta.SetStatusCondition(&appBundle.Status.Conditions, metav1.Condition{
+			Type:               conditionPlanned,
+			Status:             metav1.ConditionFalse,
+			Reason:             "DryRunFailed",
+			Message:            message,
+			ObservedGeneration: appBundle.Generation,
+		})
+		r.Recorder.Event(appBundle, corev1.EventTypeWarning, reasonPlanFailed, message)
+	} else {
+		meta.SetStatusCondition(&appBundle.Status.Conditions, metav1.Condition{
+			Type:               conditionPlanned,
+			Status:             metav1.ConditionTrue,
+			Reason:             "PlanReady",
+			Message:            summary,
+			ObservedGeneration: appBundle.Generation,
+		})
+		r.Recorder.Event(appBundle, corev1.EventTypeNormal, reasonPlanReady, summary)
+	}
+
+	if err := r.updateBundleStatus(ctx, appBundle); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// planObject determines the change a deployment would make to a rendered object and
+// validates it with a server-side dry-run of the same create or update. It returns the
+// planned change and its diff.
+func (r *AppBundleReconciler) planObject(ctx context.Context, appBundle *appv1alpha1.AppBundle, desired *unstructured.Unstructured) (appv1alpha1.PlannedObject, string, error) {
+	planned := appv1alpha1.PlannedObject{
+		ResourceReference: appv1alpha1.ResourceReference{
+			APIVersion: desired.GetAPIVersion(),
+			Kind:       desired.GetKind(),
+			Name:       desired.GetName(),
+			Namespace:  desired.GetNamespace(),
+		},
+		Group:     desired.GetLabels()["app.example.com/group"],
+		Component: desired.GetLabels()["app.example.com/component"],
+		Unknown:   render.PendingFields(desired.Object),
+	}
+	// The server cannot validate values that are not known yet
+	dryRun := len(planned.Unknown) == 0
+	r.setOwnerReference(ctx, appBundle, desired)
+
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(desired.GroupVersionKind())
+	err := r.Get(ctx, types.NamespacedName{Name: desired.GetName(), Namespace: desired.GetNamespace()}, live)
+	if err != nil && !errors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+		return planned, "", err
+	}
+	if err != nil {
+		planned.Action = appv1alpha1.PlanActionCreate
+		if !dryRun {
+			return planned, formatObject("+ ", render.RedactSecret(desired.Object)), nil
+		}
+		if err := r.Create(ctx, desired.DeepCopy(), client.DryRunAll); err != nil {
+			planned.Message = err.Error()
+		}
+		return planned, formatObject("+ ", render.RedactSecret(desired.Object)), nil
+	}
+
+	compared := desired.DeepCopy()
+	if err := render.ApplyIgnoreDifferences(appBundle, compared, live); err != nil {
+		return planned, "", err
+	}
+	// The dry-run makes the same update as a deployment, so the object it returns holds the
+	// server's defaults, the changes of mutating webhooks and none of the fields the update removes
+	applied := compared.DeepCopy()
+	applied.SetResourceVersion(live.GetResourceVersion())
+	if dryRun {
+		if err := r.Update(ctx, applied, client.DryRunAll); err != nil {
+			planned.Message = err.Error()
+			dryRun = false
+		}
+	}
+	if !dryRun {
+		applied = compared
+	}
+
+	changes := render.ChangedFields(applied.Object, live.Object)
+	if dryRun {
+		changes = append(changes, render.RemovedFields(applied.Object, live.Object)...)
+		sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	}
+	changes = render.RedactSecretChanges(desired, changes)
+	if len(changes) == 0 {
+		planned.Action = appv1alpha1.PlanActionNoOp
+		return planned, "", nil
+	}
+	planned.Action = appv1alpha1.PlanActionUpdate
+	for _, change := range changes {
+		planned.Fields = append(planned.Fields, change.Path)
+	}
+	return planned, render.FormatChanges(changes), nil
+}
+
+// planPrune reports a deployed object that the spec no longer renders, or nil if it is already gone
+func (r *AppBundleReconciler) planPrune(ctx context.Context, obj *unstructured.Unstructured) (*appv1alpha1.PlannedObject, string, error) {
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(obj.GroupVersionKind())
+	if err := r.Get(ctx, types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}, live); err != nil {
+		if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil, "", nil
+		}
+		return nil, "", err
+	}
+
+	planned := &appv1alpha1.PlannedObject{
+		ResourceReference: appv1alpha1.ResourceReference{
+			APIVersion: obj.GetAPIVersion(),
+			Kind:       obj.GetKind(),
+			Name:       obj.GetName(),
+			Namespace:  obj.GetNamespace(),
+		},
+		Group:     obj.GetLabels()["app.example.com/group"],
+		Component: obj.GetLabels()["app.example.com/component"],
+		Action:    appv1alpha1.PlanActionPrune,
+	}
+	if err := r.Delete(ctx, live, client.DryRunAll); err != nil && !errors.IsNotFound(err) {
+		planned.Message = err.Error()
+	}
+	return planned, formatObject("- ", render.RedactSecret(obj.Object)), nil
+}
+
+// writePlanDiffs stores the diff of every changed object in the bundle's plan ConfigMap
+func (r *AppBundleReconciler) writePlanDiffs(ctx context.Context, appBundle *appv1alpha1.AppBundle, diffs map[string]string) error {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      planConfigMapName(appBundle),
+			Namespace: r.bundleNamespace(appBundle),
+		},
+	}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, configMap, func() error {
+		if configMap.Labels == nil {
+			configMap.Labels = map[string]string{}
+		}
+		configMap.Labels["app.example.com/appbundle"] = appBundle.Name
+		configMap.Data = diffs
+		return controllerutil.SetControllerReference(bundleOwner(appBundle), configMap, r.Scheme)
+	})
+	return err
+}
+
+// clearPlan removes the plan of a bundle that left Plan mode
+func (r *AppBundleReconciler) clearPlan(ctx context.Context, appBundle *appv1alpha1.AppBundle) error {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      planConfigMapName(appBundle),
+			Namespace: r.bundleNamespace(appBundle),
+		},
+	}
+	if err := r.Delete(ctx, configMap); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	appBundle.Status.Plan = nil
+	meta.RemoveStatusCondition(&appBundle.Status.Conditions, conditionPlanned)
+	return nil
+}
+
+// diffKey returns the plan ConfigMap key holding the diff of an object
+func diffKey(obj *unstructured.Unstructured) string {
+	parts := []string{strings.ToLower(obj.GetKind())}
+	if obj.GetNamespace() != "" {
+		parts = append(parts, obj.GetNamespace())
+	}
+	return strings.Join(append(parts, obj.GetName()), ".")
+}
+
+// formatObject renders an object as YAML with every line prefixed
+func formatObject(prefix string, obj map[string]interface{}) string {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return prefix + err.Error() + "\n"
+	}
+	var out strings.Builder
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		out.WriteString(prefix + line + "\n")
+	}
+	return out.String()
+}
@@ -0,0 +1,368 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+	"github.com/example/appbundle-operator/internal/render"
+)
+
+const (
+	// revisionLabel records the revision number on AppBundleRevision objects
+	revisionLabel = "app.example.com/revision"
+	// defaultRevisionHistoryLimit is used when spec.revisionHistoryLimit is not set
+	defaultRevisionHistoryLimit = 10
+	// maxRevisionSize bounds the encoded manifests of a revision and its Secret values, well
+	// below the size etcd accepts for a single object
+	maxRevisionSize = 1 << 20
+	// secretDataHashAnnotation replaces the values of a Secret in a recorded snapshot
+	secretDataHashAnnotation = "app.example.com/secret-data-hash"
+	// conditionRevisionRecorded is set to False when a rollout could not be recorded as a revision
+	conditionRevisionRecorded = "RevisionRecorded"
+)
+
+// revisionName returns the name of the AppBundleRevision for a revision number
+func revisionName(appBundle *appv1alpha1.AppBundle, revision int64) string {
+	return fmt.Sprintf("%s-rev-%d", bundleObjectPrefix(appBundle), revision)
+}
+
+// listRevisions returns the revisions of an AppBundle sorted from oldest to newest
+func (r *AppBundleReconciler) listRevisions(ctx context.Context, appBundle *appv1alpha1.AppBundle) ([]appv1alpha1.AppBundleRevision, error) {
+	revisionList := &appv1alpha1.AppBundleRevisionList{}
+	if err := r.List(ctx, revisionList,
+		client.InNamespace(r.bundleNamespace(appBundle)),
+		client.MatchingLabels{"app.example.com/appbundle": appBundle.Name},
+	); err != nil {
+		return nil, err
+	}
+
+	// Revisions of a ClusterAppBundle share the namespace with those of AppBundles
+	var revisions []appv1alpha1.AppBundleRevision
+	owner := bundleOwner(appBundle)
+	for _, revision := range revisionList.Items {
+		if metav1.IsControlledBy(&revision, owner) {
+			revisions = append(revisions, revision)
+		}
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Spec.Revision < revisions[j].Spec.Revision
+	})
+	return revisions, nil
+}
+
+// latestRevision returns the newest revision of an AppBundle, or nil if none exists
+func (r *AppBundleReconciler) latestRevision(ctx context.Context, appBundle *appv1alpha1.AppBundle) (*appv1alpha1.AppBundleRevision, error) {
+	revisions, err := r.listRevisions(ctx, appBundle)
+	if err != nil || len(revisions) == 0 {
+		return nil, err
+	}
+	return &revisions[len(revisions)-1], nil
+}
+
+// recordRevision creates a new AppBundleRevision holding the objects applied by pass when the
+// deployed spec differs from the latest recorded one, and prunes revisions beyond the history
+// limit
+func (r *AppBundleReconciler) recordRevision(ctx context.Context, appBundle *appv1alpha1.AppBundle, pass *deployPass) error {
+	logger := log.FromContext(ctx)
+
+	hash, err := specHash(appBundle)
+	if err != nil {
+		return err
+	}
+
+	revisions, err := r.listRevisions(ctx, appBundle)
+	if err != nil {
+		return fmt.Errorf("failed to list revisions: %w", err)
+	}
+
+	if len(revisions) > 0 && revisions[len(revisions)-1].Spec.SpecHash == hash {
+		appBundle.Status.CurrentRevision = revisions[len(revisions)-1].Spec.Revision
+		return nil
+	}
+
+	snapshot, err := r.appliedSnapshot(ctx, appBundle, pass)
+	if err != nil {
+		return fmt.Errorf("failed to collect revision: %w", err)
+	}
+	// Secret values are kept out of the revision, which anyone reading revisions can see
+	secretValues, err := extractSecretValues(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode revision: %w", err)
+	}
+	manifests, err := encodeSnapshot(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode revision: %w", err)
+	}
+
+	next := int64(1)
+	if len(revisions) > 0 {
+		next = revisions[len(revisions)-1].Spec.Revision + 1
+	}
+
+	revision := &appv1alpha1.AppBundleRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      revisionName(appBundle, next),
+			Namespace: r.bundleNamespace(appBundle),
+			Labels: map[string]string{
+				"app.example.com/appbundle": appBundle.Name,
+				revisionLabel:               strconv.FormatInt(next, 10),
+			},
+		},
+		Spec: appv1alpha1.AppBundleRevisionSpec{
+			BundleName:       appBundle.Name,
+			Revision:         next,
+			BundleGeneration: appBundle.Generation,
+			SpecHash:         hash,
+			DeployedAt:       metav1.Now(),
+			Manifests:        manifests,
+			GroupStatuses:    appBundle.Status.GroupStatuses,
+		},
+	}
+	secretsSize := 0
+	for _, data := range secretValues {
+		secretsSize += len(data)
+	}
+	if len(manifests) > maxRevisionSize || secretsSize > maxRevisionSize {
+		message := fmt.Sprintf("Revision %d was not recorded: its manifests (%d bytes) or Secret values (%d bytes) exceed %d bytes",
+			next, len(manifests), secretsSize, maxRevisionSize)
+		logger.Info("Skipping AppBundleRevision that exceeds the size limit", "revision", next,
+			"manifestsSize", len(manifests), "secretsSize", secretsSize)
+		r.Recorder.Event(appBundle, corev1.EventTypeWarning, reasonRevisionTooLarge, message)
+		meta.SetStatusCondition(&appBundle.Status.Conditions, metav1.Condition{
+			Type:               conditionRevisionRecorded,
+			Status:             metav1.ConditionFalse,
+			Reason:             reasonRevisionTooLarge,
+			Message:            message,
+			ObservedGeneration: appBundle.Generation,
+		})
+		return nil
+	}
+	if len(secretValues) > 0 {
+		revision.Spec.SecretsRef = revision.Name
+	}
+
+	if err := controllerutil.SetControllerReference(bundleOwner(appBundle), revision, r.Scheme); err != nil {
+		return err
+	}
+	if err := r.Create(ctx, revision); err != nil {
+		return fmt.Errorf("failed to create revision: %w", err)
+	}
+	if len(secretValues) > 0 {
+		if err := r.createRevisionSecrets(ctx, revision, secretValues); err != nil {
+			// A revision whose Secrets cannot be restored is of no use for a rollback
+			if deleteErr := r.Delete(ctx, revision); deleteErr != nil && !errors.IsNotFound(deleteErr) {
+				logger.Error(deleteErr, "Failed to delete incomplete AppBundleRevision", "name", revision.Name)
+			}
+			return fmt.Errorf("failed to store Secret values of revision: %w", err)
+		}
+	}
+	meta.RemoveStatusCondition(&appBundle.Status.Conditions, conditionRevisionRecorded)
+
+	logger.Info("Recorded AppBundleRevision", "revision", next, "name", revision.Name)
+	r.Recorder.Eventf(appBundle, corev1.EventTypeNormal, reasonRevisionCreated,
+		"Recorded revision %d (%s)", next, revision.Name)
+	appBundle.Status.CurrentRevision = next
+
+	return r.pruneRevisions(ctx, appBundle, append(revisions, *revision))
+}
+
+// appliedSnapshot returns the objects applied by pass as a snapshot, so that the revision
+// holds what was deployed even when sources, values or outputs changed since. Groups the pass
+// did not deploy, such as the groups kept by a retry, were applied by an earlier pass; their
+// objects are read back from the cluster.
+func (r *AppBundleReconciler) appliedSnapshot(ctx context.Context, appBundle *appv1alpha1.AppBundle, pass *deployPass) (*bundleSnapshot, error) {
+	hash, err := specHash(appBundle)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &bundleSnapshot{SpecHash: hash}
+	for _, group := range render.SortedGroups(appBundle) {
+		objects, deployed := pass.applied[group.Name]
+		if !deployed {
+			if objects, err = r.liveGroupObjects(ctx, appBundle, group); err != nil {
+				return nil, err
+			}
+		}
+		snapshotGroup := snapshotGroup{Name: group.Name, Order: group.Order}
+		for _, obj := range objects {
+			snapshotGroup.Objects = append(snapshotGroup.Objects, obj.Object)
+		}
+		snapshot.Groups = append(snapshot.Groups, snapshotGroup)
+	}
+	return snapshot, nil
+}
+
+// liveGroupObjects reads the inventoried objects of a group's current components from the
+// cluster, without the fields the API server sets
+func (r *AppBundleReconciler) liveGroupObjects(ctx context.Context, appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group) ([]*unstructured.Unstructured, error) {
+	var objects []*unstructured.Unstructured
+	for _, entry := range appBundle.Status.Inventory {
+		if entry.Group != group.Name {
+			continue
+		}
+		if _, found := specComponent(appBundle, entry.Group, entry.Component); entry.Component != "" && !found {
+			continue
+		}
+		obj := resourceObject(entry.ResourceReference)
+		if err := r.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to get %s %s: %w", entry.Kind, inventoryName(entry), err)
+		}
+		unstructured.RemoveNestedField(obj.Object, "status")
+		for _, field := range []string{"uid", "resourceVersion", "generation", "creationTimestamp", "managedFields"} {
+			unstructured.RemoveNestedField(obj.Object, "metadata", field)
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
+// createRevisionSecrets stores the Secret values of a revision in a Secret owned by the revision,
+// so it is garbage collected when the revision is pruned
+func (r *AppBundleReconciler) createRevisionSecrets(ctx context.Context, revision *appv1alpha1.AppBundleRevision, values map[string][]byte) error {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      revision.Spec.SecretsRef,
+			Namespace: revision.Namespace,
+			Labels:    revision.Labels,
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: values,
+	}
+	if err := controllerutil.SetControllerReference(revision, secret, r.Scheme); err != nil {
+		return err
+	}
+	return r.Create(ctx, secret)
+}
+
+// revisionSnapshot decodes the manifests of a revision and restores the values of its Secrets
+func (r *AppBundleReconciler) revisionSnapshot(ctx context.Context, revision *appv1alpha1.AppBundleRevision) (*bundleSnapshot, error) {
+	snapshot, err := decodeSnapshot(revision.Spec.Manifests)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string][]byte{}
+	if revision.Spec.SecretsRef != "" {
+		secret := &corev1.Secret{}
+		key := client.ObjectKey{Namespace: revision.Namespace, Name: revision.Spec.SecretsRef}
+		if err := r.Get(ctx, key, secret); err != nil {
+			return nil, fmt.Errorf("failed to load Secret values: %w", err)
+		}
+		values = secret.Data
+	}
+	if err := restoreSecretValues(snapshot, values); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// pruneRevisions deletes the oldest revisions beyond the history limit
+func (r *AppBundleReconciler) pruneRevisions(ctx context.Context, appBundle *appv1alpha1.AppBundle, revisions []appv1alpha1.AppBundleRevision) error {
+	limit := defaultRevisionHistoryLimit
+	if appBundle.Spec.RevisionHistoryLimit != nil {
+		limit = int(*appBundle.Spec.RevisionHistoryLimit)
+	}
+
+	for i := 0; i < len(revisions)-limit; i++ {
+		if revisions[i].Spec.Revision == appBundle.Status.CurrentRevision {
+			continue
+		}
+		if err := r.Delete(ctx, &revisions[i]); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to prune revision %s: %w", revisions[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// reconcileRollbackTo deploys the manifests of the revision named by spec.rollbackTo
+func (r *AppBundleReconciler) reconcileRollbackTo(ctx context.Context, appBundle *appv1alpha1.AppBundle) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	target := *appBundle.Spec.RollbackTo
+
+	revisions, err := r.listRevisions(ctx, appBundle)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	var revision *appv1alpha1.AppBundleRevision
+	for i := range revisions {
+		if revisions[i].Spec.Revision == target {
+			revision = &revisions[i]
+			break
+		}
+	}
+	if revision == nil {
+		return r.updateStatusWithError(ctx, appBundle, fmt.Errorf("revision %d not found", target))
+	}
+
+	snapshot, err := r.revisionSnapshot(ctx, revision)
+	if err != nil {
+		return r.updateStatusWithError(ctx, appBundle, fmt.Errorf("failed to decode revision %d: %w", target, err))
+	}
+
+	scope := map[string]bool{}
+	for _, group := range snapshot.Groups {
+		scope[group.Name] = true
+	}
+	for _, group := range render.Groups(appBundle) {
+		scope[group.Name] = true
+	}
+
+	logger.Info("Rolling back to recorded revision", "revision", target)
+	r.Recorder.Eventf(appBundle, corev1.EventTypeNormal, reasonRollbackStarted, "Rolling back to revision %d", target)
+	if err := r.rollback(ctx, appBundle, snapshot, scope); err != nil {
+		r.Recorder.Eventf(appBundle, corev1.EventTypeWarning, reasonRollbackFailed, "Rollback to revision %d failed: %v", target, err)
+		return r.updateStatusWithError(ctx, appBundle, fmt.Errorf("rollback to revision %d failed: %w", target, err))
+	}
+
+	message := fmt.Sprintf("Rolled back to revision %d", target)
+	appBundle.Status.Phase = appv1alpha1.PhaseRolledBack
+	appBundle.Status.Message = message
+	appBundle.Status.GroupStatuses = revision.Spec.GroupStatuses
+	appBundle.Status.CurrentRevision = target
+	appBundle.Status.ObservedGeneration = appBundle.Generation
+	meta.SetStatusCondition(&appBundle.Status.Conditions, metav1.Condition{
+		Type:               "Ready",
+		Status:             metav1.ConditionTrue,
+		Reason:             "RolledBackToRevision",
+		Message:            message,
+		ObservedGeneration: appBundle.Generation,
+	})
+	r.Recorder.Event(appBundle, corev1.EventTypeNormal, reasonRolledBack, message)
+
+	if err := r.updateBundleStatus(ctx, appBundle); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
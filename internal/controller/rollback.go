@@ -0,0 +1,345 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+	"github.com/example/appbundle-operator/internal/render"
+)
+
+// bundleSnapshot holds the rendered manifests of a deployment, as stored in an AppBundleRevision
+type bundleSnapshot struct {
+	SpecHash string          `json:"specHash"`
+	Groups   []snapshotGroup `json:"groups"`
+}
+
+// snapshotGroup holds the rendered manifests of a single group, in deployment order
+type snapshotGroup struct {
+	Name    string                   `json:"name"`
+	Order   int                      `json:"order"`
+	Objects []map[string]interface{} `json:"objects"`
+}
+
+// specHash returns a stable hash of the AppBundle spec and, for a templated bundle, of the
+// template it renders
+func specHash(appBundle *appv1alpha1.AppBundle) (string, error) {
+	data, err := json.Marshal(appBundle.Spec)
+	if err != nil {
+		return "", err
+	}
+	// A templated bundle also changes with its template
+	if appBundle.Spec.TemplateRef != nil && appBundle.Status.Template != nil {
+		template, err := json.Marshal(appBundle.Status.Template)
+		if err != nil {
+			return "", err
+		}
+		data = append(data, template...)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// renderBundle renders every component of the AppBundle in deployment order
+func (r *AppBundleReconciler) renderBundle(ctx context.Context, appBundle *appv1alpha1.AppBundle) (*bundleSnapshot, error) {
+	hash, err := specHash(appBundle)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := render.Bundle(ctx, appBundle, r.fetcher())
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &bundleSnapshot{SpecHash: hash}
+	for _, group := range groups {
+		snapshotGroup := snapshotGroup{Name: group.Name, Order: group.Order}
+		for _, obj := range group.Objects {
+			snapshotGroup.Objects = append(snapshotGroup.Objects, obj.Object)
+		}
+		snapshot.Groups = append(snapshot.Groups, snapshotGroup)
+	}
+	return snapshot, nil
+}
+
+// encodeSnapshot serializes and compresses a snapshot
+func encodeSnapshot(snapshot *bundleSnapshot) ([]byte, error) {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeSnapshot decompresses and deserializes a snapshot
+func decodeSnapshot(data []byte) (*bundleSnapshot, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = reader.Close() }()
+
+	raw, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &bundleSnapshot{}
+	if err := json.Unmarshal(raw, snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// secretValuesKey identifies a Secret of a snapshot within the Secret holding the snapshot's values
+func secretValuesKey(obj *unstructured.Unstructured) string {
+	return obj.GetNamespace() + "." + obj.GetName()
+}
+
+// extractSecretValues moves the data and stringData of the Secrets in a snapshot out of it, leaving
+// a hash of the values in their place, and returns the values keyed by Secret
+func extractSecretValues(snapshot *bundleSnapshot) (map[string][]byte, error) {
+	values := map[string][]byte{}
+	for _, group := range snapshot.Groups {
+		for _, content := range group.Objects {
+			obj := &unstructured.Unstructured{Object: content}
+			if obj.GetKind() != "Secret" || obj.GroupVersionKind().Group != "" {
+				continue
+			}
+			secretValues := map[string]interface{}{}
+			for _, field := range []string{"data", "stringData"} {
+				if value, ok := content[field]; ok {
+					secretValues[field] = value
+					delete(content, field)
+				}
+			}
+			data, err := json.Marshal(secretValues)
+			if err != nil {
+				return nil, err
+			}
+			sum := sha256.Sum256(data)
+			annotations := obj.GetAnnotations()
+			if annotations == nil {
+				annotations = map[string]string{}
+			}
+			annotations[secretDataHashAnnotation] = hex.EncodeToString(sum[:])
+			obj.SetAnnotations(annotations)
+			values[secretValuesKey(obj)] = data
+		}
+	}
+	return values, nil
+}
+
+// restoreSecretValues puts the values extracted by extractSecretValues back into the Secrets of a snapshot
+func restoreSecretValues(snapshot *bundleSnapshot, values map[string][]byte) error {
+	for _, group := range snapshot.Groups {
+		for _, content := range group.Objects {
+			obj := &unstructured.Unstructured{Object: content}
+			annotations := obj.GetAnnotations()
+			hash, ok := annotations[secretDataHashAnnotation]
+			if !ok {
+				continue
+			}
+			data, ok := values[secretValuesKey(obj)]
+			if !ok {
+				return fmt.Errorf("values of Secret %s are not recorded", obj.GetName())
+			}
+			if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != hash {
+				return fmt.Errorf("recorded values of Secret %s do not match the revision", obj.GetName())
+			}
+			secretValues := map[string]interface{}{}
+			if err := json.Unmarshal(data, &secretValues); err != nil {
+				return err
+			}
+			for field, value := range secretValues {
+				content[field] = value
+			}
+			delete(annotations, secretDataHashAnnotation)
+			if len(annotations) == 0 {
+				annotations = nil
+			}
+			obj.SetAnnotations(annotations)
+		}
+	}
+	return nil
+}
+
+// handleDeploymentFailure applies the AppBundle's rollback policy after a group failed
+func (r *AppBundleReconciler) handleDeploymentFailure(ctx context.Context, appBundle *appv1alpha1.AppBundle, failedGroup appv1alpha1.Group, deployErr error) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	policy := appBundle.Spec.RollbackPolicy
+	if policy == "" || policy == appv1alpha1.RollbackPolicyNone {
+		return r.updateStatusWithError(ctx, appBundle, deployErr)
+	}
+
+	revision, err := r.latestRevision(ctx, appBundle)
+	if err != nil {
+		logger.Error(err, "Failed to load last deployed revision, skipping rollback")
+		return r.updateStatusWithError(ctx, appBundle, deployErr)
+	}
+	if revision == nil {
+		r.Recorder.Event(appBundle, corev1.EventTypeWarning, reasonRollbackSkipped,
+			"No successfully deployed revision to roll back to")
+		return r.updateStatusWithError(ctx, appBundle, deployErr)
+	}
+	snapshot, err := r.revisionSnapshot(ctx, revision)
+	if err != nil {
+		logger.Error(err, "Failed to decode last deployed revision, skipping rollback", "revision", revision.Spec.Revision)
+		return r.updateStatusWithError(ctx, appBundle, deployErr)
+	}
+
+	// Re-applying the same spec that just failed would not change anything
+	if hash, err := specHash(appBundle); err == nil && hash == snapshot.SpecHash {
+		return r.updateStatusWithError(ctx, appBundle, deployErr)
+	}
+
+	scope := map[string]bool{failedGroup.Name: true}
+	if policy == appv1alpha1.RollbackPolicyBundle {
+		for _, group := range snapshot.Groups {
+			scope[group.Name] = true
+		}
+		for _, group := range render.Groups(appBundle) {
+			scope[group.Name] = true
+		}
+	}
+
+	logger.Info("Rolling back to last deployed revision", "policy", policy, "failedGroup", failedGroup.Name, "revision", revision.Spec.Revision)
+	r.Recorder.Eventf(appBundle, corev1.EventTypeWarning, reasonRollbackStarted,
+		"Rolling back to revision %d (policy %s) after group %s failed: %v", revision.Spec.Revision, policy, failedGroup.Name, deployErr)
+
+	if err := r.rollback(ctx, appBundle, snapshot, scope); err != nil {
+		r.Recorder.Eventf(appBundle, corev1.EventTypeWarning, reasonRollbackFailed, "Rollback failed: %v", err)
+		return r.updateStatusWithError(ctx, appBundle, fmt.Errorf("%w; rollback failed: %v", deployErr, err))
+	}
+
+	// The rolled back groups no longer run the current spec, so a retry deploys them again
+	for i := range appBundle.Status.GroupStatuses {
+		if scope[appBundle.Status.GroupStatuses[i].Name] {
+			appBundle.Status.GroupStatuses[i].SpecHash = ""
+		}
+	}
+
+	message := fmt.Sprintf("Deployment failed and was rolled back to revision %d: %v", revision.Spec.Revision, deployErr)
+	appBundle.Status.Phase = appv1alpha1.PhaseRolledBack
+	appBundle.Status.Message = message
+	appBundle.Status.CurrentRevision = revision.Spec.Revision
+	appBundle.Status.ObservedGeneration = appBundle.Generation
+	meta.SetStatusCondition(&appBundle.Status.Conditions, metav1.Condition{
+		Type:               "Ready",
+		Status:             metav1.ConditionFalse,
+		Reason:             "RolledBack",
+		Message:            message,
+		ObservedGeneration: appBundle.Generation,
+	})
+	r.Recorder.Eventf(appBundle, corev1.EventTypeNormal, reasonRolledBack,
+		"Rolled back %d group(s) to revision %d", len(scope), revision.Spec.Revision)
+
+	if err := r.updateBundleStatus(ctx, appBundle); err != nil {
+		return ctrl.Result{}, err
+	}
+	// The failed spec is not retried until it changes
+	return ctrl.Result{}, nil
+}
+
+// rollback re-applies the snapshot manifests of the groups in scope and prunes
+// objects rendered from the current spec that the snapshot does not contain
+func (r *AppBundleReconciler) rollback(ctx context.Context, appBundle *appv1alpha1.AppBundle, snapshot *bundleSnapshot, scope map[string]bool) error {
+	logger := log.FromContext(ctx)
+
+	keep := map[string]bool{}
+	var errs []error
+	for _, group := range snapshot.Groups {
+		if !scope[group.Name] {
+			continue
+		}
+		for _, content := range group.Objects {
+			obj := &unstructured.Unstructured{Object: content}
+			keep[objectKey(obj)] = true
+			// Hooks run at their point of a rollout, not when restoring one
+			if render.IsHook(obj) {
+				continue
+			}
+
+			r.setOwnerReference(ctx, appBundle, obj)
+			if _, err := r.applyObject(ctx, appBundle, obj); err != nil {
+				errs = append(errs, fmt.Errorf("failed to restore %s %s: %w", obj.GetKind(), obj.GetName(), err))
+				continue
+			}
+			logger.Info("Restored resource", "group", group.Name, "kind", obj.GetKind(), "name", obj.GetName())
+		}
+	}
+
+	current, err := r.renderBundle(ctx, appBundle)
+	if err != nil {
+		logger.Info("Cannot render current spec, skipping pruning of new resources", "error", err)
+		return utilerrors.NewAggregate(errs)
+	}
+
+	// Delete resources introduced by the failed spec in reverse order
+	for i := len(current.Groups) - 1; i >= 0; i-- {
+		group := current.Groups[i]
+		if !scope[group.Name] {
+			continue
+		}
+		for j := len(group.Objects) - 1; j >= 0; j-- {
+			obj := &unstructured.Unstructured{Object: group.Objects[j]}
+			// Objects the bundle never applied, such as ones it refused to adopt, are not its to delete
+			if keep[objectKey(obj)] || !inInventory(appBundle, obj) {
+				continue
+			}
+			logger.Info("Pruning resource introduced by failed spec", "kind", obj.GetKind(), "name", obj.GetName())
+			if err := r.Delete(ctx, obj); err != nil && !errors.IsNotFound(err) {
+				errs = append(errs, fmt.Errorf("failed to prune %s %s: %w", obj.GetKind(), obj.GetName(), err))
+				continue
+			}
+			forgetInventory(appBundle, obj)
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// objectKey identifies an object by group, kind, namespace and name
+func objectKey(obj *unstructured.Unstructured) string {
+	gvk := obj.GroupVersionKind()
+	return fmt.Sprintf("%s/%s/%s/%s", gvk.Group, gvk.Kind, obj.GetNamespace(), obj.GetName())
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+	"github.com/example/appbundle-operator/internal/render"
+)
+
+// resolveTemplate records the AppBundleTemplate a templated bundle renders in its status,
+// and the rendered generation in an AppBundleTemplateRevision so that bundles can be pinned
+// to it. A bundle deployed from another generation of the template is deployed again.
+func (r *AppBundleReconciler) resolveTemplate(ctx context.Context, appBundle *appv1alpha1.AppBundle) error {
+	var live *appv1alpha1.AppBundleTemplate
+	if ref := appBundle.Spec.TemplateRef; ref != nil {
+		template := &appv1alpha1.AppBundleTemplate{}
+		if err := r.Get(ctx, types.NamespacedName{Name: ref.Name}, template); err == nil {
+			live = template
+		} else if !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	previous := appBundle.Status.Template
+	changed, err := render.ResolveTemplate(appBundle, live, func(name string, generation int64) (*appv1alpha1.AppBundleTemplateRevision, error) {
+		return r.templateRevision(ctx, name, generation)
+	})
+	if err != nil {
+		return err
+	}
+	current := appBundle.Status.Template
+	if current != nil && live != nil && current.Generation == live.Generation {
+		if err := r.recordTemplateRevision(ctx, live); err != nil {
+			return err
+		}
+	}
+	if changed && previous != nil &&
+		(appBundle.Status.Phase == appv1alpha1.PhaseDeployed || appBundle.Status.Phase == appv1alpha1.PhaseRolledBack) {
+		log.FromContext(ctx).Info("AppBundleTemplate changed, redeploying", "template", current.Name, "generation", current.Generation)
+		r.Recorder.Eventf(appBundle, corev1.EventTypeNormal, reasonTemplateChanged,
+			"AppBundleTemplate %s changed from generation %d to %d", current.Name, previous.Generation, current.Generation)
+		appBundle.Status.Phase = appv1alpha1.PhasePending
+	}
+	return nil
+}
+
+// resolveRecordedTemplate loads the template generation recorded in a bundle's status from
+// its AppBundleTemplateRevision, for cleaning up a bundle whose template is gone
+func (r *AppBundleReconciler) resolveRecordedTemplate(ctx context.Context, appBundle *appv1alpha1.AppBundle) error {
+	recorded := appBundle.Status.Template
+	if recorded == nil {
+		return nil
+	}
+	revision, err := r.templateRevision(ctx, recorded.Name, recorded.Generation)
+	if err != nil || revision == nil {
+		return err
+	}
+	recorded.Spec = revision.Spec.Template.DeepCopy()
+	return nil
+}
+
+// templateRevision reads the AppBundleTemplateRevision of a template generation, returning
+// nil if that generation was not recorded
+func (r *AppBundleReconciler) templateRevision(ctx context.Context, name string, generation int64) (*appv1alpha1.AppBundleTemplateRevision, error) {
+	revision := &appv1alpha1.AppBundleTemplateRevision{}
+	if err := r.Get(ctx, types.NamespacedName{Name: render.TemplateRevisionName(name, generation)}, revision); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	if revision.Spec.TemplateName != name || revision.Spec.Generation != generation {
+		return nil, nil
+	}
+	return revision, nil
+}
+
+// recordTemplateRevision records the current generation of a template in an
+// AppBundleTemplateRevision, unless it already is, and prunes the generations no bundle
+// renders anymore
+func (r *AppBundleReconciler) recordTemplateRevision(ctx context.Context, template *appv1alpha1.AppBundleTemplate) error {
+	existing, err := r.templateRevision(ctx, template.Name, template.Generation)
+	if err != nil || existing != nil {
+		return err
+	}
+	revision := &appv1alpha1.AppBundleTemplateRevision{
+		ObjectMeta: metav1.ObjectMeta{Name: render.TemplateRevisionName(template.Name, template.Generation)},
+		Spec: appv1alpha1.AppBundleTemplateRevisionSpec{
+			TemplateName: template.Name,
+			Generation:   template.Generation,
+			Template:     *template.Spec.DeepCopy(),
+		},
+	}
+	if err := r.Create(ctx, revision); err != nil && !errors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to record generation %d of AppBundleTemplate %s: %w", template.Generation, template.Name, err)
+	}
+	log.FromContext(ctx).Info("Recorded AppBundleTemplateRevision", "template", template.Name, "generation", template.Generation)
+	return r.pruneTemplateRevisions(ctx, template.Name)
+}
+
+// pruneTemplateRevisions deletes the recorded generations of a template that are neither
+// its current generation nor pinned or rendered by any bundle
+func (r *AppBundleReconciler) pruneTemplateRevisions(ctx context.Context, name string) error {
+	keep := map[int64]bool{}
+	template := &appv1alpha1.AppBundleTemplate{}
+	if err := r.Get(ctx, types.NamespacedName{Name: name}, template); err == nil {
+		keep[template.Generation] = true
+	} else if !errors.IsNotFound(err) {
+		return err
+	}
+	appBundles := &appv1alpha1.AppBundleList{}
+	if err := r.List(ctx, appBundles); err != nil {
+		return err
+	}
+	clusterAppBundles := &appv1alpha1.ClusterAppBundleList{}
+	if err := r.List(ctx, clusterAppBundles); err != nil {
+		return err
+	}
+	bundles := appBundles.Items
+	for _, clusterAppBundle := range clusterAppBundles.Items {
+		bundles = append(bundles, *asAppBundle(&clusterAppBundle))
+	}
+	for _, appBundle := range bundles {
+		ref := appBundle.Spec.TemplateRef
+		if ref == nil || ref.Name != name || !appBundle.DeletionTimestamp.IsZero() {
+			continue
+		}
+		keep[ref.Generation] = true
+		if recorded := appBundle.Status.Template; recorded != nil && recorded.Name == name {
+			keep[recorded.Generation] = true
+		}
+	}
+
+	revisions := &appv1alpha1.AppBundleTemplateRevisionList{}
+	if err := r.List(ctx, revisions); err != nil {
+		return err
+	}
+	for i := range revisions.Items {
+		revision := &revisions.Items[i]
+		if revision.Spec.TemplateName != name || keep[revision.Spec.Generation] {
+			continue
+		}
+		if err := r.Delete(ctx, revision); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+		log.FromContext(ctx).Info("Pruned AppBundleTemplateRevision", "template", name, "generation", revision.Spec.Generation)
+	}
+	return nil
+}
+
+// rejectTemplate fails a bundle whose AppBundleTemplate cannot be rendered. The error is
+// returned so that the bundle is retried.
+func (r *AppBundleReconciler) rejectTemplate(ctx context.Context, appBundle *appv1alpha1.AppBundle, err error) (ctrl.Result, error) {
+	r.Recorder.Eventf(appBundle, corev1.EventTypeWarning, reasonTemplateUnavailable, "%v", err)
+	appBundle.Status.Phase = appv1alpha1.PhaseFailed
+	appBundle.Status.Message = fmt.Sprintf("Template unavailable: %v", err)
+	meta.SetStatusCondition(&appBundle.Status.Conditions, metav1.Condition{
+		Type:               "Ready",
+		Status:             metav1.ConditionFalse,
+		Reason:             reasonTemplateUnavailable,
+		Message:            err.Error(),
+		ObservedGeneration: appBundle.Generation,
+	})
+	if statusErr := r.updateBundleStatus(ctx, appBundle); statusErr != nil {
+		return ctrl.Result{}, statusErr
+	}
+	return ctrl.Result{}, err
+}
+
+// templateToAppBundles maps an AppBundleTemplate to the AppBundles instantiated from it
+func (r *AppBundleReconciler) templateToAppBundles(ctx context.Context, obj client.Object) []reconcile.Request {
+	appBundles := &appv1alpha1.AppBundleList{}
+	if err := r.List(ctx, appBundles); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list AppBundles for template", "template", obj.GetName())
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, appBundle := range appBundles.Items {
+		if ref := appBundle.Spec.TemplateRef; ref != nil && ref.Name == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&appBundle)})
+		}
+	}
+	return requests
+}
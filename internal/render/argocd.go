@@ -0,0 +1,134 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package render
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+)
+
+const (
+	// ApplicationAPIVersion is the API version of Argo CD Applications
+	ApplicationAPIVersion = "argoproj.io/v1alpha1"
+	// ApplicationKind is the kind of Argo CD Applications
+	ApplicationKind = "Application"
+)
+
+// ApplicationSource is the Git location Argo CD syncs a bundle from
+type ApplicationSource struct {
+	RepoURL        string
+	Path           string
+	TargetRevision string
+	// Recurse includes manifests in subdirectories of Path, as written by Porch
+	Recurse bool
+}
+
+// maxApplicationNameLength is the longest name of an Argo CD Application
+const maxApplicationNameLength = 253
+
+// ApplicationName returns the name of the Argo CD Application of an AppBundle. The
+// namespace is included because Applications of all bundles share the Argo CD namespace,
+// and a hash of namespace and name keeps bundles such as a-b/c and a/b-c apart.
+func ApplicationName(appBundle *appv1alpha1.AppBundle) string {
+	sum := sha256.Sum256([]byte(appBundle.Namespace + "/" + appBundle.Name))
+	suffix := "-" + hex.EncodeToString(sum[:])[:8]
+	name := appBundle.Namespace + "-" + appBundle.Name
+	if appBundle.Namespace == "" {
+		name = "cluster-" + appBundle.Name
+	}
+	if len(name)+len(suffix) > maxApplicationNameLength {
+		name = name[:maxApplicationNameLength-len(suffix)]
+	}
+	return name + suffix
+}
+
+// ApplicationNamespace returns the namespace of the Argo CD Application (default to "argocd")
+func ApplicationNamespace(appBundle *appv1alpha1.AppBundle) string {
+	if appBundle.Spec.ArgoCD != nil && appBundle.Spec.ArgoCD.Namespace != "" {
+		return appBundle.Spec.ArgoCD.Namespace
+	}
+	return "argocd"
+}
+
+// Application renders the Argo CD Application that syncs an AppBundle's manifests from source
+func Application(appBundle *appv1alpha1.AppBundle, source ApplicationSource) *unstructured.Unstructured {
+	spec := appBundle.Spec.ArgoCD
+	if spec == nil {
+		spec = &appv1alpha1.ArgoCDIntegrationSpec{}
+	}
+
+	project := spec.Project
+	if project == "" {
+		project = "default"
+	}
+	server := spec.DestinationServer
+	if server == "" {
+		server = "https://kubernetes.default.svc"
+	}
+	destinationNamespace := spec.DestinationNamespace
+	if destinationNamespace == "" {
+		destinationNamespace = appBundle.Namespace
+	}
+	path := source.Path
+	if path == "" {
+		path = "."
+	}
+	targetRevision := source.TargetRevision
+	if targetRevision == "" {
+		targetRevision = "HEAD"
+	}
+
+	applicationSource := map[string]interface{}{
+		"repoURL":        source.RepoURL,
+		"path":           path,
+		"targetRevision": targetRevision,
+	}
+	if source.Recurse {
+		applicationSource["directory"] = map[string]interface{}{"recurse": true}
+	}
+	applicationSpec := map[string]interface{}{
+		"project": project,
+		"source":  applicationSource,
+		"destination": map[string]interface{}{
+			"server":    server,
+			"namespace": destinationNamespace,
+		},
+	}
+	if spec.AutomatedSync {
+		applicationSpec["syncPolicy"] = map[string]interface{}{
+			"automated": map[string]interface{}{
+				"prune":    true,
+				"selfHeal": true,
+			},
+		}
+	}
+
+	application := &unstructured.Unstructured{Object: map[string]interface{}{"spec": applicationSpec}}
+	application.SetAPIVersion(ApplicationAPIVersion)
+	application.SetKind(ApplicationKind)
+	application.SetName(ApplicationName(appBundle))
+	application.SetNamespace(ApplicationNamespace(appBundle))
+	application.SetLabels(map[string]string{
+		AppBundleLabel:          appBundle.Name,
+		AppBundleNamespaceLabel: appBundle.Namespace,
+	})
+	return application
+}
@@ -0,0 +1,275 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package render
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+)
+
+// ConfigHashAnnotation holds the hash of the ConfigMaps and Secrets a workload's pods use.
+// It is set on the pod template, so that a change of their content rolls the workload.
+const ConfigHashAnnotation = "app.example.com/config-hash"
+
+// workloadKinds lists the kinds whose pod template is rolled out again when it changes
+var workloadKinds = map[string]bool{
+	"Deployment":  true,
+	"StatefulSet": true,
+	"DaemonSet":   true,
+}
+
+// ConfigHashes holds the hashes of the ConfigMaps and Secrets rendered by the components of a
+// bundle, so that a render pass hashes each component's configuration only once
+type ConfigHashes struct {
+	// components maps a group and component to its ConfigMaps and Secrets, keyed by
+	// configKey, and their hashes
+	components map[string]map[string]string
+}
+
+// NewConfigHashes returns an empty ConfigHashes
+func NewConfigHashes() *ConfigHashes {
+	return &ConfigHashes{components: map[string]map[string]string{}}
+}
+
+// BundleConfigHashes renders the components whose configuration workloads of other components
+// can use: the template components of ConfigMaps and Secrets, and the components named in
+// configFrom. Components that cannot be rendered yet, such as those referencing the outputs
+// of components that are not deployed, are left out until they are rendered themselves.
+func BundleConfigHashes(ctx context.Context, appBundle *appv1alpha1.AppBundle, fetcher Fetcher) *ConfigHashes {
+	configFrom := map[string]bool{}
+	for _, group := range Groups(appBundle) {
+		for _, component := range group.Components {
+			for _, name := range component.ConfigFrom {
+				configFrom[name] = true
+			}
+		}
+	}
+
+	configs := NewConfigHashes()
+	for _, group := range SortedGroups(appBundle) {
+		for _, component := range group.Components {
+			if component.PorchPackageRef != nil || (!isConfigTemplate(component) && !configFrom[component.Name]) {
+				continue
+			}
+			objects, err := componentObjects(ctx, appBundle, group, component, BaseSyncWave(group), fetcher)
+			if err != nil {
+				continue
+			}
+			configs.add(group, component, objects)
+		}
+	}
+	return configs
+}
+
+// add records the ConfigMaps and Secrets among the objects a component rendered
+func (c *ConfigHashes) add(group appv1alpha1.Group, component appv1alpha1.Component, objects []*unstructured.Unstructured) {
+	hashes := map[string]string{}
+	for _, obj := range objects {
+		if hash, ok := configHash(obj); ok {
+			hashes[configKey(obj.GetKind(), obj.GetNamespace(), obj.GetName())] = hash
+		}
+	}
+	c.components[group.Name+"/"+component.Name] = hashes
+}
+
+// component returns the ConfigMaps and Secrets of a component, or nil if it was not rendered
+func (c *ConfigHashes) component(group appv1alpha1.Group, component appv1alpha1.Component) map[string]string {
+	return c.components[group.Name+"/"+component.Name]
+}
+
+// injectConfigHashes annotates the pod templates of a component's workloads with the hash of
+// the bundle's ConfigMaps and Secrets they reference through volumes, envFrom or env, plus
+// those of the components named in configFrom. The ConfigMaps and Secrets of the bundle are
+// read from configs.
+func injectConfigHashes(appBundle *appv1alpha1.AppBundle, component appv1alpha1.Component, objects []*unstructured.Unstructured, configs *ConfigHashes) error {
+	var workloads []*unstructured.Unstructured
+	for _, obj := range objects {
+		if workloadKinds[obj.GetKind()] {
+			workloads = append(workloads, obj)
+		}
+	}
+	if len(workloads) == 0 {
+		return nil
+	}
+
+	// The component's own ConfigMaps and Secrets and the bundle's template ones
+	referable := map[string]string{}
+	for _, obj := range objects {
+		if hash, ok := configHash(obj); ok {
+			referable[configKey(obj.GetKind(), obj.GetNamespace(), obj.GetName())] = hash
+		}
+	}
+	for _, group := range SortedGroups(appBundle) {
+		for _, source := range group.Components {
+			if isConfigTemplate(source) {
+				maps.Copy(referable, configs.component(group, source))
+			}
+		}
+	}
+	var declared []string
+	for _, name := range component.ConfigFrom {
+		found := false
+		for _, group := range SortedGroups(appBundle) {
+			for _, source := range group.Components {
+				if source.Name != name {
+					continue
+				}
+				found = true
+				for key, hash := range configs.component(group, source) {
+					referable[key] = hash
+					declared = append(declared, key)
+				}
+			}
+		}
+		if !found {
+			return fmt.Errorf("configFrom of component %s names unknown component %s", component.Name, name)
+		}
+	}
+
+	for _, workload := range workloads {
+		podSpec, found, err := unstructured.NestedMap(workload.Object, "spec", "template", "spec")
+		if err != nil || !found {
+			continue
+		}
+		keys := append(podConfigReferences(podSpec, workload.GetNamespace()), declared...)
+		slices.Sort(keys)
+		keys = slices.Compact(keys)
+
+		digest := sha256.New()
+		referenced := false
+		for _, key := range keys {
+			if hash, ok := referable[key]; ok {
+				referenced = true
+				fmt.Fprintf(digest, "%s=%s\n", key, hash)
+			}
+		}
+		if !referenced {
+			continue
+		}
+		if err := unstructured.SetNestedField(workload.Object, hex.EncodeToString(digest.Sum(nil)),
+			"spec", "template", "metadata", "annotations", ConfigHashAnnotation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isConfigTemplate reports whether a component is a plain template of a ConfigMap or Secret
+func isConfigTemplate(component appv1alpha1.Component) bool {
+	if component.HelmChart != nil || component.Kustomize != nil || component.Source != nil ||
+		component.PorchPackageRef != nil || component.Hook != "" || len(component.Template.Raw) == 0 {
+		return false
+	}
+	var meta struct {
+		Kind string `json:"kind"`
+	}
+	if err := json.Unmarshal(component.Template.Raw, &meta); err != nil {
+		return false
+	}
+	return meta.Kind == "ConfigMap" || meta.Kind == "Secret"
+}
+
+// configHash hashes the content of a ConfigMap or Secret
+func configHash(obj *unstructured.Unstructured) (string, bool) {
+	if obj.GetKind() != "ConfigMap" && obj.GetKind() != "Secret" {
+		return "", false
+	}
+	content := map[string]interface{}{}
+	for _, field := range []string{"data", "binaryData", "stringData"} {
+		if value, found := obj.Object[field]; found {
+			content[field] = value
+		}
+	}
+	data, err := json.Marshal(content)
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), true
+}
+
+// configKey identifies a ConfigMap or Secret by kind, namespace and name
+func configKey(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
+
+// podConfigReferences lists the ConfigMaps and Secrets a pod spec references through
+// volumes, projected volumes, envFrom and env
+func podConfigReferences(podSpec map[string]interface{}, namespace string) []string {
+	var keys []string
+	add := func(kind string, source map[string]interface{}, field string) {
+		if name, ok := source[field].(string); ok && name != "" {
+			keys = append(keys, configKey(kind, namespace, name))
+		}
+	}
+	nested := func(source map[string]interface{}, field string) map[string]interface{} {
+		value, _ := source[field].(map[string]interface{})
+		return value
+	}
+
+	volumes, _, _ := unstructured.NestedSlice(podSpec, "volumes")
+	for _, item := range volumes {
+		volume, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		add("ConfigMap", nested(volume, "configMap"), "name")
+		add("Secret", nested(volume, "secret"), "secretName")
+		sources, _, _ := unstructured.NestedSlice(volume, "projected", "sources")
+		for _, item := range sources {
+			if source, ok := item.(map[string]interface{}); ok {
+				add("ConfigMap", nested(source, "configMap"), "name")
+				add("Secret", nested(source, "secret"), "name")
+			}
+		}
+	}
+
+	for _, field := range []string{"initContainers", "containers"} {
+		containers, _, _ := unstructured.NestedSlice(podSpec, field)
+		for _, item := range containers {
+			container, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			envFrom, _, _ := unstructured.NestedSlice(container, "envFrom")
+			for _, item := range envFrom {
+				if source, ok := item.(map[string]interface{}); ok {
+					add("ConfigMap", nested(source, "configMapRef"), "name")
+					add("Secret", nested(source, "secretRef"), "name")
+				}
+			}
+			env, _, _ := unstructured.NestedSlice(container, "env")
+			for _, item := range env {
+				if variable, ok := item.(map[string]interface{}); ok {
+					valueFrom := nested(variable, "valueFrom")
+					add("ConfigMap", nested(valueFrom, "configMapKeyRef"), "name")
+					add("Secret", nested(valueFrom, "secretKeyRef"), "name")
+				}
+			}
+		}
+	}
+	return keys
+}
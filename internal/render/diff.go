@@ -0,0 +1,272 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package render
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+)
+
+// DiffObject returns the differences between a rendered object and its live state in the
+// format used for plans, honouring the bundle's ignoreDifferences rules. It returns an empty
+// string when the live object matches.
+func DiffObject(appBundle *appv1alpha1.AppBundle, desired, live *unstructured.Unstructured) (string, error) {
+	compared := desired.DeepCopy()
+	if err := ApplyIgnoreDifferences(appBundle, compared, live); err != nil {
+		return "", err
+	}
+	return FormatChanges(RedactSecretChanges(desired, ChangedFields(compared.Object, live.Object))), nil
+}
+
+// FieldChange is a field whose live value differs from its desired value
+type FieldChange struct {
+	Path    string
+	Desired interface{}
+	Live    interface{}
+}
+
+// quantityFields are the maps whose values are resource quantities, which the API server normalises
+var quantityFields = map[string]bool{
+	"limits":               true,
+	"requests":             true,
+	"hard":                 true,
+	"used":                 true,
+	"capacity":             true,
+	"overhead":             true,
+	"max":                  true,
+	"min":                  true,
+	"default":              true,
+	"defaultRequest":       true,
+	"maxLimitRequestRatio": true,
+}
+
+// ChangedFields returns the fields set in desired whose live value differs, sorted by path
+func ChangedFields(desired, live map[string]interface{}) []FieldChange {
+	desired = foldStringData(desired)
+	var changes []FieldChange
+	for key, value := range desired {
+		switch key {
+		case "status":
+			continue
+		case "metadata":
+			desiredMeta, _ := value.(map[string]interface{})
+			liveMeta, _ := live["metadata"].(map[string]interface{})
+			for _, metaKey := range []string{"labels", "annotations"} {
+				if desiredValue, ok := desiredMeta[metaKey]; ok {
+					changes = append(changes, compareField("metadata."+metaKey, desiredValue, liveMeta[metaKey])...)
+				}
+			}
+		default:
+			changes = append(changes, compareField(key, value, live[key])...)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// RemovedFields returns the fields set in live that desired no longer holds, which an update
+// replacing live with desired removes, sorted by path
+func RemovedFields(desired, live map[string]interface{}) []FieldChange {
+	var removed []FieldChange
+	for _, change := range ChangedFields(live, desired) {
+		if change.Live == nil {
+			removed = append(removed, FieldChange{Path: change.Path, Live: change.Desired})
+		}
+	}
+	return removed
+}
+
+// compareField recursively checks that desired is contained in live
+func compareField(path string, desired, live interface{}) []FieldChange {
+	switch desiredValue := desired.(type) {
+	case map[string]interface{}:
+		liveValue, ok := live.(map[string]interface{})
+		if !ok {
+			return []FieldChange{{Path: path, Desired: desired, Live: live}}
+		}
+		var changes []FieldChange
+		for key, value := range desiredValue {
+			changes = append(changes, compareField(path+"."+key, value, liveValue[key])...)
+		}
+		return changes
+	case []interface{}:
+		liveValue, ok := live.([]interface{})
+		if !ok || len(liveValue) != len(desiredValue) {
+			return []FieldChange{{Path: path, Desired: desired, Live: live}}
+		}
+		var changes []FieldChange
+		for i := range desiredValue {
+			changes = append(changes, compareField(fmt.Sprintf("%s[%d]", path, i), desiredValue[i], liveValue[i])...)
+		}
+		return changes
+	case nil:
+		return nil
+	default:
+		// Compare scalars by their string form so 1, 1.0 and "1" match
+		if live == nil {
+			return []FieldChange{{Path: path, Desired: desired, Live: live}}
+		}
+		if fmt.Sprint(desiredValue) == fmt.Sprint(live) || (isQuantityField(path) && equalQuantities(desiredValue, live)) {
+			return nil
+		}
+		return []FieldChange{{Path: path, Desired: desired, Live: live}}
+	}
+}
+
+// isQuantityField reports whether the field at path holds a resource quantity, such as a value
+// of resources.limits or the sizeLimit of an emptyDir volume
+func isQuantityField(path string) bool {
+	segments := strings.Split(path, ".")
+	if segments[len(segments)-1] == "sizeLimit" {
+		return true
+	}
+	if len(segments) < 2 {
+		return false
+	}
+	parent := segments[len(segments)-2]
+	if index := strings.Index(parent, "["); index >= 0 {
+		parent = parent[:index]
+	}
+	return quantityFields[parent]
+}
+
+// foldStringData returns a Secret with its stringData merged into data, as the API server
+// stores it, so a Secret written with stringData compares against its live data
+func foldStringData(obj map[string]interface{}) map[string]interface{} {
+	stringData, ok := obj["stringData"].(map[string]interface{})
+	if !ok || obj["kind"] != "Secret" || obj["apiVersion"] != "v1" {
+		return obj
+	}
+	folded := make(map[string]interface{}, len(obj))
+	for key, value := range obj {
+		folded[key] = value
+	}
+	data := map[string]interface{}{}
+	if existing, ok := obj["data"].(map[string]interface{}); ok {
+		for key, value := range existing {
+			data[key] = value
+		}
+	}
+	// stringData takes precedence over data, as it does on write
+	for key, value := range stringData {
+		data[key] = base64.StdEncoding.EncodeToString([]byte(fmt.Sprint(value)))
+	}
+	folded["data"] = data
+	delete(folded, "stringData")
+	return folded
+}
+
+// equalQuantities reports whether two scalars are the same resource quantity in different
+// forms, such as 1000m and 1 or 1073741824 and 1Gi, which the API server normalises
+func equalQuantities(desired, live interface{}) bool {
+	desiredQuantity, err := resource.ParseQuantity(fmt.Sprint(desired))
+	if err != nil {
+		return false
+	}
+	liveQuantity, err := resource.ParseQuantity(fmt.Sprint(live))
+	if err != nil {
+		return false
+	}
+	return desiredQuantity.Cmp(liveQuantity) == 0
+}
+
+// redactedValue replaces the values of Secrets in plans and diffs
+const redactedValue = "<redacted>"
+
+// isSecret reports whether obj is a core Secret
+func isSecret(obj map[string]interface{}) bool {
+	return obj["kind"] == "Secret" && obj["apiVersion"] == "v1"
+}
+
+// RedactSecret returns a copy of obj in which the values of a Secret's data and stringData are
+// redacted, or obj itself when it is not a Secret
+func RedactSecret(obj map[string]interface{}) map[string]interface{} {
+	if !isSecret(obj) {
+		return obj
+	}
+	redacted := make(map[string]interface{}, len(obj))
+	for key, value := range obj {
+		if key == "data" || key == "stringData" {
+			value = redact(value)
+		}
+		redacted[key] = value
+	}
+	return redacted
+}
+
+// RedactSecretChanges redacts the values of the changed data and stringData fields of a Secret
+func RedactSecretChanges(obj *unstructured.Unstructured, changes []FieldChange) []FieldChange {
+	if !isSecret(obj.Object) {
+		return changes
+	}
+	redacted := make([]FieldChange, 0, len(changes))
+	for _, change := range changes {
+		for _, field := range []string{"data", "stringData"} {
+			if change.Path == field || strings.HasPrefix(change.Path, field+".") {
+				change.Desired = redact(change.Desired)
+				change.Live = redact(change.Live)
+			}
+		}
+		redacted = append(redacted, change)
+	}
+	return redacted
+}
+
+// redact replaces a value, or every value of a map, with a placeholder
+func redact(value interface{}) interface{} {
+	switch value := value.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(value))
+		for key := range value {
+			redacted[key] = redactedValue
+		}
+		return redacted
+	default:
+		return redactedValue
+	}
+}
+
+// FormatChanges renders changed fields as a diff from the live to the desired value
+func FormatChanges(changes []FieldChange) string {
+	var diff strings.Builder
+	for _, change := range changes {
+		fmt.Fprintf(&diff, "~ %s\n- %s\n+ %s\n", change.Path, formatValue(change.Live), formatValue(change.Desired))
+	}
+	return diff.String()
+}
+
+// formatValue renders a field value on a single line
+func formatValue(value interface{}) string {
+	if value == nil {
+		return "<none>"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
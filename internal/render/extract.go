@@ -0,0 +1,80 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/jsonpath"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+)
+
+// OutputObject returns the rendered object an output reads: the first object of the
+// output's kind and name, each defaulting to any
+func OutputObject(objects []*unstructured.Unstructured, output appv1alpha1.ComponentOutput) (*unstructured.Unstructured, error) {
+	for _, obj := range objects {
+		if output.Kind != "" && obj.GetKind() != output.Kind {
+			continue
+		}
+		if output.ObjectName != "" && obj.GetName() != output.ObjectName {
+			continue
+		}
+		return obj, nil
+	}
+	return nil, fmt.Errorf("output %s: no object of kind %q named %q", output.Name, output.Kind, output.ObjectName)
+}
+
+// EvaluateOutput extracts the value of an output from a live object. A JSONPath matching
+// several values yields a list.
+func EvaluateOutput(output appv1alpha1.ComponentOutput, live *unstructured.Unstructured) (interface{}, error) {
+	if output.Expression != "" {
+		value, err := evaluateCEL(output.Expression, map[string]interface{}{"object": live.Object})
+		if err != nil {
+			return nil, fmt.Errorf("output %s: %w", output.Name, err)
+		}
+		return value, nil
+	}
+
+	path := output.JSONPath
+	if !strings.HasPrefix(path, "{") {
+		path = "{" + path + "}"
+	}
+	parser := jsonpath.New(output.Name)
+	if err := parser.Parse(path); err != nil {
+		return nil, fmt.Errorf("output %s: invalid JSONPath %q: %w", output.Name, output.JSONPath, err)
+	}
+	results, err := parser.FindResults(live.Object)
+	if err != nil {
+		return nil, fmt.Errorf("output %s: %w", output.Name, err)
+	}
+	var values []interface{}
+	for _, result := range results {
+		for _, value := range result {
+			values = append(values, value.Interface())
+		}
+	}
+	switch len(values) {
+	case 0:
+		return nil, fmt.Errorf("output %s: %s matches nothing", output.Name, output.JSONPath)
+	case 1:
+		return values[0], nil
+	}
+	return values, nil
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package render
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+)
+
+const (
+	// KustomizationAPIVersion is the API version of Flux Kustomizations
+	KustomizationAPIVersion = "kustomize.toolkit.fluxcd.io/v1"
+	// KustomizationKind is the kind of Flux Kustomizations
+	KustomizationKind = "Kustomization"
+)
+
+// Backend returns the delivery backend of an AppBundle. Without an explicit backend, bundles
+// that use Porch are delivered through Porch and all others directly.
+func Backend(appBundle *appv1alpha1.AppBundle) appv1alpha1.DeliveryBackend {
+	if appBundle.Spec.Backend != "" {
+		return appBundle.Spec.Backend
+	}
+	if appBundle.Spec.PorchIntegration != nil && appBundle.Spec.PorchIntegration.Enabled {
+		return appv1alpha1.BackendPorch
+	}
+	for _, group := range Groups(appBundle) {
+		for _, component := range group.Components {
+			if component.PorchPackageRef != nil {
+				return appv1alpha1.BackendPorch
+			}
+		}
+	}
+	return appv1alpha1.BackendDirect
+}
+
+// ValidateClusterBundle reports what a ClusterAppBundle, an AppBundle without a namespace,
+// cannot deliver. The CRD rejects most of it, but not what a template brings in.
+func ValidateClusterBundle(appBundle *appv1alpha1.AppBundle) error {
+	if appBundle.Namespace != "" {
+		return nil
+	}
+	for _, group := range Groups(appBundle) {
+		for _, component := range group.Components {
+			if component.PorchPackageRef != nil {
+				return fmt.Errorf("component %s/%s references a Porch package, which is only available to AppBundles",
+					group.Name, component.Name)
+			}
+		}
+	}
+	switch Backend(appBundle) {
+	case appv1alpha1.BackendPorch:
+		return errors.New("the Porch backend is only available to AppBundles")
+	case appv1alpha1.BackendFlux:
+		if KustomizationNamespace(appBundle) == "" {
+			return errors.New("the Flux backend of a ClusterAppBundle requires spec.flux.namespace")
+		}
+	}
+	return nil
+}
+
+// maxKustomizationNameLength is the longest name of a Flux Kustomization, which Flux
+// records as a label value on the objects it applies
+const maxKustomizationNameLength = 63
+
+// KustomizationName returns the name of the Flux Kustomization of a group. The bundle's
+// namespace, or "cluster" for a ClusterAppBundle, is included because bundles may share
+// spec.flux.namespace, and a hash of namespace, name and group keeps the names apart when
+// they are truncated or their parts contain dashes.
+func KustomizationName(appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group) string {
+	sum := sha256.Sum256([]byte(appBundle.Namespace + "/" + appBundle.Name + "/" + group.Name))
+	suffix := "-" + hex.EncodeToString(sum[:])[:8]
+	prefix := appBundle.Namespace
+	if prefix == "" {
+		prefix = "cluster"
+	}
+	name := prefix + "-" + appBundle.Name + "-" + group.Name
+	if len(name)+len(suffix) > maxKustomizationNameLength {
+		name = name[:maxKustomizationNameLength-len(suffix)]
+	}
+	return name + suffix
+}
+
+// KustomizationNamespace returns the namespace of the Flux Kustomizations of a bundle
+// (default to the AppBundle namespace). It is empty for a ClusterAppBundle without
+// spec.flux.namespace, which ValidateClusterBundle rejects.
+func KustomizationNamespace(appBundle *appv1alpha1.AppBundle) string {
+	if appBundle.Spec.Flux != nil && appBundle.Spec.Flux.Namespace != "" {
+		return appBundle.Spec.Flux.Namespace
+	}
+	return appBundle.Namespace
+}
+
+// GroupPath returns the path within the Flux source that holds the manifests of a group
+func GroupPath(appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group) string {
+	basePath := ""
+	if appBundle.Spec.Flux != nil {
+		basePath = appBundle.Spec.Flux.Path
+	}
+	return "./" + path.Join(basePath, group.Name)
+}
+
+// FluxBundle renders one Flux Kustomization per group in deployment order. Each
+// Kustomization depends on the one of the previous group, so Flux deploys the groups in order.
+func FluxBundle(appBundle *appv1alpha1.AppBundle) ([]RenderedGroup, error) {
+	if appBundle.Spec.Flux == nil {
+		return nil, errors.New("the Flux backend requires spec.flux")
+	}
+
+	var groups []RenderedGroup
+	previous := ""
+	for _, group := range SortedGroups(appBundle) {
+		for _, component := range group.Components {
+			if component.PorchPackageRef != nil {
+				return nil, fmt.Errorf("component %s references a Porch package, which the Flux backend cannot deliver", component.Name)
+			}
+			if component.Hook != "" {
+				return nil, fmt.Errorf("component %s is a hook, which the Flux backend cannot run", component.Name)
+			}
+			if component.RolloutStrategy != nil {
+				return nil, fmt.Errorf("component %s has a rolloutStrategy, which the Flux backend cannot run", component.Name)
+			}
+			if component.DeletionPolicy != "" {
+				return nil, fmt.Errorf("component %s has a deletionPolicy, which the Flux backend cannot apply to a single component", component.Name)
+			}
+		}
+		kustomization := FluxKustomization(appBundle, group, previous)
+		groups = append(groups, RenderedGroup{
+			Name:    group.Name,
+			Order:   group.Order,
+			Objects: []*unstructured.Unstructured{kustomization},
+		})
+		previous = kustomization.GetName()
+	}
+	return groups, nil
+}
+
+// FluxKustomization renders the Flux Kustomization of a group. dependsOn names the
+// Kustomization of the previous group, or is empty for the first group.
+func FluxKustomization(appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group, dependsOn string) *unstructured.Unstructured {
+	flux := appBundle.Spec.Flux
+	interval := 5 * time.Minute
+	if flux.Interval != nil {
+		interval = flux.Interval.Duration
+	}
+	sourceKind := flux.SourceRef.Kind
+	if sourceKind == "" {
+		sourceKind = "GitRepository"
+	}
+	sourceRef := map[string]interface{}{
+		"kind": sourceKind,
+		"name": flux.SourceRef.Name,
+	}
+	if flux.SourceRef.Namespace != "" {
+		sourceRef["namespace"] = flux.SourceRef.Namespace
+	}
+
+	spec := map[string]interface{}{
+		"interval":  interval.String(),
+		"path":      GroupPath(appBundle, group),
+		"prune":     flux.Prune,
+		"sourceRef": sourceRef,
+		// Waiting for every applied resource makes the Kustomization Ready only once the
+		// group is deployed, which is what the next group depends on
+		"wait": true,
+	}
+	// Flux applies a whole group at once, so only the bundle's deletion policy applies
+	if appBundle.Spec.DeletionPolicy == appv1alpha1.DeletionPolicyOrphan || appBundle.Spec.DeletionPolicy == appv1alpha1.DeletionPolicyRetain {
+		spec["deletionPolicy"] = "Orphan"
+	}
+	if dependsOn != "" {
+		spec["dependsOn"] = []interface{}{map[string]interface{}{"name": dependsOn}}
+	}
+
+	kustomization := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	kustomization.SetAPIVersion(KustomizationAPIVersion)
+	kustomization.SetKind(KustomizationKind)
+	kustomization.SetName(KustomizationName(appBundle, group))
+	kustomization.SetNamespace(KustomizationNamespace(appBundle))
+	kustomization.SetLabels(map[string]string{
+		AppBundleLabel:          appBundle.Name,
+		AppBundleNamespaceLabel: appBundle.Namespace,
+		GroupLabel:              group.Name,
+	})
+	return kustomization
+}
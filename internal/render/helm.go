@@ -0,0 +1,256 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package render
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+)
+
+const (
+	// HelmHookAnnotation marks a rendered object as a Helm hook
+	HelmHookAnnotation = "helm.sh/hook"
+	// HelmHookDeletePolicyAnnotation holds the delete policy of a Helm hook
+	HelmHookDeletePolicyAnnotation = "helm.sh/hook-delete-policy"
+
+	argoHookAnnotation             = "argocd.argoproj.io/hook"
+	argoHookDeletePolicyAnnotation = "argocd.argoproj.io/hook-delete-policy"
+)
+
+// HelmChart renders the chart of a component like `helm template`, in install order: the
+// chart's CRDs, pre-install hooks, the chart's resources, then post-install hooks. Test, delete
+// and rollback hooks are not rendered.
+func HelmChart(ctx context.Context, appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group, component appv1alpha1.Component, baseSyncWave int, fetcher Fetcher) ([]*unstructured.Unstructured, error) {
+	source := component.HelmChart
+	if fetcher == nil {
+		return nil, fmt.Errorf("component %s: rendering Helm charts requires a fetcher", component.Name)
+	}
+	chrt, err := fetcher.HelmChart(ctx, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart of component %s: %w", component.Name, err)
+	}
+	values, err := HelmValues(ctx, appBundle, source, fetcher)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve values of component %s: %w", component.Name, err)
+	}
+
+	releaseName := source.ReleaseName
+	if releaseName == "" {
+		releaseName = component.Name
+	}
+	install := action.NewInstall(&action.Configuration{Log: func(string, ...interface{}) {}})
+	install.DryRun = true
+	install.DryRunOption = "client"
+	install.ClientOnly = true
+	install.Replace = true
+	install.IncludeCRDs = true
+	install.ReleaseName = releaseName
+	install.Namespace = appBundle.Namespace
+	rel, err := install.RunWithContext(ctx, chrt, values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render chart of component %s: %w", component.Name, err)
+	}
+
+	resources, err := parseManifests(rel.Manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rendered chart of component %s: %w", component.Name, err)
+	}
+	preHooks, postHooks, err := sortHooks(rel.Hooks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse hooks of component %s: %w", component.Name, err)
+	}
+
+	// CRDs go first, as Helm installs them before running any hook
+	var crds, others []*unstructured.Unstructured
+	for _, obj := range resources {
+		if obj.GetKind() == "CustomResourceDefinition" {
+			crds = append(crds, obj)
+		} else {
+			others = append(others, obj)
+		}
+	}
+	var objects []*unstructured.Unstructured
+	objects = append(objects, crds...)
+	objects = append(objects, preHooks...)
+	objects = append(objects, others...)
+	objects = append(objects, postHooks...)
+
+	for _, obj := range objects {
+		decorate(obj, appBundle, group, component, baseSyncWave+component.Order)
+		mapHookAnnotations(obj)
+	}
+	return objects, nil
+}
+
+// IsHelmHook reports whether a rendered object is a Helm hook
+func IsHelmHook(obj *unstructured.Unstructured) bool {
+	_, ok := obj.GetAnnotations()[HelmHookAnnotation]
+	return ok
+}
+
+// RecreateHelmHook reports whether an existing copy of a hook must be deleted before the hook
+// is applied again; Helm does this unless the hook's delete policy says otherwise
+func RecreateHelmHook(obj *unstructured.Unstructured) bool {
+	if !IsHelmHook(obj) {
+		return false
+	}
+	policy, ok := obj.GetAnnotations()[HelmHookDeletePolicyAnnotation]
+	return !ok || strings.Contains(policy, string(release.HookBeforeHookCreation))
+}
+
+// DeleteHelmHook reports whether a hook that has run is deleted, because its delete policy
+// is hook-succeeded and it succeeded or hook-failed and it failed
+func DeleteHelmHook(obj *unstructured.Unstructured, succeeded bool) bool {
+	if !IsHelmHook(obj) {
+		return false
+	}
+	deleteOn := release.HookFailed
+	if succeeded {
+		deleteOn = release.HookSucceeded
+	}
+	for _, policy := range strings.Split(obj.GetAnnotations()[HelmHookDeletePolicyAnnotation], ",") {
+		if release.HookDeletePolicy(strings.TrimSpace(policy)) == deleteOn {
+			return true
+		}
+	}
+	return false
+}
+
+// mapHookAnnotations adds the Argo CD equivalents of a Helm hook's annotations, so that
+// Argo CD runs pre-install hooks before and post-install hooks after the sync
+func mapHookAnnotations(obj *unstructured.Unstructured) {
+	annotations := obj.GetAnnotations()
+	events, ok := annotations[HelmHookAnnotation]
+	if !ok {
+		return
+	}
+	if strings.Contains(events, string(release.HookPreInstall)) || strings.Contains(events, string(release.HookPreUpgrade)) {
+		annotations[argoHookAnnotation] = "PreSync"
+	} else {
+		annotations[argoHookAnnotation] = "PostSync"
+	}
+
+	var policies []string
+	for _, policy := range strings.Split(annotations[HelmHookDeletePolicyAnnotation], ",") {
+		switch release.HookDeletePolicy(strings.TrimSpace(policy)) {
+		case release.HookSucceeded:
+			policies = append(policies, "HookSucceeded")
+		case release.HookFailed:
+			policies = append(policies, "HookFailed")
+		case release.HookBeforeHookCreation:
+			policies = append(policies, "BeforeHookCreation")
+		}
+	}
+	if len(policies) > 0 {
+		annotations[argoHookDeletePolicyAnnotation] = strings.Join(policies, ",")
+	}
+	obj.SetAnnotations(annotations)
+}
+
+// HelmValues merges the values of a chart: valuesFrom in order, then the inline values
+func HelmValues(ctx context.Context, appBundle *appv1alpha1.AppBundle, source *appv1alpha1.HelmChartSource, fetcher Fetcher) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	if len(source.ValuesFrom) > 0 {
+		var err error
+		if values, err = fetcher.Values(ctx, appBundle.Namespace, source.ValuesFrom); err != nil {
+			return nil, err
+		}
+	}
+	if len(source.Values.Raw) > 0 {
+		inline := map[string]interface{}{}
+		if err := json.Unmarshal(source.Values.Raw, &inline); err != nil {
+			return nil, fmt.Errorf("failed to parse values: %w", err)
+		}
+		values = chartutil.CoalesceTables(inline, values)
+	}
+	return values, nil
+}
+
+// sortHooks returns the pre- and post-install hooks, ordered by weight and name like Helm
+// runs them
+func sortHooks(hooks []*release.Hook) (pre, post []*unstructured.Unstructured, err error) {
+	sorted := append([]*release.Hook{}, hooks...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Weight != sorted[j].Weight {
+			return sorted[i].Weight < sorted[j].Weight
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	for _, hook := range sorted {
+		var objects []*unstructured.Unstructured
+		if objects, err = parseManifests(hook.Manifest); err != nil {
+			return nil, nil, err
+		}
+		switch {
+		case hasHookEvent(hook, release.HookPreInstall, release.HookPreUpgrade):
+			pre = append(pre, objects...)
+		case hasHookEvent(hook, release.HookPostInstall, release.HookPostUpgrade):
+			post = append(post, objects...)
+		}
+	}
+	return pre, post, nil
+}
+
+// hasHookEvent reports whether a hook runs on any of the given events
+func hasHookEvent(hook *release.Hook, events ...release.HookEvent) bool {
+	for _, event := range hook.Events {
+		for _, candidate := range events {
+			if event == candidate {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseManifests decodes a multi-document YAML stream, skipping empty documents
+func parseManifests(manifests string) ([]*unstructured.Unstructured, error) {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(strings.NewReader(manifests)))
+	var objects []*unstructured.Unstructured
+	for {
+		document, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return objects, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		obj := map[string]interface{}{}
+		if err := yaml.Unmarshal(document, &obj); err != nil {
+			return nil, err
+		}
+		if len(obj) == 0 {
+			continue
+		}
+		objects = append(objects, &unstructured.Unstructured{Object: obj})
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package render
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+)
+
+// HookAnnotation records the hook type of an object rendered from a hook component
+const HookAnnotation = "app.example.com/hook"
+
+// argoHookPhases maps hook types to the Argo CD hook that runs at the same point of a sync
+var argoHookPhases = map[appv1alpha1.HookType]string{
+	appv1alpha1.HookPreGroup:   "Sync",
+	appv1alpha1.HookPostGroup:  "Sync",
+	appv1alpha1.HookPostDeploy: "PostSync",
+	appv1alpha1.HookPreDelete:  "PreDelete",
+}
+
+// Hook is a hook component together with the group it is declared in
+type Hook struct {
+	Group     appv1alpha1.Group
+	Component appv1alpha1.Component
+}
+
+// IsHook reports whether a rendered object belongs to a hook component
+func IsHook(obj *unstructured.Unstructured) bool {
+	_, ok := obj.GetAnnotations()[HookAnnotation]
+	return ok
+}
+
+// GroupComponents splits the components of a group, sorted by order, into its PreGroup hooks,
+// the components it deploys and its PostGroup hooks. Hooks of other types are left out.
+func GroupComponents(group appv1alpha1.Group) (pre, components, post []appv1alpha1.Component) {
+	for _, component := range SortedComponents(group) {
+		switch component.Hook {
+		case "":
+			components = append(components, component)
+		case appv1alpha1.HookPreGroup:
+			pre = append(pre, component)
+		case appv1alpha1.HookPostGroup:
+			post = append(post, component)
+		}
+	}
+	return pre, components, post
+}
+
+// BundleHooks returns the hooks of a type across every group, in deployment order
+func BundleHooks(appBundle *appv1alpha1.AppBundle, hook appv1alpha1.HookType) []Hook {
+	var hooks []Hook
+	for _, group := range SortedGroups(appBundle) {
+		for _, component := range SortedComponents(group) {
+			if component.Hook == hook {
+				hooks = append(hooks, Hook{Group: group, Component: component})
+			}
+		}
+	}
+	return hooks
+}
+
+// HasHookDeletePolicy reports whether a hook component has a delete policy. Hooks without
+// any policy are deleted before they run again.
+func HasHookDeletePolicy(component appv1alpha1.Component, policy appv1alpha1.HookDeletePolicy) bool {
+	if len(component.HookDeletePolicies) == 0 {
+		return policy == appv1alpha1.HookDeleteBeforeCreation
+	}
+	return slices.Contains(component.HookDeletePolicies, policy)
+}
+
+// decorateHook marks the objects of a hook component and adds the Argo CD hook annotations
+// that run them at the same point of a sync. Group hooks take the waves just before and
+// after the group's range.
+func decorateHook(obj *unstructured.Unstructured, group appv1alpha1.Group, component appv1alpha1.Component) {
+	annotations := obj.GetAnnotations()
+	annotations[HookAnnotation] = string(component.Hook)
+	annotations[argoHookAnnotation] = argoHookPhases[component.Hook]
+	switch component.Hook {
+	case appv1alpha1.HookPreGroup:
+		annotations[SyncWaveAnnotation] = strconv.Itoa(BaseSyncWave(group) - 1)
+	case appv1alpha1.HookPostGroup:
+		annotations[SyncWaveAnnotation] = strconv.Itoa(BaseSyncWave(group) + 99)
+	}
+	if len(component.HookDeletePolicies) > 0 {
+		policies := make([]string, 0, len(component.HookDeletePolicies))
+		for _, policy := range component.HookDeletePolicies {
+			policies = append(policies, string(policy))
+		}
+		annotations[argoHookDeletePolicyAnnotation] = strings.Join(policies, ",")
+	}
+	obj.SetAnnotations(annotations)
+}
@@ -0,0 +1,216 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package render
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+)
+
+// pathSegment is one step of an ignored field path
+type pathSegment struct {
+	// key selects a map entry; empty for pure list steps
+	key string
+	// index selects a list element; -1 if the step does not address a list
+	index int
+	// wildcard selects every list element
+	wildcard bool
+}
+
+// parseJSONPointer parses an RFC 6901 JSON pointer such as /spec/replicas
+func parseJSONPointer(pointer string) ([]pathSegment, error) {
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q: must start with /", pointer)
+	}
+	var segments []pathSegment
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		segment := pathSegment{key: token, index: -1}
+		// Numeric tokens address list elements when the value is a list
+		if index, err := strconv.Atoi(token); err == nil && index >= 0 {
+			segment.index = index
+		}
+		segments = append(segments, segment)
+	}
+	return segments, nil
+}
+
+// parseJQPath parses a jq-style path such as .spec.template.spec.containers[].image
+func parseJQPath(expression string) ([]pathSegment, error) {
+	if !strings.HasPrefix(expression, ".") && !strings.HasPrefix(expression, "[") {
+		return nil, fmt.Errorf("invalid jq path %q: must start with . or [", expression)
+	}
+
+	var segments []pathSegment
+	rest := expression
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			if end == 0 {
+				if rest == "" || rest[0] == '[' {
+					continue
+				}
+				return nil, fmt.Errorf("invalid jq path %q: empty field name", expression)
+			}
+			segments = append(segments, pathSegment{key: rest[:end], index: -1})
+			rest = rest[end:]
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end == -1 {
+				return nil, fmt.Errorf("invalid jq path %q: unterminated [", expression)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			switch {
+			case inner == "":
+				segments = append(segments, pathSegment{index: -1, wildcard: true})
+			case strings.HasPrefix(inner, `"`):
+				key, err := strconv.Unquote(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid jq path %q: %w", expression, err)
+				}
+				segments = append(segments, pathSegment{key: key, index: -1})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("invalid jq path %q: bad index %q", expression, inner)
+				}
+				segments = append(segments, pathSegment{index: index})
+			}
+		default:
+			return nil, fmt.Errorf("invalid jq path %q: unexpected %q", expression, rest)
+		}
+	}
+	return segments, nil
+}
+
+// ignoreDifferencesFor returns the ignore rules of the bundle and of the component
+// that rendered obj, as identified by its tracking labels
+func ignoreDifferencesFor(appBundle *appv1alpha1.AppBundle, obj *unstructured.Unstructured) []appv1alpha1.IgnoreDifference {
+	rules := append([]appv1alpha1.IgnoreDifference{}, appBundle.Spec.IgnoreDifferences...)
+	groupName := obj.GetLabels()[GroupLabel]
+	componentName := obj.GetLabels()[ComponentLabel]
+	for _, group := range Groups(appBundle) {
+		if group.Name != groupName {
+			continue
+		}
+		for _, component := range group.Components {
+			if component.Name == componentName {
+				rules = append(rules, component.IgnoreDifferences...)
+			}
+		}
+	}
+	return rules
+}
+
+// ruleMatches reports whether an ignore rule applies to obj
+func ruleMatches(rule appv1alpha1.IgnoreDifference, obj *unstructured.Unstructured) bool {
+	gvk := obj.GroupVersionKind()
+	return (rule.Group == "" || rule.Group == gvk.Group) &&
+		(rule.Kind == "" || rule.Kind == gvk.Kind) &&
+		(rule.Name == "" || rule.Name == obj.GetName())
+}
+
+// ApplyIgnoreDifferences replaces the ignored fields of desired with their values in live,
+// so that they are neither overwritten on update nor reported as drift
+func ApplyIgnoreDifferences(appBundle *appv1alpha1.AppBundle, desired, live *unstructured.Unstructured) error {
+	for _, rule := range ignoreDifferencesFor(appBundle, desired) {
+		if !ruleMatches(rule, desired) {
+			continue
+		}
+		var paths [][]pathSegment
+		for _, pointer := range rule.JSONPointers {
+			segments, err := parseJSONPointer(pointer)
+			if err != nil {
+				return err
+			}
+			paths = append(paths, segments)
+		}
+		for _, expression := range rule.JQPathExpressions {
+			segments, err := parseJQPath(expression)
+			if err != nil {
+				return err
+			}
+			paths = append(paths, segments)
+		}
+		for _, segments := range paths {
+			if preserved, ok := preserveField(desired.Object, live.Object, segments).(map[string]interface{}); ok {
+				desired.Object = preserved
+			}
+		}
+	}
+	return nil
+}
+
+// preserveField returns desired with the value at path taken from live. Fields missing
+// from live are removed from desired; list elements are only matched by position.
+func preserveField(desired, live interface{}, path []pathSegment) interface{} {
+	if len(path) == 0 {
+		if live == nil {
+			return nil
+		}
+		return runtime.DeepCopyJSONValue(live)
+	}
+	segment := path[0]
+
+	if desiredList, ok := desired.([]interface{}); ok && (segment.wildcard || segment.index >= 0) {
+		liveList, _ := live.([]interface{})
+		for i := range desiredList {
+			if (!segment.wildcard && i != segment.index) || i >= len(liveList) {
+				continue
+			}
+			if value := preserveField(desiredList[i], liveList[i], path[1:]); value != nil {
+				desiredList[i] = value
+			}
+		}
+		return desiredList
+	}
+
+	if segment.wildcard || segment.key == "" {
+		return desired
+	}
+	desiredMap, isMap := desired.(map[string]interface{})
+	liveMap, _ := live.(map[string]interface{})
+	if !isMap {
+		if desired != nil || liveMap == nil {
+			return desired
+		}
+		desiredMap = map[string]interface{}{}
+	}
+
+	value := preserveField(desiredMap[segment.key], liveMap[segment.key], path[1:])
+	if value == nil {
+		delete(desiredMap, segment.key)
+	} else {
+		desiredMap[segment.key] = value
+	}
+	if desired == nil && len(desiredMap) == 0 {
+		return nil
+	}
+	return desiredMap
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package render
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/kustomize/api/konfig"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+)
+
+// Kustomization builds the kustomization of a component like `kustomize build`, in
+// kustomize's output order: namespaces, CRDs, RBAC and configuration before the workloads
+// that depend on them
+func Kustomization(ctx context.Context, appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group, component appv1alpha1.Component, baseSyncWave int, fetcher Fetcher) ([]*unstructured.Unstructured, error) {
+	if fetcher == nil {
+		return nil, fmt.Errorf("component %s: building kustomizations requires a fetcher", component.Name)
+	}
+	fs, err := fetcher.Kustomization(ctx, appBundle.Namespace, component.Kustomize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kustomization of component %s: %w", component.Name, err)
+	}
+
+	objects, err := buildKustomization(fs, path.Join("/", component.Kustomize.Path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build kustomization of component %s: %w", component.Name, err)
+	}
+	for _, obj := range objects {
+		decorate(obj, appBundle, group, component, baseSyncWave+component.Order)
+	}
+	return objects, nil
+}
+
+// buildKustomization runs kustomize on the kustomization in dir
+func buildKustomization(fs filesys.FileSystem, dir string) ([]*unstructured.Unstructured, error) {
+	// Like `kustomize build`, sort in legacy order unless the kustomization sets sortOptions,
+	// so that ConfigMaps and Secrets are ready before the workloads that mount them. The
+	// default options keep plugins disabled and loads within the kustomization root.
+	options := krusty.MakeDefaultOptions()
+	options.Reorder = krusty.ReorderOptionLegacy
+	resources, err := krusty.MakeKustomizer(options).Run(fs, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []*unstructured.Unstructured
+	for _, resource := range resources.Resources() {
+		// Round-trip through JSON so numbers have the types unstructured objects expect
+		data, err := resource.MarshalJSON()
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert resource %s: %w", resource.CurId(), err)
+		}
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(data); err != nil {
+			return nil, fmt.Errorf("failed to convert resource %s: %w", resource.CurId(), err)
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
+// hasKustomization reports whether dir holds a kustomization file
+func hasKustomization(fs filesys.FileSystem, dir string) bool {
+	for _, name := range konfig.RecognizedKustomizationFileNames() {
+		if fs.Exists(path.Join(dir, name)) {
+			return true
+		}
+	}
+	return false
+}
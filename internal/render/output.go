@@ -0,0 +1,118 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package render
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// KustomizationFile is the name of the kustomization written by WriteKustomization
+const KustomizationFile = "kustomization.yaml"
+
+// WriteManifests writes objects as a multi-document YAML stream. Each document starts with a
+// comment naming its group, component and sync wave.
+func WriteManifests(w io.Writer, objects []*unstructured.Unstructured) error {
+	for i, obj := range objects {
+		data, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s %s: %w", obj.GetKind(), obj.GetName(), err)
+		}
+		if i > 0 {
+			if _, err := io.WriteString(w, "---\n"); err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(w, header(obj)); err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// header returns the comment that names the group, component and sync wave of an object.
+// Objects rendered for a whole group, such as Flux Kustomizations, only name the group.
+func header(obj *unstructured.Unstructured) string {
+	name := obj.GetLabels()[GroupLabel]
+	if component := obj.GetLabels()[ComponentLabel]; component != "" {
+		name += "/" + component
+	}
+	if syncWave, ok := obj.GetAnnotations()[SyncWaveAnnotation]; ok {
+		return fmt.Sprintf("# %s (sync-wave %s)\n", name, syncWave)
+	}
+	return fmt.Sprintf("# %s\n", name)
+}
+
+// WriteKustomization writes every object to its own file in dir and a kustomization.yaml
+// listing them in deployment order
+func WriteKustomization(dir string, objects []*unstructured.Unstructured) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	var kustomization strings.Builder
+	kustomization.WriteString("apiVersion: kustomize.config.k8s.io/v1beta1\nkind: Kustomization\nresources:\n")
+	for i, obj := range objects {
+		name := FileName(i, obj)
+		file, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		writeErr := WriteManifests(file, []*unstructured.Unstructured{obj})
+		if err := file.Close(); err != nil && writeErr == nil {
+			writeErr = err
+		}
+		if writeErr != nil {
+			return writeErr
+		}
+		kustomization.WriteString("- " + name + "\n")
+	}
+	return os.WriteFile(filepath.Join(dir, KustomizationFile), []byte(kustomization.String()), 0o644)
+}
+
+// FileName returns the file an object is written to by WriteKustomization. The position
+// prefix keeps files in deployment order when listed alphabetically.
+func FileName(index int, obj *unstructured.Unstructured) string {
+	parts := []string{
+		fmt.Sprintf("%03d", index+1),
+		obj.GetLabels()[GroupLabel],
+		obj.GetLabels()[ComponentLabel],
+		strings.ToLower(obj.GetKind()),
+	}
+	return sanitizeFileName(strings.Join(parts, "-")) + ".yaml"
+}
+
+// sanitizeFileName replaces characters that are unsafe in file names
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '-'
+		}
+	}, name)
+}
@@ -0,0 +1,599 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package render
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/ext"
+	"github.com/google/cel-go/interpreter"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"sigs.k8s.io/yaml"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+)
+
+// Parameters resolves the parameters of a bundle: each parameter's default, overridden by
+// the bundle's values and then by its parameter sources, converted to the parameter's type
+func Parameters(ctx context.Context, appBundle *appv1alpha1.AppBundle, fetcher Fetcher) (map[string]interface{}, error) {
+	var sourced map[string]string
+	if len(appBundle.Spec.ParameterSources) > 0 {
+		if fetcher == nil {
+			return nil, fmt.Errorf("reading parameter sources requires a fetcher")
+		}
+		var err error
+		if sourced, err = fetcher.Parameters(ctx, appBundle.Namespace, appBundle.Spec.ParameterSources); err != nil {
+			return nil, err
+		}
+	}
+
+	declared := DeclaredParameters(appBundle)
+	for name := range appBundle.Spec.Values {
+		if !slices.ContainsFunc(declared, func(parameter appv1alpha1.Parameter) bool { return parameter.Name == name }) {
+			return nil, fmt.Errorf("value set for undeclared parameter %s", name)
+		}
+	}
+
+	params := map[string]interface{}{}
+	for _, parameter := range declared {
+		var value interface{}
+		var err error
+		if raw, ok := sourced[parameter.Name]; ok {
+			value, err = parseParameter(parameter.Type, raw)
+		} else if set, ok := appBundle.Spec.Values[parameter.Name]; ok {
+			if err = json.Unmarshal(set.Raw, &value); err == nil {
+				value, err = checkParameter(parameter.Type, value)
+			}
+		} else if parameter.Default != nil {
+			if err = json.Unmarshal(parameter.Default.Raw, &value); err == nil {
+				value, err = checkParameter(parameter.Type, value)
+			}
+		} else {
+			err = fmt.Errorf("no value set")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parameter %s: %w", parameter.Name, err)
+		}
+		params[parameter.Name] = value
+	}
+	return params, nil
+}
+
+// ValidateParameters resolves the parameters of a bundle and the expressions in all of its
+// templates, so that undefined references are reported before anything is applied
+func ValidateParameters(ctx context.Context, appBundle *appv1alpha1.AppBundle, fetcher Fetcher) error {
+	params, err := Parameters(ctx, appBundle, fetcher)
+	if err != nil {
+		return err
+	}
+	// Outputs of components that are not deployed yet are stood in for
+	vars := variables(WithPendingOutputs(appBundle), params)
+	for _, group := range Groups(appBundle) {
+		for _, component := range group.Components {
+			if !substitutes(appBundle, component) {
+				continue
+			}
+			if _, err := Substitute(component.Template.Raw, vars); err != nil {
+				return fmt.Errorf("template of component %s/%s: %w", group.Name, component.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// parseParameter converts a value read from a parameter source to the parameter's type
+func parseParameter(parameterType appv1alpha1.ParameterType, raw string) (interface{}, error) {
+	switch parameterType {
+	case appv1alpha1.ParameterTypeInteger:
+		return strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+	case appv1alpha1.ParameterTypeNumber:
+		return strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	case appv1alpha1.ParameterTypeBoolean:
+		return strconv.ParseBool(strings.TrimSpace(raw))
+	case appv1alpha1.ParameterTypeObject, appv1alpha1.ParameterTypeArray:
+		var value interface{}
+		if err := yaml.Unmarshal([]byte(raw), &value); err != nil {
+			return nil, err
+		}
+		return checkParameter(parameterType, value)
+	default:
+		return raw, nil
+	}
+}
+
+// checkParameter checks that a decoded JSON value has the parameter's type, turning whole
+// numbers of integer parameters into int64
+func checkParameter(parameterType appv1alpha1.ParameterType, value interface{}) (interface{}, error) {
+	ok := false
+	switch parameterType {
+	case appv1alpha1.ParameterTypeInteger:
+		if number, isNumber := value.(float64); isNumber && number == math.Trunc(number) {
+			return int64(number), nil
+		}
+	case appv1alpha1.ParameterTypeNumber:
+		_, ok = value.(float64)
+	case appv1alpha1.ParameterTypeBoolean:
+		_, ok = value.(bool)
+	case appv1alpha1.ParameterTypeObject:
+		_, ok = value.(map[string]interface{})
+	case appv1alpha1.ParameterTypeArray:
+		_, ok = value.([]interface{})
+	default:
+		_, ok = value.(string)
+	}
+	if !ok {
+		parameterTypeName := string(parameterType)
+		if parameterTypeName == "" {
+			parameterTypeName = string(appv1alpha1.ParameterTypeString)
+		}
+		return nil, fmt.Errorf("value %v is not of type %s", value, parameterTypeName)
+	}
+	return value, nil
+}
+
+// variables returns what expressions in templates can reference
+func variables(appBundle *appv1alpha1.AppBundle, params map[string]interface{}) map[string]interface{} {
+	labels := map[string]interface{}{}
+	for key, value := range appBundle.Labels {
+		labels[key] = value
+	}
+	annotations := map[string]interface{}{}
+	for key, value := range appBundle.Annotations {
+		annotations[key] = value
+	}
+	vars := map[string]interface{}{
+		"params":     params,
+		"components": map[string]interface{}{},
+		"groups":     map[string]interface{}{},
+		"bundle": map[string]interface{}{
+			"name":        appBundle.Name,
+			"namespace":   appBundle.Namespace,
+			"labels":      labels,
+			"annotations": annotations,
+		},
+	}
+	ambiguous := ambiguousComponents(appBundle)
+	for _, resolved := range appBundle.Status.Outputs {
+		outputs := componentOutputs(vars, ambiguous, resolved.Group, resolved.Component)
+		for name, raw := range resolved.Values {
+			var value interface{}
+			if err := json.Unmarshal(raw.Raw, &value); err == nil {
+				outputs[name] = value
+			}
+		}
+	}
+	return vars
+}
+
+// ambiguousComponents returns the names of the components declaring outputs in more than
+// one group, whose outputs can only be referenced through their group
+func ambiguousComponents(appBundle *appv1alpha1.AppBundle) map[string]bool {
+	groupOf := map[string]string{}
+	ambiguous := map[string]bool{}
+	for _, group := range Groups(appBundle) {
+		for _, component := range group.Components {
+			if len(component.Outputs) == 0 {
+				continue
+			}
+			if other, ok := groupOf[component.Name]; ok && other != group.Name {
+				ambiguous[component.Name] = true
+			}
+			groupOf[component.Name] = group.Name
+		}
+	}
+	return ambiguous
+}
+
+// componentOutputs returns the outputs of a component in vars, adding the component if
+// needed. Components are reachable as groups.GROUP.components.NAME and, unless their name
+// is ambiguous, as components.NAME.
+func componentOutputs(vars map[string]interface{}, ambiguous map[string]bool, group, component string) map[string]interface{} {
+	groups := vars["groups"].(map[string]interface{})
+	if _, ok := groups[group]; !ok {
+		groups[group] = map[string]interface{}{"components": map[string]interface{}{}}
+	}
+	components := groups[group].(map[string]interface{})["components"].(map[string]interface{})
+	if _, ok := components[component]; !ok {
+		components[component] = map[string]interface{}{"outputs": map[string]interface{}{}}
+	}
+	if !ambiguous[component] {
+		vars["components"].(map[string]interface{})[component] = components[component]
+	}
+	return components[component].(map[string]interface{})["outputs"].(map[string]interface{})
+}
+
+// PendingOutput stands in for an output that is not resolved yet when templates are
+// validated; CEL expressions referencing it evaluate to it as well
+const PendingOutput = "<pending>"
+
+// WithPendingOutputs returns a copy of a bundle in which every declared output that is not
+// resolved yet holds PendingOutput, so its templates render before the components producing
+// those outputs are deployed
+func WithPendingOutputs(appBundle *appv1alpha1.AppBundle) *appv1alpha1.AppBundle {
+	pending := appBundle.DeepCopy()
+	resolved := map[string]int{}
+	for i, outputs := range pending.Status.Outputs {
+		resolved[outputs.Group+"/"+outputs.Component] = i
+	}
+	value := apiextensionsv1.JSON{Raw: []byte(strconv.Quote(PendingOutput))}
+	for _, group := range Groups(appBundle) {
+		for _, component := range group.Components {
+			if len(component.Outputs) == 0 {
+				continue
+			}
+			i, ok := resolved[group.Name+"/"+component.Name]
+			if !ok {
+				pending.Status.Outputs = append(pending.Status.Outputs, appv1alpha1.ComponentOutputs{
+					Group:     group.Name,
+					Component: component.Name,
+				})
+				i = len(pending.Status.Outputs) - 1
+			}
+			outputs := &pending.Status.Outputs[i]
+			if outputs.Values == nil {
+				outputs.Values = map[string]apiextensionsv1.JSON{}
+			}
+			for _, output := range component.Outputs {
+				if _, ok := outputs.Values[output.Name]; !ok {
+					outputs.Values[output.Name] = value
+				}
+			}
+		}
+	}
+	return pending
+}
+
+// PendingFields returns the paths of the fields of a rendered object whose values depend on
+// outputs that are not resolved yet, sorted by path
+func PendingFields(obj map[string]interface{}) []string {
+	var paths []string
+	var walk func(path string, value interface{})
+	walk = func(path string, value interface{}) {
+		switch value := value.(type) {
+		case map[string]interface{}:
+			for key, field := range value {
+				if path != "" {
+					key = path + "." + key
+				}
+				walk(key, field)
+			}
+		case []interface{}:
+			for i, item := range value {
+				walk(fmt.Sprintf("%s[%d]", path, i), item)
+			}
+		case string:
+			if strings.Contains(value, PendingOutput) {
+				paths = append(paths, path)
+			}
+		}
+	}
+	walk("", obj)
+	sort.Strings(paths)
+	return paths
+}
+
+// pendingPatterns returns the CEL attribute patterns of the pending outputs in vars
+func pendingPatterns(vars map[string]interface{}) []*interpreter.AttributePattern {
+	var patterns []*interpreter.AttributePattern
+	pending := func(components map[string]interface{}, prefix func() *interpreter.AttributePattern) {
+		for component, entry := range components {
+			outputs, _ := entry.(map[string]interface{})["outputs"].(map[string]interface{})
+			for name, value := range outputs {
+				if value == PendingOutput {
+					patterns = append(patterns, prefix().QualString(component).QualString("outputs").QualString(name))
+				}
+			}
+		}
+	}
+	components, _ := vars["components"].(map[string]interface{})
+	pending(components, func() *interpreter.AttributePattern { return cel.AttributePattern("components") })
+	groups, _ := vars["groups"].(map[string]interface{})
+	for group, entry := range groups {
+		components, _ := entry.(map[string]interface{})["components"].(map[string]interface{})
+		pending(components, func() *interpreter.AttributePattern {
+			return cel.AttributePattern("groups").QualString(group).QualString("components")
+		})
+	}
+	return patterns
+}
+
+// hasExpressions reports whether a template may contain expressions
+func hasExpressions(raw []byte) bool {
+	return bytes.Contains(raw, []byte("{{")) || bytes.Contains(raw, []byte("${"))
+}
+
+// substitutes reports whether the expressions in the template of a component are resolved:
+// as the component's substitute field says, or else when the bundle declares parameters or
+// outputs. Templates of other bundles are applied as they are, so the {{ }} and ${ } they
+// contain for other tools, like Alertmanager templates or shell scripts, are left alone.
+func substitutes(appBundle *appv1alpha1.AppBundle, component appv1alpha1.Component) bool {
+	if !hasExpressions(component.Template.Raw) {
+		return false
+	}
+	if component.Substitute != nil {
+		return *component.Substitute
+	}
+	if len(DeclaredParameters(appBundle)) > 0 {
+		return true
+	}
+	for _, group := range Groups(appBundle) {
+		for _, declared := range group.Components {
+			if len(declared.Outputs) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Substitute resolves the Go template actions ({{ }}) and CEL expressions (${ }) in the
+// string values of a JSON template. A string that consists of a single action or
+// expression is replaced by a value of the result's type: the YAML value of a Go template's
+// output, like an unquoted value in a Helm chart, or the value of the CEL expression.
+// Referencing an undefined variable or key is an error; \{{ escapes {{ and $${ escapes ${.
+func Substitute(raw []byte, vars map[string]interface{}) ([]byte, error) {
+	// Numbers are kept as they are written
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var content interface{}
+	if err := decoder.Decode(&content); err != nil {
+		return nil, err
+	}
+	substituted, err := substituteValue(content, vars)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(substituted)
+}
+
+// substituteValue substitutes the strings of a decoded JSON value recursively
+func substituteValue(value interface{}, vars map[string]interface{}) (interface{}, error) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, item := range typed {
+			substituted, err := substituteValue(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			typed[key] = substituted
+		}
+	case []interface{}:
+		for i, item := range typed {
+			substituted, err := substituteValue(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			typed[i] = substituted
+		}
+	case string:
+		return substituteString(typed, vars)
+	}
+	return value, nil
+}
+
+// substituteString resolves the Go template actions, then the CEL expressions in s
+func substituteString(s string, vars map[string]interface{}) (interface{}, error) {
+	if strings.Contains(s, "{{") {
+		output, err := executeTemplate(strings.ReplaceAll(s, `\{{`, `{{"{{"}}`), vars)
+		if err != nil {
+			return nil, err
+		}
+		trimmed := strings.TrimSpace(s)
+		if strings.HasPrefix(trimmed, "{{") && strings.HasSuffix(trimmed, "}}") && strings.Count(trimmed, "{{") == 1 {
+			var value interface{}
+			if err := yaml.Unmarshal([]byte(output), &value); err == nil && value != nil {
+				return value, nil
+			}
+		}
+		s = output
+	}
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	return evaluateExpressions(s, vars)
+}
+
+const (
+	// maxTemplateOutput bounds the output of a Go template
+	maxTemplateOutput = 1 << 20
+	// celCostLimit bounds the cost of evaluating a CEL expression, like the API server
+	// bounds the cost of a validation rule
+	celCostLimit = 1000000
+	// celInterruptCheckFrequency is how many comprehension iterations a CEL evaluation runs
+	// between checks of its deadline
+	celInterruptCheckFrequency = 100
+	// celTimeout bounds the time of evaluating a CEL expression
+	celTimeout = time.Second
+)
+
+// excludedTemplateFuncs are the Sprig functions templates cannot use: those reading the
+// controller's environment or network, and those whose cost a template can make unbounded
+var excludedTemplateFuncs = []string{
+	"env", "expandenv", "getHostByName",
+	"repeat", "until", "untilStep", "derivePassword",
+	"genPrivateKey", "genCA", "genCAWithKey", "genSelfSignedCert", "genSelfSignedCertWithKey",
+	"genSignedCert", "genSignedCertWithKey",
+	"randAlpha", "randAlphaNum", "randAscii", "randNumeric", "randBytes",
+}
+
+// executeTemplate executes a Go template with the Sprig functions Helm charts use, except
+// excludedTemplateFuncs
+func executeTemplate(text string, vars map[string]interface{}) (string, error) {
+	funcs := sprig.TxtFuncMap()
+	for _, name := range excludedTemplateFuncs {
+		delete(funcs, name)
+	}
+	tmpl, err := template.New("template").Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	out := &limitedBuilder{limit: maxTemplateOutput}
+	if err := tmpl.Execute(out, vars); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// limitedBuilder is a strings.Builder that fails writes beyond limit bytes
+type limitedBuilder struct {
+	strings.Builder
+	limit int
+}
+
+// Write appends p, failing if the output would exceed the limit
+func (b *limitedBuilder) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, fmt.Errorf("template output exceeds the limit of %d bytes", b.limit)
+	}
+	return b.Builder.Write(p)
+}
+
+// evaluateExpressions replaces each ${expression} in s by its CEL value. If s is a single
+// expression, its value is returned as it is.
+func evaluateExpressions(s string, vars map[string]interface{}) (interface{}, error) {
+	var out strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			out.WriteString(s)
+			break
+		}
+		if start > 0 && s[start-1] == '$' {
+			out.WriteString(s[:start-1] + "${")
+			s = s[start+2:]
+			continue
+		}
+		end := expressionEnd(s, start+2)
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated expression in %q", s)
+		}
+		value, err := evaluateCEL(s[start+2:end], vars)
+		if err != nil {
+			return nil, err
+		}
+		if start == 0 && end == len(s)-1 && out.Len() == 0 {
+			return value, nil
+		}
+		out.WriteString(s[:start])
+		if text, ok := value.(string); ok {
+			out.WriteString(text)
+		} else {
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+			out.Write(encoded)
+		}
+		s = s[end+1:]
+	}
+	return out.String(), nil
+}
+
+// expressionEnd returns the index of the brace closing the expression starting at start,
+// skipping nested braces and quoted strings, or -1
+func expressionEnd(s string, start int) int {
+	depth := 0
+	var quote byte
+	for i := start; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '{':
+			depth++
+		case c == '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// evaluateCEL evaluates a CEL expression and converts its value to plain JSON types
+func evaluateCEL(expression string, vars map[string]interface{}) (interface{}, error) {
+	options := []cel.EnvOption{ext.Strings(), ext.Encoders()}
+	for name := range vars {
+		options = append(options, cel.Variable(name, cel.DynType))
+	}
+	env, err := cel.NewEnv(options...)
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(expression)
+	if issues.Err() != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", expression, issues.Err())
+	}
+	var activation interface{} = vars
+	var programOptions []cel.ProgramOption
+	if patterns := pendingPatterns(vars); len(patterns) > 0 {
+		if activation, err = cel.PartialVars(vars, patterns...); err != nil {
+			return nil, err
+		}
+		programOptions = append(programOptions, cel.EvalOptions(cel.OptPartialEval))
+	}
+	programOptions = append(programOptions, cel.CostLimit(celCostLimit), cel.InterruptCheckFrequency(celInterruptCheckFrequency))
+	program, err := env.Program(ast, programOptions...)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), celTimeout)
+	defer cancel()
+	value, _, err := program.ContextEval(ctx, activation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate %q: %w", expression, err)
+	}
+	if types.IsUnknown(value) {
+		return PendingOutput, nil
+	}
+	native, err := value.ConvertToNative(reflect.TypeOf(&structpb.Value{}))
+	if err != nil {
+		return nil, fmt.Errorf("expression %q has no JSON value: %w", expression, err)
+	}
+	data, err := protojson.Marshal(native.(*structpb.Value))
+	if err != nil {
+		return nil, err
+	}
+	var result interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
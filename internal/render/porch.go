@@ -0,0 +1,367 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package render
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+)
+
+// PackageVariantName returns the PackageVariant name for a Porch component
+// (custom format: appbundle-<package>)
+func PackageVariantName(component appv1alpha1.Component) string {
+	return fmt.Sprintf("appbundle-%s", component.PorchPackageRef.PackageName)
+}
+
+// PackageVariantNamespace returns the namespace for a component's PackageVariant (default to "default")
+func PackageVariantNamespace(component appv1alpha1.Component) string {
+	if component.PorchPackageRef.Namespace != "" {
+		return component.PorchPackageRef.Namespace
+	}
+	return "default"
+}
+
+// DownstreamRepository returns the Porch downstream repo (default to "mgmt" or use from spec)
+func DownstreamRepository(appBundle *appv1alpha1.AppBundle) string {
+	if appBundle.Spec.PorchIntegration != nil && appBundle.Spec.PorchIntegration.Repository != "" {
+		return appBundle.Spec.PorchIntegration.Repository
+	}
+	return "mgmt"
+}
+
+// PackageVariant renders the PackageVariant for a Porch component, including the
+// pipeline mutators that inject sync waves, tracking labels and the wait Job
+func PackageVariant(appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group, component appv1alpha1.Component, baseSyncWave int) (*unstructured.Unstructured, error) {
+	packageVariantName := PackageVariantName(component)
+	pvNamespace := PackageVariantNamespace(component)
+	downstreamRepo := DownstreamRepository(appBundle)
+
+	// Determine revision (default to "main")
+	revision := "main"
+	if component.PorchPackageRef.Revision != "" {
+		revision = component.PorchPackageRef.Revision
+	}
+
+	// Create PackageVariant CRD
+	packageVariant := &unstructured.Unstructured{}
+	packageVariant.SetAPIVersion("config.porch.kpt.dev/v1alpha1")
+	packageVariant.SetKind("PackageVariant")
+	packageVariant.SetName(packageVariantName)
+	packageVariant.SetNamespace(pvNamespace)
+
+	// Calculate sync wave
+	syncWave := baseSyncWave + component.Order
+	syncWaveStr := strconv.Itoa(syncWave)
+
+	// Calculate wait job sync wave (between current and next group)
+	// Place it at baseSyncWave + 50 (middle of the group's range)
+	waitSyncWave := baseSyncWave + 50
+	waitSyncWaveStr := strconv.Itoa(waitSyncWave)
+
+	// Build mutators list
+	mutators := []interface{}{
+		// Mutator 1: Set sync wave annotations on all resources
+		map[string]interface{}{
+			"image": "gcr.io/kpt-fn/set-annotations:v0.1.4",
+			"configMap": map[string]interface{}{
+				SyncWaveAnnotation: syncWaveStr,
+			},
+		},
+		// Mutator 2: Add AppBundle tracking labels to all resources
+		map[string]interface{}{
+			"image": "gcr.io/kpt-fn/set-labels:v0.2.0",
+			"configMap": map[string]interface{}{
+				AppBundleLabel:          appBundle.Name,
+				AppBundleNamespaceLabel: appBundle.Namespace,
+				GroupLabel:              group.Name,
+				ComponentLabel:          component.Name,
+			},
+		},
+		// Mutator 3: Inject wait Job using Starlark
+		// This Job waits for all workload resources to be ready before proceeding
+		// Only inject RBAC resources in the first Porch component (baseSyncWave == 0)
+		waitJobMutator(appBundle, group, component, packageVariantName, waitSyncWaveStr, baseSyncWave == 0),
+	}
+
+	// Set PackageVariant spec with pipeline mutators
+	spec := map[string]interface{}{
+		"upstream": map[string]interface{}{
+			"repo":     component.PorchPackageRef.Repository,
+			"package":  component.PorchPackageRef.PackageName,
+			"revision": revision,
+		},
+		"downstream": map[string]interface{}{
+			"repo":    downstreamRepo,
+			"package": packageVariantName,
+		},
+		"annotations": map[string]interface{}{
+			"approval.nephio.org/policy": "initial",
+		},
+		"adoptionPolicy": "adoptExisting",
+		"deletionPolicy": packageVariantDeletionPolicy(appBundle, component),
+		// Pipeline mutators to inject annotations and wait Job
+		"pipeline": map[string]interface{}{
+			"mutators": mutators,
+		},
+	}
+
+	if err := unstructured.SetNestedMap(packageVariant.Object, spec, "spec"); err != nil {
+		return nil, err
+	}
+
+	// Add annotations to metadata
+	annotations := map[string]string{
+		SyncWaveAnnotation: strconv.Itoa(syncWave),
+	}
+	packageVariant.SetAnnotations(annotations)
+
+	// Add labels
+	labels := map[string]string{
+		AppBundleLabel:          appBundle.Name,
+		AppBundleNamespaceLabel: appBundle.Namespace,
+		GroupLabel:              group.Name,
+		ComponentLabel:          component.Name,
+	}
+	packageVariant.SetLabels(labels)
+
+	return packageVariant, nil
+}
+
+// packageVariantDeletionPolicy maps a component's deletion policy to the PackageVariant's,
+// which either deletes the downstream package or leaves it in place
+func packageVariantDeletionPolicy(appBundle *appv1alpha1.AppBundle, component appv1alpha1.Component) string {
+	if DeletionPolicy(appBundle, component) == appv1alpha1.DeletionPolicyDelete {
+		return "delete"
+	}
+	return "orphan"
+}
+
+// waitJobMutator creates a Starlark mutator that injects a wait Job
+// The wait Job uses Argo CD hooks to pause deployment until resources are ready
+// includeRBAC determines whether to inject ServiceAccount, ClusterRole, and ClusterRoleBinding (only needed once)
+func waitJobMutator(appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group, component appv1alpha1.Component, packageName, syncWave string, includeRBAC bool) map[string]interface{} {
+	// Determine namespace for the wait job
+	namespace := appBundle.Namespace
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	// Derive the job name suffix from the package source: rendering the same spec always yields
+	// the same Job, while a new package revision gets a new Job instead of conflicting with the old one
+	ref := component.PorchPackageRef
+	sum := sha256.Sum256([]byte(strings.Join([]string{appBundle.Name, group.Name, component.Name,
+		ref.Repository, ref.PackageName, ref.Revision}, "/")))
+	jobSuffix := hex.EncodeToString(sum[:])[:5]
+
+	// Calculate sync waves for RBAC resources (must be before the Job)
+	// RBAC resources need to exist before the Job that uses them
+	// syncWave parameter is the Job's sync wave, RBAC should be 1 less
+	jobSyncWave, _ := strconv.Atoi(syncWave)
+	rbacSyncWave := jobSyncWave - 1 // One wave before the Job
+
+	// Build RBAC resources code (conditionally included)
+	rbacComment := "false"
+	rbacCode := "# RBAC resources not included (already created in first package)"
+	if includeRBAC {
+		rbacComment = "true"
+		rbacCode = fmt.Sprintf(`# Create ServiceAccount
+    sa_yaml = {
+        "apiVersion": "v1",
+        "kind": "ServiceAccount",
+        "metadata": {
+            "name": "appbundle-wait-reader",
+            "namespace": target_namespace,
+            "annotations": {
+                "argocd.argoproj.io/sync-wave": "%s"
+            },
+            "labels": {
+                "app.example.com/appbundle": "%s",
+                "app.example.com/wait-job": "true"
+            }
+        }
+    }
+    resource_list["items"].append(sa_yaml)
+    
+    # Create ClusterRole for reading resource status
+    clusterrole_yaml = {
+        "apiVersion": "rbac.authorization.k8s.io/v1",
+        "kind": "ClusterRole",
+        "metadata": {
+            "name": "appbundle-wait-reader-" + target_namespace,
+            "annotations": {
+                "argocd.argoproj.io/sync-wave": "%s"
+            },
+            "labels": {
+                "app.example.com/appbundle": "%s",
+                "app.example.com/wait-job": "true"
+            }
+        },
+        "rules": [
+            {
+                "apiGroups": ["apps"],
+                "resources": ["deployments", "deployments/status", "statefulsets", "statefulsets/status", "daemonsets", "daemonsets/status"],
+                "verbs": ["get", "list", "watch"]
+            },
+            {
+                "apiGroups": ["batch"],
+                "resources": ["jobs", "jobs/status"],
+                "verbs": ["get", "list", "watch"]
+            },
+            {
+                "apiGroups": [""],
+                "resources": ["pods", "pods/status", "services"],
+                "verbs": ["get", "list", "watch"]
+            }
+        ]
+    }
+    resource_list["items"].append(clusterrole_yaml)
+    
+    # Create ClusterRoleBinding
+    clusterrolebinding_yaml = {
+        "apiVersion": "rbac.authorization.k8s.io/v1",
+        "kind": "ClusterRoleBinding",
+        "metadata": {
+            "name": "appbundle-wait-reader-" + target_namespace,
+            "annotations": {
+                "argocd.argoproj.io/sync-wave": "%s"
+            },
+            "labels": {
+                "app.example.com/appbundle": "%s",
+                "app.example.com/wait-job": "true"
+            }
+        },
+        "roleRef": {
+            "apiGroup": "rbac.authorization.k8s.io",
+            "kind": "ClusterRole",
+            "name": "appbundle-wait-reader-" + target_namespace
+        },
+        "subjects": [
+            {
+                "kind": "ServiceAccount",
+                "name": "appbundle-wait-reader",
+                "namespace": target_namespace
+            }
+        ]
+    }
+    resource_list["items"].append(clusterrolebinding_yaml)
+    `, strconv.Itoa(rbacSyncWave), appBundle.Name,
+			strconv.Itoa(rbacSyncWave), appBundle.Name,
+			strconv.Itoa(rbacSyncWave), appBundle.Name)
+	}
+
+	// Build the Starlark script that injects the wait Job
+	// Note: KPT Starlark doesn't need imports - resource_list is passed directly
+	starlarkScript := fmt.Sprintf(`def transform(resource_list):
+    # Determine the target namespace from resources in the package
+    target_namespace = None
+    specific_wait_commands = []
+    
+    # Scan all resources in the package to determine namespace and specific resources
+    for resource in resource_list["items"]:
+        kind = resource.get("kind", "")
+        metadata = resource.get("metadata", {})
+        name = metadata.get("name", "")
+        ns = metadata.get("namespace", "")
+        
+        # Store the target namespace from the first namespaced resource
+        if ns and not target_namespace:
+            target_namespace = ns
+        
+        # Collect specific wait commands for known workload resources
+        # Note: Starlark doesn't support f-strings, use string concatenation
+        if kind == "Deployment" and name and ns:
+            specific_wait_commands.append("kubectl rollout status deployment/" + name + " -n " + ns + " --timeout=15m")
+        elif kind == "StatefulSet" and name and ns:
+            specific_wait_commands.append("kubectl rollout status statefulset/" + name + " -n " + ns + " --timeout=15m")
+        elif kind == "DaemonSet" and name and ns:
+            specific_wait_commands.append("kubectl rollout status daemonset/" + name + " -n " + ns + " --timeout=15m")
+    
+    # If no specific namespace found, use default
+    if not target_namespace:
+        target_namespace = "%s"
+    
+    # Build wait script - always create a wait job
+    # If we found specific resources, wait for them. Otherwise, use a simple delay
+    if specific_wait_commands:
+        wait_script = " && ".join(specific_wait_commands)
+    else:
+        # Generic wait: just add a delay to ensure resources have time to deploy
+        # This is a fallback when we can't detect specific resources in the package
+        wait_script = "echo 'Waiting for resources to be created in namespace " + target_namespace + "...' && sleep 10 && echo 'Proceeding to next group'"
+    
+    # Always create wait job to ensure sequential deployment
+    # RBAC resources (only added in first package): %s
+%s
+    
+    # Create the wait Job
+    job_yaml = {
+        "apiVersion": "batch/v1",
+        "kind": "Job",
+        "metadata": {
+            "name": "wait-%s-%s-%s",
+            "namespace": target_namespace,
+            "annotations": {
+                "argocd.argoproj.io/hook": "Sync",
+                "argocd.argoproj.io/sync-wave": "%s"
+            },
+            "labels": {
+                "app.example.com/appbundle": "%s",
+                "app.example.com/group": "%s",
+                "app.example.com/component": "%s",
+                "app.example.com/wait-job": "true"
+            }
+        },
+        "spec": {
+            "ttlSecondsAfterFinished": 300,
+            "backoffLimit": 3,
+            "template": {
+                "spec": {
+                    "restartPolicy": "Never",
+                    "serviceAccountName": "appbundle-wait-reader",
+                    "containers": [{
+                        "name": "wait",
+                        "image": "bitnami/kubectl:latest",
+                        "command": ["sh", "-c"],
+                        "args": [wait_script]
+                    }]
+                }
+            }
+        }
+    }
+    resource_list["items"].append(job_yaml)
+    
+    return resource_list
+
+# Call the transform function
+transform(ctx.resource_list)
+`, namespace, rbacComment, rbacCode,
+		group.Name, component.Name, jobSuffix, syncWave, appBundle.Name, group.Name, component.Name) // Job with package suffix (Job wave)
+
+	return map[string]interface{}{
+		"image": "gcr.io/kpt-fn/starlark:v0.4.3",
+		"configMap": map[string]interface{}{
+			"source": starlarkScript,
+		},
+	}
+}
@@ -0,0 +1,312 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package render turns an AppBundle into the manifests the controller applies. It is shared
+// by the controller and the kubectl-appbundle plugin so that both render identical output.
+package render
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+)
+
+const (
+	// SyncWaveAnnotation is the Argo CD sync wave annotation
+	SyncWaveAnnotation = "argocd.argoproj.io/sync-wave"
+	// AppBundleLabel records the AppBundle a rendered object belongs to
+	AppBundleLabel = "app.example.com/appbundle"
+	// AppBundleNamespaceLabel records the namespace of the AppBundle a rendered object belongs
+	// to, which is empty for a ClusterAppBundle, since the object may live in another namespace
+	AppBundleNamespaceLabel = "app.example.com/appbundle-namespace"
+	// GroupLabel records the group a rendered object belongs to
+	GroupLabel = "app.example.com/group"
+	// ComponentLabel records the component a rendered object belongs to
+	ComponentLabel = "app.example.com/component"
+)
+
+// RenderedGroup holds the rendered objects of a group, in deployment order
+type RenderedGroup struct {
+	Name    string
+	Order   int
+	Objects []*unstructured.Unstructured
+}
+
+// Bundle renders the objects the controller applies for an AppBundle, group by group in
+// deployment order. The objects depend on the bundle's delivery backend: Flux bundles render
+// to one Kustomization per group, all other bundles to their component templates.
+func Bundle(ctx context.Context, appBundle *appv1alpha1.AppBundle, fetcher Fetcher) ([]RenderedGroup, error) {
+	switch Backend(appBundle) {
+	case appv1alpha1.BackendFlux:
+		return FluxBundle(appBundle)
+	case appv1alpha1.BackendDirect:
+		for _, group := range Groups(appBundle) {
+			for _, component := range group.Components {
+				if component.PorchPackageRef != nil {
+					return nil, fmt.Errorf("component %s references a Porch package, which the Direct backend cannot deliver", component.Name)
+				}
+			}
+		}
+	}
+	return Templates(ctx, appBundle, fetcher)
+}
+
+// Templates renders every component of an AppBundle, group by group in deployment order.
+// Porch components render to their PackageVariant. Every component is rendered before the
+// workloads are annotated with the hash of their configuration, which is taken from the
+// rendered objects.
+func Templates(ctx context.Context, appBundle *appv1alpha1.AppBundle, fetcher Fetcher) ([]RenderedGroup, error) {
+	configs := NewConfigHashes()
+	rendered := map[string][]*unstructured.Unstructured{}
+	for _, group := range SortedGroups(appBundle) {
+		for _, component := range group.Components {
+			if component.PorchPackageRef != nil {
+				continue
+			}
+			objects, err := componentObjects(ctx, appBundle, group, component, BaseSyncWave(group), fetcher)
+			if err != nil {
+				return nil, err
+			}
+			configs.add(group, component, objects)
+			rendered[group.Name+"/"+component.Name] = objects
+		}
+	}
+
+	var groups []RenderedGroup
+	for _, group := range SortedGroups(appBundle) {
+		baseSyncWave := BaseSyncWave(group)
+		renderedGroup := RenderedGroup{Name: group.Name, Order: group.Order}
+		for _, component := range SortedComponents(group) {
+			if component.PorchPackageRef != nil {
+				obj, err := PackageVariant(appBundle, group, component, baseSyncWave)
+				if err != nil {
+					return nil, err
+				}
+				renderedGroup.Objects = append(renderedGroup.Objects, obj)
+				continue
+			}
+			objects := rendered[group.Name+"/"+component.Name]
+			if err := injectConfigHashes(appBundle, component, objects, configs); err != nil {
+				return nil, err
+			}
+			renderedGroup.Objects = append(renderedGroup.Objects, objects...)
+		}
+		groups = append(groups, renderedGroup)
+	}
+	return groups, nil
+}
+
+// Manifests renders every component of an AppBundle into a flat list in deployment order
+func Manifests(ctx context.Context, appBundle *appv1alpha1.AppBundle, fetcher Fetcher) ([]*unstructured.Unstructured, error) {
+	groups, err := Bundle(ctx, appBundle, fetcher)
+	if err != nil {
+		return nil, err
+	}
+	var objects []*unstructured.Unstructured
+	for _, group := range groups {
+		objects = append(objects, group.Objects...)
+	}
+	return objects, nil
+}
+
+// BaseSyncWave returns the first sync wave of a group; each group owns a range of 100 waves
+func BaseSyncWave(group appv1alpha1.Group) int {
+	return group.Order * 100
+}
+
+// DeletionPolicy returns the deletion policy of a component, falling back to the bundle's
+// and then to Delete
+func DeletionPolicy(appBundle *appv1alpha1.AppBundle, component appv1alpha1.Component) appv1alpha1.DeletionPolicy {
+	if component.DeletionPolicy != "" {
+		return component.DeletionPolicy
+	}
+	if appBundle.Spec.DeletionPolicy != "" {
+		return appBundle.Spec.DeletionPolicy
+	}
+	return appv1alpha1.DeletionPolicyDelete
+}
+
+// AdoptionPolicy returns the adoption policy of a component, falling back to the bundle's
+// and then to IfUnowned
+func AdoptionPolicy(appBundle *appv1alpha1.AppBundle, component appv1alpha1.Component) appv1alpha1.AdoptionPolicy {
+	if component.AdoptionPolicy != "" {
+		return component.AdoptionPolicy
+	}
+	if appBundle.Spec.AdoptionPolicy != "" {
+		return appBundle.Spec.AdoptionPolicy
+	}
+	return appv1alpha1.AdoptionPolicyIfUnowned
+}
+
+// SortedGroups returns the groups of an AppBundle sorted by order
+func SortedGroups(appBundle *appv1alpha1.AppBundle) []appv1alpha1.Group {
+	sortedGroups := make([]appv1alpha1.Group, len(Groups(appBundle)))
+	copy(sortedGroups, Groups(appBundle))
+	sort.SliceStable(sortedGroups, func(i, j int) bool {
+		return sortedGroups[i].Order < sortedGroups[j].Order
+	})
+	return sortedGroups
+}
+
+// SortedComponents returns the components of a group sorted by order
+func SortedComponents(group appv1alpha1.Group) []appv1alpha1.Component {
+	sortedComponents := make([]appv1alpha1.Component, len(group.Components))
+	copy(sortedComponents, group.Components)
+	sort.SliceStable(sortedComponents, func(i, j int) bool {
+		return sortedComponents[i].Order < sortedComponents[j].Order
+	})
+	return sortedComponents
+}
+
+// Fetcher loads the external inputs of components. The controller reads values from the
+// cluster; the kubectl plugin may render without one.
+type Fetcher interface {
+	// HelmChart loads the chart referenced by a component
+	HelmChart(ctx context.Context, source *appv1alpha1.HelmChartSource) (*chart.Chart, error)
+	// Values merges the values kept in the referenced ConfigMaps and Secrets of a namespace
+	Values(ctx context.Context, namespace string, refs []appv1alpha1.ValuesReference) (map[string]interface{}, error)
+	// Kustomization loads the files of a kustomization source into a filesystem rooted at "/"
+	Kustomization(ctx context.Context, namespace string, source *appv1alpha1.KustomizeSource) (filesys.FileSystem, error)
+	// Source loads the files of an OCI artifact or Git commit into a filesystem rooted at "/"
+	Source(ctx context.Context, namespace string, source *appv1alpha1.ComponentSource) (filesys.FileSystem, error)
+	// Parameters reads the parameter values kept in ConfigMaps and Secrets of a namespace
+	Parameters(ctx context.Context, namespace string, sources []appv1alpha1.ParameterSource) (map[string]string, error)
+}
+
+// Component renders every object of a non-Porch component: its template, the output of its
+// Helm chart in install order, the output of its kustomization, or the manifests of its
+// source. Workloads are annotated with the hash of the configuration they use, read from
+// configs, which the component's own ConfigMaps and Secrets are added to. Callers rendering
+// several components share one BundleConfigHashes; a nil configs is computed when the
+// component has workloads.
+func Component(ctx context.Context, appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group, component appv1alpha1.Component, baseSyncWave int, fetcher Fetcher, configs *ConfigHashes) ([]*unstructured.Unstructured, error) {
+	objects, err := componentObjects(ctx, appBundle, group, component, baseSyncWave, fetcher)
+	if err != nil {
+		return nil, err
+	}
+	if configs == nil && slices.ContainsFunc(objects, func(obj *unstructured.Unstructured) bool { return workloadKinds[obj.GetKind()] }) {
+		configs = BundleConfigHashes(ctx, appBundle, fetcher)
+	}
+	if configs != nil {
+		configs.add(group, component, objects)
+	}
+	if err := injectConfigHashes(appBundle, component, objects, configs); err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+// componentObjects renders the objects of a non-Porch component
+func componentObjects(ctx context.Context, appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group, component appv1alpha1.Component, baseSyncWave int, fetcher Fetcher) ([]*unstructured.Unstructured, error) {
+	switch {
+	case component.HelmChart != nil:
+		return HelmChart(ctx, appBundle, group, component, baseSyncWave, fetcher)
+	case component.Kustomize != nil:
+		return Kustomization(ctx, appBundle, group, component, baseSyncWave, fetcher)
+	case component.Source != nil:
+		return Source(ctx, appBundle, group, component, baseSyncWave, fetcher)
+	}
+	if substitutes(appBundle, component) {
+		params, err := Parameters(ctx, appBundle, fetcher)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := Substitute(component.Template.Raw, variables(appBundle, params))
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve template of component %s: %w", component.Name, err)
+		}
+		component.Template.Raw = raw
+	}
+	obj, err := Template(appBundle, group, component, baseSyncWave)
+	if err != nil {
+		return nil, err
+	}
+	return []*unstructured.Unstructured{obj}, nil
+}
+
+// Template renders the template of a component into the object that will be applied,
+// injecting the sync wave annotation, tracking labels and namespace
+func Template(appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group, component appv1alpha1.Component, baseSyncWave int) (*unstructured.Unstructured, error) {
+	// Validate that template is provided for non-Porch components
+	if len(component.Template.Raw) == 0 {
+		return nil, fmt.Errorf("component %s has neither template nor porchPackageRef", component.Name)
+	}
+
+	// Parse the template into an unstructured object
+	obj := &unstructured.Unstructured{}
+	if err := json.Unmarshal(component.Template.Raw, obj); err != nil {
+		return nil, fmt.Errorf("failed to parse template of component %s: %w", component.Name, err)
+	}
+
+	decorate(obj, appBundle, group, component, baseSyncWave+component.Order)
+	return obj, nil
+}
+
+// decorate injects the sync wave annotation and tracking labels into a rendered object and
+// defaults its namespace to the AppBundle namespace
+func decorate(obj *unstructured.Unstructured, appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group, component appv1alpha1.Component, syncWave int) {
+	// Add Argo CD sync wave annotation
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[SyncWaveAnnotation] = strconv.Itoa(syncWave)
+	obj.SetAnnotations(annotations)
+	if component.Hook != "" {
+		decorateHook(obj, group, component)
+	}
+
+	// Add labels for tracking
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	labels[AppBundleLabel] = appBundle.Name
+	labels[AppBundleNamespaceLabel] = appBundle.Namespace
+	labels[GroupLabel] = group.Name
+	labels[ComponentLabel] = component.Name
+	obj.SetLabels(labels)
+
+	// Set namespace if not specified
+	if obj.GetNamespace() == "" && appBundle.Namespace != "" && !clusterScopedKinds[obj.GetKind()] {
+		obj.SetNamespace(appBundle.Namespace)
+	}
+}
+
+// clusterScopedKinds lists common cluster-scoped kinds, which must not get a namespace
+var clusterScopedKinds = map[string]bool{
+	"Namespace":                      true,
+	"CustomResourceDefinition":       true,
+	"ClusterRole":                    true,
+	"ClusterRoleBinding":             true,
+	"PersistentVolume":               true,
+	"StorageClass":                   true,
+	"PriorityClass":                  true,
+	"IngressClass":                   true,
+	"RuntimeClass":                   true,
+	"APIService":                     true,
+	"MutatingWebhookConfiguration":   true,
+	"ValidatingWebhookConfiguration": true,
+}
@@ -0,0 +1,1116 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package render
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/yaml"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+	"github.com/example/appbundle-operator/internal/source"
+)
+
+// Run `go test ./internal/render -update` to regenerate the golden files
+var update = flag.Bool("update", false, "update golden files")
+
+func loadAppBundle(t *testing.T, name string) *appv1alpha1.AppBundle {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name+".yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	appBundle := &appv1alpha1.AppBundle{}
+	if err := yaml.Unmarshal(data, appBundle); err != nil {
+		t.Fatal(err)
+	}
+	return appBundle
+}
+
+func compareGolden(t *testing.T, path string, actual []byte) {
+	t.Helper()
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, actual, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("missing golden file, run with -update: %v", err)
+	}
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("%s does not match the rendered output, run with -update to accept:\n%s", path, actual)
+	}
+}
+
+// testFetcher returns a Fetcher whose cluster holds the ConfigMap web-kustomization in the
+// namespace shop, made from the files in testdata/kustomize/configmap, plus objects. The
+// charts in testdata/charts are served as the repository https://charts.example.com
+func testFetcher(t *testing.T, objects ...client.Object) *source.Fetcher {
+	t.Helper()
+	dir := filepath.Join("testdata", "kustomize", "configmap")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-kustomization", Namespace: "shop"},
+		Data:       map[string]string{},
+	}
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		configMap.Data[entry.Name()] = string(data)
+	}
+	reader := fake.NewClientBuilder().WithObjects(append(objects, configMap)...).Build()
+	return source.NewFetcher(reader, source.WithLocalRepositories(map[string]string{
+		"https://charts.example.com": filepath.Join("testdata", "charts"),
+	}))
+}
+
+func TestManifestsGolden(t *testing.T) {
+	for _, name := range []string{"basic", "porch", "flux", "helm", "kustomize", "parameters"} {
+		t.Run(name, func(t *testing.T) {
+			objects, err := Manifests(context.Background(), loadAppBundle(t, name), testFetcher(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err := WriteManifests(&out, objects); err != nil {
+				t.Fatal(err)
+			}
+			compareGolden(t, filepath.Join("testdata", name+".golden.yaml"), out.Bytes())
+
+			// Rendering must be deterministic so the controller and the CLI agree
+			again, err := Manifests(context.Background(), loadAppBundle(t, name), testFetcher(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var outAgain bytes.Buffer
+			if err := WriteManifests(&outAgain, again); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out.Bytes(), outAgain.Bytes()) {
+				t.Error("rendering the same AppBundle twice produced different output")
+			}
+		})
+	}
+}
+
+func TestKustomizationGolden(t *testing.T) {
+	objects, err := Manifests(context.Background(), loadAppBundle(t, "basic"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := WriteKustomization(dir, objects); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(objects)+1 {
+		t.Fatalf("expected %d files, got %d", len(objects)+1, len(entries))
+	}
+	for _, entry := range entries {
+		actual, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		compareGolden(t, filepath.Join("testdata", "basic.kustomization", entry.Name()), actual)
+	}
+}
+
+func TestComponentSyncWaveAndLabels(t *testing.T) {
+	appBundle := loadAppBundle(t, "basic")
+	groups, err := Bundle(context.Background(), appBundle, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 || groups[0].Name != "infrastructure" || groups[1].Name != "application" {
+		t.Fatalf("groups are not in deployment order: %+v", groups)
+	}
+
+	service := groups[1].Objects[0]
+	if service.GetKind() != "Service" {
+		t.Fatalf("components are not in deployment order, got %s first", service.GetKind())
+	}
+	if wave := service.GetAnnotations()[SyncWaveAnnotation]; wave != "100" {
+		t.Errorf("expected sync wave 100, got %q", wave)
+	}
+	if owner := service.GetAnnotations()["example.com/owner"]; owner != "shop-team" {
+		t.Errorf("template annotations were not preserved, got %q", owner)
+	}
+	if service.GetNamespace() != "shop" {
+		t.Errorf("expected the AppBundle namespace, got %q", service.GetNamespace())
+	}
+	if config := groups[0].Objects[0]; config.GetNamespace() != "shop-config" {
+		t.Errorf("explicit namespace was overridden, got %q", config.GetNamespace())
+	}
+	for key, value := range map[string]string{AppBundleLabel: "web", GroupLabel: "application", ComponentLabel: "service"} {
+		if service.GetLabels()[key] != value {
+			t.Errorf("expected label %s=%s, got %q", key, value, service.GetLabels()[key])
+		}
+	}
+}
+
+func TestBackend(t *testing.T) {
+	porchComponent := appv1alpha1.Component{
+		Name:            "redis",
+		PorchPackageRef: &appv1alpha1.PorchPackageReference{PackageName: "redis"},
+	}
+	for name, tc := range map[string]struct {
+		spec    appv1alpha1.AppBundleSpec
+		backend appv1alpha1.DeliveryBackend
+	}{
+		"templates only": {
+			spec:    appv1alpha1.AppBundleSpec{Groups: []appv1alpha1.Group{{Name: "app"}}},
+			backend: appv1alpha1.BackendDirect,
+		},
+		"porch integration enabled": {
+			spec: appv1alpha1.AppBundleSpec{
+				PorchIntegration: &appv1alpha1.PorchIntegrationSpec{Enabled: true},
+			},
+			backend: appv1alpha1.BackendPorch,
+		},
+		"porch component": {
+			spec:    appv1alpha1.AppBundleSpec{Groups: []appv1alpha1.Group{{Components: []appv1alpha1.Component{porchComponent}}}},
+			backend: appv1alpha1.BackendPorch,
+		},
+		"explicit": {
+			spec: appv1alpha1.AppBundleSpec{
+				Backend:          appv1alpha1.BackendFlux,
+				PorchIntegration: &appv1alpha1.PorchIntegrationSpec{Enabled: true},
+			},
+			backend: appv1alpha1.BackendFlux,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if backend := Backend(&appv1alpha1.AppBundle{Spec: tc.spec}); backend != tc.backend {
+				t.Errorf("expected backend %s, got %s", tc.backend, backend)
+			}
+		})
+	}
+
+	t.Run("direct rejects porch components", func(t *testing.T) {
+		appBundle := &appv1alpha1.AppBundle{Spec: appv1alpha1.AppBundleSpec{
+			Backend: appv1alpha1.BackendDirect,
+			Groups:  []appv1alpha1.Group{{Name: "cache", Components: []appv1alpha1.Component{porchComponent}}},
+		}}
+		if _, err := Bundle(context.Background(), appBundle, nil); err == nil {
+			t.Error("expected an error for a Porch component with the Direct backend")
+		}
+	})
+}
+
+func TestApplicationName(t *testing.T) {
+	first := &appv1alpha1.AppBundle{ObjectMeta: metav1.ObjectMeta{Namespace: "a-b", Name: "c"}}
+	second := &appv1alpha1.AppBundle{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "b-c"}}
+	if name := ApplicationName(first); !strings.HasPrefix(name, "a-b-c-") {
+		t.Errorf("unexpected Application name %q", name)
+	}
+	if ApplicationName(first) == ApplicationName(second) {
+		t.Errorf("expected a-b/c and a/b-c to get different Application names, both got %q", ApplicationName(first))
+	}
+}
+
+func TestFluxDependsOnPreviousGroup(t *testing.T) {
+	groups, err := Bundle(context.Background(), loadAppBundle(t, "flux"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 || len(groups[0].Objects) != 1 || len(groups[1].Objects) != 1 {
+		t.Fatalf("expected one Kustomization per group, got %+v", groups)
+	}
+	first, second := groups[0].Objects[0], groups[1].Objects[0]
+	if _, found := first.Object["spec"].(map[string]interface{})["dependsOn"]; found {
+		t.Error("the first group must not depend on another Kustomization")
+	}
+	dependsOn := second.Object["spec"].(map[string]interface{})["dependsOn"].([]interface{})
+	if name := dependsOn[0].(map[string]interface{})["name"]; name != first.GetName() {
+		t.Errorf("expected %s to depend on %s, got %v", second.GetName(), first.GetName(), name)
+	}
+}
+
+func TestKustomizationName(t *testing.T) {
+	appBundle := loadAppBundle(t, "flux")
+	group := appBundle.Spec.Groups[0]
+	cluster := appBundle.DeepCopy()
+	cluster.Namespace = ""
+	if name := KustomizationName(cluster, group); !strings.HasPrefix(name, "cluster-"+appBundle.Name+"-"+group.Name+"-") {
+		t.Errorf("unexpected Kustomization name %q", name)
+	}
+	namespaced := cluster.DeepCopy()
+	namespaced.Namespace = "flux-system"
+	if KustomizationName(cluster, group) == KustomizationName(namespaced, group) {
+		t.Errorf("expected a ClusterAppBundle and an AppBundle of the same name to get different Kustomization names, both got %q",
+			KustomizationName(cluster, group))
+	}
+	long := cluster.DeepCopy()
+	long.Name = strings.Repeat("a", 80)
+	if name := KustomizationName(long, group); len(name) > maxKustomizationNameLength {
+		t.Errorf("expected a Kustomization name of at most %d characters, got %q", maxKustomizationNameLength, name)
+	}
+}
+
+func TestDeletionPolicy(t *testing.T) {
+	appBundle := &appv1alpha1.AppBundle{Spec: appv1alpha1.AppBundleSpec{}}
+	data := appv1alpha1.Component{
+		Name:            "data",
+		DeletionPolicy:  appv1alpha1.DeletionPolicyRetain,
+		PorchPackageRef: &appv1alpha1.PorchPackageReference{PackageName: "postgres"},
+	}
+	cache := appv1alpha1.Component{
+		Name:            "cache",
+		PorchPackageRef: &appv1alpha1.PorchPackageReference{PackageName: "redis"},
+	}
+	if policy := DeletionPolicy(appBundle, cache); policy != appv1alpha1.DeletionPolicyDelete {
+		t.Errorf("expected Delete without any policy, got %s", policy)
+	}
+	appBundle.Spec.DeletionPolicy = appv1alpha1.DeletionPolicyOrphan
+	if policy := DeletionPolicy(appBundle, cache); policy != appv1alpha1.DeletionPolicyOrphan {
+		t.Errorf("expected the bundle's Orphan policy, got %s", policy)
+	}
+	if policy := DeletionPolicy(appBundle, data); policy != appv1alpha1.DeletionPolicyRetain {
+		t.Errorf("expected the component's Retain policy, got %s", policy)
+	}
+
+	appBundle.Spec.DeletionPolicy = appv1alpha1.DeletionPolicyDelete
+	for component, expected := range map[*appv1alpha1.Component]string{&data: "orphan", &cache: "delete"} {
+		packageVariant, err := PackageVariant(appBundle, appv1alpha1.Group{Name: "backend"}, *component, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if policy, _, _ := unstructured.NestedString(packageVariant.Object, "spec", "deletionPolicy"); policy != expected {
+			t.Errorf("expected PackageVariant of %s to have deletionPolicy %s, got %s", component.Name, expected, policy)
+		}
+	}
+
+	flux := loadAppBundle(t, "flux")
+	flux.Spec.DeletionPolicy = appv1alpha1.DeletionPolicyRetain
+	groups, err := Bundle(context.Background(), flux, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy, _, _ := unstructured.NestedString(groups[0].Objects[0].Object, "spec", "deletionPolicy"); policy != "Orphan" {
+		t.Errorf("expected the Kustomization to orphan its objects, got %q", policy)
+	}
+
+	flux.Spec.Groups[0].Components[0].DeletionPolicy = appv1alpha1.DeletionPolicyRetain
+	if _, err := Bundle(context.Background(), flux, nil); err == nil || !strings.Contains(err.Error(), "deletionPolicy") {
+		t.Errorf("expected a component deletionPolicy to be rejected by the Flux backend, got %v", err)
+	}
+}
+
+func TestChangedFields(t *testing.T) {
+	desired := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{"cpu": "1000m"},
+		},
+		"stringData": map[string]interface{}{"password": "hunter2"},
+	}
+	live := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{"cpu": "1"},
+		},
+		"data": map[string]interface{}{"password": "aHVudGVyMg=="},
+	}
+	changes := ChangedFields(desired, live)
+	if len(changes) != 1 || changes[0].Path != "metadata.annotations.cpu" {
+		t.Errorf("expected only the annotation to differ, got %+v", changes)
+	}
+
+	desired = map[string]interface{}{
+		"spec": map[string]interface{}{
+			"containers": []interface{}{map[string]interface{}{
+				"env":       []interface{}{map[string]interface{}{"name": "CPU", "value": "1000m"}},
+				"resources": map[string]interface{}{"limits": map[string]interface{}{"cpu": "1000m", "memory": "1Gi"}},
+			}},
+			"volumes": []interface{}{map[string]interface{}{
+				"emptyDir": map[string]interface{}{"sizeLimit": "1024Mi"},
+			}},
+		},
+	}
+	live = map[string]interface{}{
+		"spec": map[string]interface{}{
+			"containers": []interface{}{map[string]interface{}{
+				"env":       []interface{}{map[string]interface{}{"name": "CPU", "value": "1"}},
+				"resources": map[string]interface{}{"limits": map[string]interface{}{"cpu": "1", "memory": "1073741824"}},
+			}},
+			"volumes": []interface{}{map[string]interface{}{
+				"emptyDir": map[string]interface{}{"sizeLimit": "1Gi"},
+			}},
+		},
+	}
+	changes = ChangedFields(desired, live)
+	if len(changes) != 1 || changes[0].Path != "spec.containers[0].env[0].value" {
+		t.Errorf("expected only the env value to differ, got %+v", changes)
+	}
+
+	live["spec"].(map[string]interface{})["nodeSelector"] = map[string]interface{}{"disk": "ssd"}
+	removed := RemovedFields(desired, live)
+	if len(removed) != 1 || removed[0].Path != "spec.nodeSelector" || removed[0].Desired != nil {
+		t.Errorf("expected only the node selector to be removed, got %+v", removed)
+	}
+}
+
+func TestDiffObjectRedactsSecrets(t *testing.T) {
+	desired := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata":   map[string]interface{}{"name": "db", "labels": map[string]interface{}{"tier": "data"}},
+		"stringData": map[string]interface{}{"password": "hunter2"},
+	}}
+	live := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata":   map[string]interface{}{"name": "db"},
+		"data":       map[string]interface{}{"password": "b2xkLXBhc3N3b3Jk"},
+	}}
+	diff, err := DiffObject(&appv1alpha1.AppBundle{}, desired, live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(diff, "~ data.password") || !strings.Contains(diff, "~ metadata.labels") {
+		t.Errorf("expected the password and the labels to differ, got:\n%s", diff)
+	}
+	if strings.Contains(diff, "aHVudGVyMg==") || strings.Contains(diff, "b2xkLXBhc3N3b3Jk") {
+		t.Errorf("expected the Secret values to be redacted, got:\n%s", diff)
+	}
+
+	redacted := RedactSecret(desired.Object)
+	if value, _, _ := unstructured.NestedString(redacted, "stringData", "password"); value != "<redacted>" {
+		t.Errorf("expected stringData to be redacted, got %q", value)
+	}
+	if value, _, _ := unstructured.NestedString(desired.Object, "stringData", "password"); value != "hunter2" {
+		t.Errorf("expected the rendered Secret to be left alone, got %q", value)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	job := func(name string) runtime.RawExtension {
+		return runtime.RawExtension{Raw: []byte(`{"apiVersion":"batch/v1","kind":"Job","metadata":{"name":"` + name + `"}}`)}
+	}
+	group := appv1alpha1.Group{Name: "database", Order: 2, Components: []appv1alpha1.Component{
+		{Name: "smoke-test", Order: 1, Hook: appv1alpha1.HookPostGroup, Template: job("smoke-test"),
+			HookDeletePolicies: []appv1alpha1.HookDeletePolicy{appv1alpha1.HookDeleteSucceeded}},
+		{Name: "postgres", Template: job("postgres")},
+		{Name: "migrate", Order: 2, Hook: appv1alpha1.HookPreGroup, Template: job("migrate")},
+		{Name: "backup", Hook: appv1alpha1.HookPreDelete, Template: job("backup")},
+	}}
+	appBundle := &appv1alpha1.AppBundle{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "shop"},
+		Spec:       appv1alpha1.AppBundleSpec{Groups: []appv1alpha1.Group{group}},
+	}
+
+	pre, components, post := GroupComponents(group)
+	if len(pre) != 1 || pre[0].Name != "migrate" || len(components) != 1 || components[0].Name != "postgres" ||
+		len(post) != 1 || post[0].Name != "smoke-test" {
+		t.Errorf("unexpected split: pre %v, components %v, post %v", pre, components, post)
+	}
+	if hooks := BundleHooks(appBundle, appv1alpha1.HookPreDelete); len(hooks) != 1 || hooks[0].Component.Name != "backup" {
+		t.Errorf("expected the backup PreDelete hook, got %v", hooks)
+	}
+	if !HasHookDeletePolicy(pre[0], appv1alpha1.HookDeleteBeforeCreation) {
+		t.Error("hooks without delete policies must be deleted before they run again")
+	}
+	if HasHookDeletePolicy(post[0], appv1alpha1.HookDeleteBeforeCreation) || !HasHookDeletePolicy(post[0], appv1alpha1.HookDeleteSucceeded) {
+		t.Error("expected only the HookSucceeded policy of the smoke test")
+	}
+
+	for component, expected := range map[*appv1alpha1.Component]map[string]string{
+		&pre[0]: {HookAnnotation: "PreGroup", argoHookAnnotation: "Sync", SyncWaveAnnotation: "199"},
+		&post[0]: {HookAnnotation: "PostGroup", argoHookAnnotation: "Sync", SyncWaveAnnotation: "299",
+			argoHookDeletePolicyAnnotation: "HookSucceeded"},
+	} {
+		objects, err := Component(context.Background(), appBundle, group, *component, BaseSyncWave(group), nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !IsHook(objects[0]) {
+			t.Errorf("expected %s to render a hook", component.Name)
+		}
+		for key, value := range expected {
+			if actual := objects[0].GetAnnotations()[key]; actual != value {
+				t.Errorf("expected %s annotation %s=%s, got %q", component.Name, key, value, actual)
+			}
+		}
+	}
+
+	flux := loadAppBundle(t, "flux")
+	flux.Spec.Groups[0].Components[0].Hook = appv1alpha1.HookPostDeploy
+	if _, err := Bundle(context.Background(), flux, nil); err == nil {
+		t.Error("expected the Flux backend to reject hooks")
+	}
+}
+
+func TestConfigHashes(t *testing.T) {
+	raw := func(manifest string) runtime.RawExtension { return runtime.RawExtension{Raw: []byte(manifest)} }
+	deployment := func(name, podSpec string) runtime.RawExtension {
+		return raw(`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"` + name + `"},` +
+			`"spec":{"template":{"spec":` + podSpec + `}}}`)
+	}
+	appBundle := &appv1alpha1.AppBundle{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "shop"},
+		Spec: appv1alpha1.AppBundleSpec{Groups: []appv1alpha1.Group{
+			{Name: "config", Components: []appv1alpha1.Component{
+				{Name: "settings", Template: raw(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"settings"},"data":{"mode":"a"}}`)},
+				{Name: "credentials", Template: raw(`{"apiVersion":"v1","kind":"Secret","metadata":{"name":"credentials"},"stringData":{"password":"a"}}`)},
+			}},
+			{Name: "app", Order: 1, Components: []appv1alpha1.Component{
+				{Name: "web", Template: deployment("web",
+					`{"containers":[{"name":"web","envFrom":[{"configMapRef":{"name":"settings"}}]}]}`)},
+				{Name: "worker", Template: deployment("worker",
+					`{"containers":[{"name":"worker","env":[{"name":"PASSWORD","valueFrom":{"secretKeyRef":{"name":"credentials","key":"password"}}}]}]}`)},
+				{Name: "static", Template: deployment("static", `{"containers":[{"name":"static"}]}`)},
+				{Name: "declared", Template: deployment("declared", `{"containers":[{"name":"declared"}]}`), ConfigFrom: []string{"settings"}},
+			}},
+		}},
+	}
+	hashes := func() map[string]string {
+		t.Helper()
+		groups, err := Templates(context.Background(), appBundle, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes := map[string]string{}
+		for _, obj := range groups[1].Objects {
+			hash, _, _ := unstructured.NestedString(obj.Object, "spec", "template", "metadata", "annotations", ConfigHashAnnotation)
+			hashes[obj.GetName()] = hash
+		}
+		return hashes
+	}
+
+	before := hashes()
+	if before["web"] == "" || before["worker"] == "" || before["declared"] == "" {
+		t.Fatalf("expected config hashes on every workload that uses configuration, got %v", before)
+	}
+	if before["static"] != "" {
+		t.Errorf("expected no config hash on a workload without configuration, got %s", before["static"])
+	}
+	if before["web"] != before["declared"] {
+		t.Error("expected the same hash for a referenced and a declared ConfigMap")
+	}
+
+	appBundle.Spec.Groups[0].Components[0].Template = raw(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"settings"},"data":{"mode":"b"}}`)
+	after := hashes()
+	if after["web"] == before["web"] || after["declared"] == before["declared"] {
+		t.Error("expected a ConfigMap change to change the hashes of the workloads using it")
+	}
+	if after["worker"] != before["worker"] {
+		t.Error("expected the hash of a workload using only the Secret to stay the same")
+	}
+
+	// A ConfigMap referencing an output that is not resolved yet does not keep the workloads
+	// rendered one by one from being annotated
+	appBundle.Spec.Groups[0].Components = append(appBundle.Spec.Groups[0].Components, appv1alpha1.Component{
+		Name:     "endpoints",
+		Template: raw(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"endpoints"},"data":{"host":"${components.database.outputs.host}"}}`),
+	})
+	app := appBundle.Spec.Groups[1]
+	objects, err := Component(context.Background(), appBundle, app, app.Components[0], BaseSyncWave(app), nil,
+		BundleConfigHashes(context.Background(), appBundle, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash, _, _ := unstructured.NestedString(objects[0].Object, "spec", "template", "metadata", "annotations", ConfigHashAnnotation); hash != after["web"] {
+		t.Errorf("expected the hash rendered in a pass over the bundle, got %q", hash)
+	}
+
+	appBundle.Spec.Groups[1].Components[3].ConfigFrom = []string{"missing"}
+	if _, err := Templates(context.Background(), appBundle, nil); err == nil {
+		t.Error("expected an error for an unknown configFrom component")
+	}
+}
+
+func TestHelmChartInstallOrder(t *testing.T) {
+	objects, err := Manifests(context.Background(), loadAppBundle(t, "helm"), testFetcher(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// CRDs come first, then pre-install hooks and the chart's resources in Helm's install
+	// order; test hooks are not rendered
+	var kinds []string
+	for _, obj := range objects {
+		kinds = append(kinds, obj.GetKind())
+		if wave := obj.GetAnnotations()[SyncWaveAnnotation]; wave != "200" {
+			t.Errorf("expected sync wave 200 for %s, got %q", obj.GetKind(), wave)
+		}
+		if obj.GetLabels()[ComponentLabel] != "web" {
+			t.Errorf("%s is missing the component label", obj.GetKind())
+		}
+	}
+	expected := []string{"CustomResourceDefinition", "Job", "Service", "Deployment"}
+	if strings.Join(kinds, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected %v, got %v", expected, kinds)
+	}
+	if objects[0].GetNamespace() != "" {
+		t.Errorf("cluster-scoped CRD got namespace %q", objects[0].GetNamespace())
+	}
+	if hook := objects[1].GetAnnotations()["argocd.argoproj.io/hook"]; hook != "PreSync" {
+		t.Errorf("expected the pre-install hook to map to PreSync, got %q", hook)
+	}
+	if !RecreateHelmHook(objects[1]) || DeleteHelmHook(objects[1], true) {
+		t.Error("expected a hook without delete policy to be recreated and kept once it ran")
+	}
+	annotations := objects[1].GetAnnotations()
+	annotations[HelmHookDeletePolicyAnnotation] = "before-hook-creation, hook-succeeded"
+	objects[1].SetAnnotations(annotations)
+	if !DeleteHelmHook(objects[1], true) || DeleteHelmHook(objects[1], false) {
+		t.Error("expected a hook-succeeded hook to be deleted only once it succeeded")
+	}
+	if DeleteHelmHook(objects[2], true) {
+		t.Error("expected a resource that is not a hook to be kept")
+	}
+	if replicas := objects[3].Object["spec"].(map[string]interface{})["replicas"]; replicas != int64(3) && replicas != float64(3) {
+		t.Errorf("inline values were not applied, replicas is %v", replicas)
+	}
+}
+
+func TestKustomizeFluxSource(t *testing.T) {
+	// Serve testdata/kustomize/repo as the artifact of a GitRepository, standing in for
+	// Flux's source-controller
+	var artifact bytes.Buffer
+	gz := gzip.NewWriter(&artifact)
+	tw := tar.NewWriter(gz)
+	root := filepath.Join("testdata", "kustomize", "repo")
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		name, _ := filepath.Rel(root, path)
+		if err := tw.WriteHeader(&tar.Header{Name: filepath.ToSlash(name), Mode: 0o644, Size: int64(len(data))}); err != nil {
+			return err
+		}
+		_, err = tw.Write(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(artifact.Bytes())
+	}))
+	defer server.Close()
+
+	sum := sha256.Sum256(artifact.Bytes())
+	gitRepository := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "source.toolkit.fluxcd.io/v1",
+		"kind":       "GitRepository",
+		"metadata":   map[string]interface{}{"name": "shop", "namespace": "flux-system"},
+		"status": map[string]interface{}{"artifact": map[string]interface{}{
+			"url":    server.URL + "/gitrepository/flux-system/shop/abc123.tar.gz",
+			"digest": "sha256:" + hex.EncodeToString(sum[:]),
+		}},
+	}}
+
+	appBundle := loadAppBundle(t, "kustomize")
+	group := appBundle.Spec.Groups[0]
+	component := group.Components[0]
+	component.Kustomize = &appv1alpha1.KustomizeSource{
+		Path:      "overlays/prod",
+		SourceRef: &appv1alpha1.FluxSourceReference{Name: "shop", Namespace: "flux-system"},
+	}
+	_, err = Component(context.Background(), appBundle, group, component, BaseSyncWave(group), testFetcher(t, gitRepository), nil)
+	if err == nil || !strings.Contains(err.Error(), "cross-namespace sources are disabled") {
+		t.Fatalf("expected a source in another namespace to be rejected, got %v", err)
+	}
+
+	gitRepository.SetNamespace(appBundle.Namespace)
+	component.Kustomize.SourceRef.Namespace = ""
+	objects, err := Component(context.Background(), appBundle, group, component, BaseSyncWave(group), testFetcher(t, gitRepository), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Namespaces come first in kustomize's output order
+	var kinds []string
+	for _, obj := range objects {
+		kinds = append(kinds, obj.GetKind())
+	}
+	expected := []string{"Namespace", "ConfigMap", "Service", "Deployment"}
+	if strings.Join(kinds, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected %v, got %v", expected, kinds)
+	}
+	for _, obj := range objects {
+		if obj.GetLabels()[ComponentLabel] != "web" || obj.GetAnnotations()[SyncWaveAnnotation] != "100" {
+			t.Errorf("%s %s is missing the component label or sync wave: %v %v", obj.GetKind(), obj.GetName(), obj.GetLabels(), obj.GetAnnotations())
+		}
+	}
+	if objects[0].GetNamespace() != "" {
+		t.Errorf("cluster-scoped Namespace got namespace %q", objects[0].GetNamespace())
+	}
+	if objects[3].GetNamespace() != "shop-prod" {
+		t.Errorf("expected the overlay's namespace, got %q", objects[3].GetNamespace())
+	}
+	replicas, _, _ := unstructured.NestedInt64(objects[3].Object, "spec", "replicas")
+	if replicas != 4 {
+		t.Errorf("expected the overlay's patch to set 4 replicas, got %d", replicas)
+	}
+}
+
+// sourceFetcher serves files as the source of every component
+type sourceFetcher struct {
+	*source.Fetcher
+	files map[string]string
+}
+
+func (f sourceFetcher) Source(ctx context.Context, namespace string, src *appv1alpha1.ComponentSource) (filesys.FileSystem, error) {
+	fs := filesys.MakeFsInMemory()
+	for name, content := range f.files {
+		if err := fs.MkdirAll(filepath.Dir(name)); err != nil {
+			return nil, err
+		}
+		if err := fs.WriteFile(name, []byte(content)); err != nil {
+			return nil, err
+		}
+	}
+	return fs, nil
+}
+
+func TestSourceManifests(t *testing.T) {
+	fetcher := sourceFetcher{Fetcher: source.NewFetcher(nil), files: map[string]string{
+		"/deploy/b-service.yaml":       "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n",
+		"/deploy/a-config.yaml":        "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n---\napiVersion: v1\nkind: Secret\nmetadata:\n  name: credentials\n",
+		"/deploy/workloads/web.json":   `{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "web"}}`,
+		"/deploy/README.md":            "not a manifest",
+		"/base/kustomization.yaml":     "resources:\n- service.yaml\n",
+		"/base/service.yaml":           "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n",
+		"/overlay/kustomization.yaml":  "namePrefix: prod-\nresources:\n- ../base\n",
+		"/overlay/unused/ignored.yaml": "kind: Ignored\n",
+	}}
+	appBundle := loadAppBundle(t, "basic")
+	group := appBundle.Spec.Groups[0]
+
+	for dir, expected := range map[string][]string{
+		// Files are read in the order of their paths, documents in the order of their files
+		"deploy": {"ConfigMap/settings", "Secret/credentials", "Service/web", "Deployment/web"},
+		// A directory holding a kustomization is built with kustomize
+		"overlay": {"Service/prod-web"},
+	} {
+		component := appv1alpha1.Component{Name: "web", Source: &appv1alpha1.ComponentSource{
+			Path: dir,
+			Git:  &appv1alpha1.GitSource{URL: "https://git.example.com/web.git"},
+		}}
+		objects, err := Component(context.Background(), appBundle, group, component, BaseSyncWave(group), fetcher, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actual []string
+		for _, obj := range objects {
+			actual = append(actual, obj.GetKind()+"/"+obj.GetName())
+			if obj.GetLabels()[ComponentLabel] != "web" || obj.GetNamespace() != appBundle.Namespace {
+				t.Errorf("%s %s was not decorated: %v", obj.GetKind(), obj.GetName(), obj.Object["metadata"])
+			}
+		}
+		if strings.Join(actual, ",") != strings.Join(expected, ",") {
+			t.Errorf("%s: expected %v, got %v", dir, expected, actual)
+		}
+	}
+}
+
+func TestParameterSources(t *testing.T) {
+	appBundle := loadAppBundle(t, "parameters")
+	appBundle.Spec.ParameterSources = []appv1alpha1.ParameterSource{
+		{Kind: "ConfigMap", Name: "shop-parameters"},
+		{Kind: "Secret", Name: "shop-overrides", Optional: true},
+	}
+	parameters := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop-parameters", Namespace: "shop-staging"},
+		Data:       map[string]string{"replicas": "5", "debug": "true", "env": "LOG_FORMAT: text\n", "unused": "x"},
+	}
+	params, err := Parameters(context.Background(), appBundle, testFetcher(t, parameters))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"replicas": int64(5),
+		"imageTag": "1.27",
+		"debug":    true,
+		"env":      map[string]interface{}{"LOG_FORMAT": "text"},
+	}
+	if !reflect.DeepEqual(params, expected) {
+		t.Errorf("expected %v, got %v", expected, params)
+	}
+
+	objects, err := Manifests(context.Background(), appBundle, testFetcher(t, parameters))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replicas, _, _ := unstructured.NestedInt64(objects[1].Object, "spec", "replicas")
+	if replicas != 5 {
+		t.Errorf("expected 5 replicas, got %d", replicas)
+	}
+
+	parameters.Data["replicas"] = "many"
+	if _, err := Parameters(context.Background(), appBundle, testFetcher(t, parameters)); err == nil {
+		t.Error("expected a non-integer value of an integer parameter to be rejected")
+	}
+}
+
+func TestUndefinedReferences(t *testing.T) {
+	for name, expression := range map[string]string{
+		"go template":  "{{ .params.missing }}",
+		"cel":          "${params.missing}",
+		"cel variable": "${cluster.name}",
+		"unterminated": "${params.replicas",
+	} {
+		t.Run(name, func(t *testing.T) {
+			appBundle := loadAppBundle(t, "parameters")
+			appBundle.Spec.Groups[0].Components[0].Template.Raw = []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"x"},"data":{"value":"` + expression + `"}}`)
+			if err := ValidateParameters(context.Background(), appBundle, nil); err == nil {
+				t.Errorf("expected %s to fail validation", expression)
+			}
+		})
+	}
+
+	appBundle := loadAppBundle(t, "parameters")
+	appBundle.Spec.Parameters = append(appBundle.Spec.Parameters, appv1alpha1.Parameter{Name: "region"})
+	if err := ValidateParameters(context.Background(), appBundle, nil); err == nil {
+		t.Error("expected a parameter without default or source to fail validation")
+	}
+}
+
+func TestComponentOutputs(t *testing.T) {
+	appBundle := loadAppBundle(t, "parameters")
+	group := &appBundle.Spec.Groups[0]
+	group.Components[0].Outputs = []appv1alpha1.ComponentOutput{{Name: "host", JSONPath: "{.data.namespace}"}}
+	group.Components[1].Template.Raw = []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"client"},"data":{` +
+		`"host":"{{ .components.config.outputs.host }}","url":"${'redis://' + components.config.outputs.host}"}}`)
+
+	// Outputs of components that are not deployed yet are stood in for
+	if err := ValidateParameters(context.Background(), appBundle, nil); err != nil {
+		t.Fatalf("pending outputs: %v", err)
+	}
+	undefined := appBundle.DeepCopy()
+	undefined.Spec.Groups[0].Components[1].Template.Raw = []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"client"},"data":{"port":"${components.config.outputs.port}"}}`)
+	if err := ValidateParameters(context.Background(), undefined, nil); err == nil {
+		t.Error("expected an undeclared output to fail validation")
+	}
+	pending, err := Component(context.Background(), WithPendingOutputs(appBundle), *group, group.Components[1], 0, nil, nil)
+	if err != nil {
+		t.Fatalf("Component with pending outputs: %v", err)
+	}
+	if fields := PendingFields(pending[0].Object); !reflect.DeepEqual(fields, []string{"data.host", "data.url"}) {
+		t.Errorf("unexpected pending fields %v", fields)
+	}
+
+	appBundle.Status.Outputs = []appv1alpha1.ComponentOutputs{{
+		Group:     "frontend",
+		Component: "config",
+		Values:    map[string]apiextensionsv1.JSON{"host": {Raw: []byte(`"10.0.0.7"`)}},
+	}}
+	objects, err := Component(context.Background(), appBundle, *group, group.Components[1], 0, nil, nil)
+	if err != nil {
+		t.Fatalf("Component: %v", err)
+	}
+	data, _, _ := unstructured.NestedStringMap(objects[0].Object, "data")
+	if data["host"] != "10.0.0.7" || data["url"] != "redis://10.0.0.7" {
+		t.Errorf("unexpected data %v", data)
+	}
+
+	// Components of the same name in several groups are told apart by their group
+	appBundle.Spec.Groups = append(appBundle.Spec.Groups, appv1alpha1.Group{
+		Name:       "backend",
+		Components: []appv1alpha1.Component{*group.Components[0].DeepCopy()},
+	})
+	group = &appBundle.Spec.Groups[0]
+	if err := ValidateParameters(context.Background(), appBundle, nil); err == nil {
+		t.Error("expected an ambiguous component name to fail validation")
+	}
+	group.Components[1].Template.Raw = []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"client"},"data":{` +
+		`"frontend":"{{ .groups.frontend.components.config.outputs.host }}","backend":"${groups.backend.components.config.outputs.host}"}}`)
+	if err := ValidateParameters(context.Background(), appBundle, nil); err != nil {
+		t.Fatalf("outputs referenced by group: %v", err)
+	}
+	appBundle.Status.Outputs = append(appBundle.Status.Outputs, appv1alpha1.ComponentOutputs{
+		Group:     "backend",
+		Component: "config",
+		Values:    map[string]apiextensionsv1.JSON{"host": {Raw: []byte(`"10.0.0.8"`)}},
+	})
+	objects, err = Component(context.Background(), appBundle, *group, group.Components[1], 0, nil, nil)
+	if err != nil {
+		t.Fatalf("Component: %v", err)
+	}
+	data, _, _ = unstructured.NestedStringMap(objects[0].Object, "data")
+	if data["frontend"] != "10.0.0.7" || data["backend"] != "10.0.0.8" {
+		t.Errorf("unexpected data %v", data)
+	}
+}
+
+func TestSubstitutionOptIn(t *testing.T) {
+	// An Alertmanager configuration carries Go templates of its own
+	alertmanager := []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"alertmanager"},"data":{` +
+		`"alertmanager.yml":"receivers:\n- name: team\n  slack_configs:\n  - title: '{{ .CommonLabels.alertname }}'\n",` +
+		`"rules.yml":"summary: Instance {{ $labels.instance }} is down",` +
+		`"entrypoint.sh":"exec alertmanager --web.listen-address=${LISTEN_ADDRESS}"}}`)
+	render := func(appBundle *appv1alpha1.AppBundle) map[string]string {
+		t.Helper()
+		group := appBundle.Spec.Groups[0]
+		objects, err := Component(context.Background(), appBundle, group, group.Components[0], 0, nil, nil)
+		if err != nil {
+			t.Fatalf("Component: %v", err)
+		}
+		data, _, _ := unstructured.NestedStringMap(objects[0].Object, "data")
+		return data
+	}
+	original := map[string]string{
+		"alertmanager.yml": "receivers:\n- name: team\n  slack_configs:\n  - title: '{{ .CommonLabels.alertname }}'\n",
+		"rules.yml":        "summary: Instance {{ $labels.instance }} is down",
+		"entrypoint.sh":    "exec alertmanager --web.listen-address=${LISTEN_ADDRESS}",
+	}
+
+	// Bundles without parameters or outputs apply their templates as they are
+	appBundle := loadAppBundle(t, "basic")
+	appBundle.Spec.Groups[0].Components[0].Template.Raw = alertmanager
+	if err := ValidateParameters(context.Background(), appBundle, nil); err != nil {
+		t.Fatalf("ValidateParameters: %v", err)
+	}
+	if data := render(appBundle); !reflect.DeepEqual(data, original) {
+		t.Errorf("expected the template to be applied as it is, got %v", data)
+	}
+
+	// With parameters, a component opts out of substitution
+	parameterized := appBundle.DeepCopy()
+	parameterized.Spec.Parameters = []appv1alpha1.Parameter{{Name: "region", Default: &apiextensionsv1.JSON{Raw: []byte(`"eu-west-1"`)}}}
+	if err := ValidateParameters(context.Background(), parameterized, nil); err == nil {
+		t.Error("expected the Alertmanager template to fail substitution once the bundle declares parameters")
+	}
+	parameterized.Spec.Groups[0].Components[0].Substitute = ptr.To(false)
+	if data := render(parameterized); !reflect.DeepEqual(data, original) {
+		t.Errorf("expected substitute: false to apply the template as it is, got %v", data)
+	}
+
+	// or escapes the actions it keeps
+	escaped := parameterized.DeepCopy()
+	escaped.Spec.Groups[0].Components[0].Substitute = nil
+	escaped.Spec.Groups[0].Components[0].Template.Raw = []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"alertmanager"},"data":{` +
+		`"rules.yml":"summary: Instance \\{{ $labels.instance }} in {{ .params.region }} is down",` +
+		`"entrypoint.sh":"exec alertmanager --web.listen-address=$${LISTEN_ADDRESS}"}}`)
+	data := render(escaped)
+	if data["rules.yml"] != "summary: Instance {{ $labels.instance }} in eu-west-1 is down" {
+		t.Errorf("unexpected rules %q", data["rules.yml"])
+	}
+	if data["entrypoint.sh"] != "exec alertmanager --web.listen-address=${LISTEN_ADDRESS}" {
+		t.Errorf("unexpected entrypoint %q", data["entrypoint.sh"])
+	}
+
+	// Bundles without parameters opt in per component
+	optIn := appBundle.DeepCopy()
+	optIn.Spec.Groups[0].Components[0].Substitute = ptr.To(true)
+	optIn.Spec.Groups[0].Components[0].Template.Raw = []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"bundle"},"data":{"name":"${bundle.name}"}}`)
+	if data := render(optIn); data["name"] != appBundle.Name {
+		t.Errorf("expected substitute: true to resolve the bundle name, got %v", data)
+	}
+}
+
+func TestEvaluateOutput(t *testing.T) {
+	live := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"metadata":   map[string]interface{}{"name": "redis"},
+		"spec": map[string]interface{}{
+			"clusterIP": "10.0.0.7",
+			"ports":     []interface{}{map[string]interface{}{"port": int64(6379)}, map[string]interface{}{"port": int64(6380)}},
+		},
+	}}
+	for _, test := range []struct {
+		output appv1alpha1.ComponentOutput
+		want   interface{}
+	}{
+		{appv1alpha1.ComponentOutput{Name: "host", JSONPath: "{.spec.clusterIP}"}, "10.0.0.7"},
+		{appv1alpha1.ComponentOutput{Name: "host", JSONPath: ".spec.clusterIP"}, "10.0.0.7"},
+		{appv1alpha1.ComponentOutput{Name: "ports", JSONPath: "{.spec.ports[*].port}"}, []interface{}{int64(6379), int64(6380)}},
+		{appv1alpha1.ComponentOutput{Name: "url", Expression: "'redis://' + object.spec.clusterIP"}, "redis://10.0.0.7"},
+		{appv1alpha1.ComponentOutput{Name: "port", Expression: "object.spec.ports[0].port"}, float64(6379)},
+	} {
+		got, err := EvaluateOutput(test.output, live)
+		if err != nil {
+			t.Errorf("%s: %v", test.output.Name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %#v, want %#v", test.output.Name, got, test.want)
+		}
+	}
+
+	if _, err := EvaluateOutput(appv1alpha1.ComponentOutput{Name: "ip", JSONPath: "{.status.loadBalancer.ingress[0].ip}"}, live); err == nil {
+		t.Error("expected a missing field to fail")
+	}
+}
+
+func TestTemplatedBundle(t *testing.T) {
+	source := loadAppBundle(t, "parameters")
+	template := &appv1alpha1.AppBundleTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop", Generation: 1},
+		Spec:       appv1alpha1.AppBundleTemplateSpec{Parameters: source.Spec.Parameters, Groups: source.Spec.Groups},
+	}
+	appBundle := &appv1alpha1.AppBundle{
+		ObjectMeta: source.ObjectMeta,
+		Spec: appv1alpha1.AppBundleSpec{
+			TemplateRef: &appv1alpha1.TemplateReference{Name: "shop"},
+			Values:      map[string]apiextensionsv1.JSON{"replicas": {Raw: []byte("3")}},
+		},
+	}
+
+	if _, err := ResolveTemplate(appBundle, nil, nil); err == nil {
+		t.Error("expected a missing template to be reported")
+	}
+	changed, err := ResolveTemplate(appBundle, template, nil)
+	if err != nil || !changed {
+		t.Fatalf("expected the template to be recorded, got %v, %v", changed, err)
+	}
+	objects, err := Manifests(context.Background(), appBundle, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replicas, _, _ := unstructured.NestedInt64(objects[1].Object, "spec", "replicas")
+	if len(objects) != 2 || replicas != 3 {
+		t.Errorf("expected the template's objects with 3 replicas, got %d objects with %d", len(objects), replicas)
+	}
+
+	// A pinned generation keeps rendering from its revision, even without a status
+	revisions := map[int64]*appv1alpha1.AppBundleTemplateRevision{1: {
+		Spec: appv1alpha1.AppBundleTemplateRevisionSpec{TemplateName: "shop", Generation: 1, Template: *template.Spec.DeepCopy()},
+	}}
+	revision := func(name string, generation int64) (*appv1alpha1.AppBundleTemplateRevision, error) {
+		return revisions[generation], nil
+	}
+	appBundle.Spec.TemplateRef.Generation = 1
+	appBundle.Status.Template = nil
+	template.Generation = 2
+	template.Spec.Groups = template.Spec.Groups[:0]
+	if _, err := ResolveTemplate(appBundle, template, nil); err == nil {
+		t.Error("expected a pinned generation without a revision to be reported")
+	}
+	if _, err := ResolveTemplate(appBundle, template, revision); err != nil {
+		t.Errorf("expected the pinned generation to be read from its revision: %v", err)
+	}
+	if len(Groups(appBundle)) != 1 {
+		t.Errorf("expected the groups of generation 1, got %v", Groups(appBundle))
+	}
+	if changed, err := ResolveTemplate(appBundle, template, revision); err != nil || changed {
+		t.Errorf("expected the pinned generation to be kept, got %v, %v", changed, err)
+	}
+	appBundle.Spec.TemplateRef.Generation = 3
+	if _, err := ResolveTemplate(appBundle, template, revision); err == nil {
+		t.Error("expected a generation that was never rendered to be reported")
+	}
+
+	appBundle.Spec.Values["region"] = apiextensionsv1.JSON{Raw: []byte(`"eu"`)}
+	if _, err := Parameters(context.Background(), appBundle, nil); err == nil {
+		t.Error("expected a value for an undeclared parameter to be rejected")
+	}
+}
+
+func TestValidateClusterBundle(t *testing.T) {
+	appBundle := loadAppBundle(t, "basic")
+	if err := ValidateClusterBundle(appBundle); err != nil {
+		t.Errorf("expected an AppBundle to be valid: %v", err)
+	}
+	appBundle.Namespace = ""
+	if err := ValidateClusterBundle(appBundle); err != nil {
+		t.Errorf("expected a directly delivered ClusterAppBundle to be valid: %v", err)
+	}
+	if name := ApplicationName(appBundle); !strings.HasPrefix(name, "cluster-"+appBundle.Name+"-") {
+		t.Errorf("unexpected Application name %q", name)
+	}
+
+	flux := appBundle.DeepCopy()
+	flux.Spec.Backend = appv1alpha1.BackendFlux
+	flux.Spec.Flux = &appv1alpha1.FluxBackendSpec{SourceRef: appv1alpha1.FluxSourceReference{Name: "platform"}}
+	if err := ValidateClusterBundle(flux); err == nil {
+		t.Error("expected the Flux backend without a namespace to be rejected")
+	}
+	flux.Spec.Flux.Namespace = "flux-system"
+	if err := ValidateClusterBundle(flux); err != nil {
+		t.Errorf("expected the Flux backend with a namespace to be valid: %v", err)
+	}
+
+	porch := appBundle.DeepCopy()
+	porch.Spec.Groups[0].Components[0].PorchPackageRef = &appv1alpha1.PorchPackageReference{PackageName: "network", Repository: "catalog"}
+	if err := ValidateClusterBundle(porch); err == nil {
+		t.Error("expected a component referencing a Porch package to be rejected")
+	}
+}
+
+func TestExpressionLimits(t *testing.T) {
+	if _, err := executeTemplate(`{{ repeat 3 "a" }}`, nil); err == nil {
+		t.Error("expected repeat to be unavailable")
+	}
+	if _, err := executeTemplate(`{{ getHostByName "example.com" }}`, nil); err == nil {
+		t.Error("expected getHostByName to be unavailable")
+	}
+	if _, err := executeTemplate(`{{ range 2000000 }}a{{ end }}`, nil); err == nil {
+		t.Error("expected an output beyond the limit to be rejected")
+	}
+	if out, err := executeTemplate(`{{ upper "a" }}`, nil); err != nil || out != "A" {
+		t.Errorf("expected Sprig functions to stay available, got %q, %v", out, err)
+	}
+
+	list := "[" + strings.Repeat("0,", 99) + "0]"
+	expression := list + ".all(a, " + list + ".all(b, " + list + ".all(c, " + list + ".all(d, true))))"
+	if _, err := evaluateCEL(expression, map[string]interface{}{}); err == nil {
+		t.Error("expected an expression beyond the cost limit to be rejected")
+	}
+}
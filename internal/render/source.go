@@ -0,0 +1,97 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package render
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+)
+
+// Source renders the manifests a component reads from an OCI artifact or Git repository. A
+// directory holding a kustomization is built with kustomize; otherwise its YAML and JSON
+// files, including those of subdirectories, are rendered in the order of their paths.
+func Source(ctx context.Context, appBundle *appv1alpha1.AppBundle, group appv1alpha1.Group, component appv1alpha1.Component, baseSyncWave int, fetcher Fetcher) ([]*unstructured.Unstructured, error) {
+	if fetcher == nil {
+		return nil, fmt.Errorf("component %s: reading sources requires a fetcher", component.Name)
+	}
+	fs, err := fetcher.Source(ctx, appBundle.Namespace, component.Source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch source of component %s: %w", component.Name, err)
+	}
+
+	dir := path.Join("/", component.Source.Path)
+	var objects []*unstructured.Unstructured
+	if hasKustomization(fs, dir) {
+		objects, err = buildKustomization(fs, dir)
+	} else {
+		objects, err = readManifests(fs, dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to render source of component %s: %w", component.Name, err)
+	}
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("source of component %s has no manifests in %s", component.Name, dir)
+	}
+	for _, obj := range objects {
+		decorate(obj, appBundle, group, component, baseSyncWave+component.Order)
+	}
+	return objects, nil
+}
+
+// readManifests parses the YAML and JSON files below dir, sorted by path
+func readManifests(fs filesys.FileSystem, dir string) ([]*unstructured.Unstructured, error) {
+	if !fs.IsDir(dir) {
+		return nil, fmt.Errorf("directory %s not found", dir)
+	}
+	var files []string
+	err := fs.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		switch path.Ext(file) {
+		case ".yaml", ".yml", ".json":
+			files = append(files, file)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var objects []*unstructured.Unstructured
+	for _, file := range files {
+		data, err := fs.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := parseManifests(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		objects = append(objects, parsed...)
+	}
+	return objects, nil
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package render
+
+import (
+	"fmt"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+)
+
+// Groups returns the groups a bundle deploys: its own, or those of the AppBundleTemplate
+// it is instantiated from, as resolved by ResolveTemplate
+func Groups(appBundle *appv1alpha1.AppBundle) []appv1alpha1.Group {
+	if appBundle.Spec.TemplateRef == nil {
+		return appBundle.Spec.Groups
+	}
+	if template := appBundle.Status.Template; template != nil && template.Spec != nil {
+		return template.Spec.Groups
+	}
+	return nil
+}
+
+// DeclaredParameters returns the parameters a bundle declares, or those of its template
+func DeclaredParameters(appBundle *appv1alpha1.AppBundle) []appv1alpha1.Parameter {
+	if appBundle.Spec.TemplateRef == nil {
+		return appBundle.Spec.Parameters
+	}
+	if template := appBundle.Status.Template; template != nil && template.Spec != nil {
+		return template.Spec.Parameters
+	}
+	return nil
+}
+
+// TemplateRevisionName returns the name of the AppBundleTemplateRevision of a template generation
+func TemplateRevisionName(name string, generation int64) string {
+	return fmt.Sprintf("%s-%d", name, generation)
+}
+
+// TemplateRevisionFunc returns the AppBundleTemplateRevision of a generation of a template,
+// or nil if that generation was not recorded
+type TemplateRevisionFunc func(name string, generation int64) (*appv1alpha1.AppBundleTemplateRevision, error)
+
+// ResolveTemplate records the AppBundleTemplate a bundle renders in its status. This is the
+// live template unless the bundle pins a generation the template has moved past; then the
+// generation is read from its AppBundleTemplateRevision. live is nil if the template does
+// not exist. It reports whether the recorded generation changed.
+func ResolveTemplate(appBundle *appv1alpha1.AppBundle, live *appv1alpha1.AppBundleTemplate, revision TemplateRevisionFunc) (bool, error) {
+	ref := appBundle.Spec.TemplateRef
+	if ref == nil {
+		appBundle.Status.Template = nil
+		return false, nil
+	}
+
+	recorded := appBundle.Status.Template
+	record := func(generation int64, spec *appv1alpha1.AppBundleTemplateSpec) bool {
+		appBundle.Status.Template = &appv1alpha1.TemplateStatus{Name: ref.Name, Generation: generation, Spec: spec.DeepCopy()}
+		return recorded == nil || recorded.Name != ref.Name || recorded.Generation != generation
+	}
+	if live != nil && (ref.Generation == 0 || live.Generation == ref.Generation) {
+		return record(live.Generation, &live.Spec), nil
+	}
+	if ref.Generation != 0 && revision != nil {
+		pinned, err := revision(ref.Name, ref.Generation)
+		if err != nil {
+			return false, err
+		}
+		if pinned != nil {
+			return record(ref.Generation, &pinned.Spec.Template), nil
+		}
+	}
+	if live == nil {
+		return false, fmt.Errorf("AppBundleTemplate %s not found", ref.Name)
+	}
+	return false, fmt.Errorf("generation %d of AppBundleTemplate %s was never rendered; the template is at generation %d",
+		ref.Generation, ref.Name, live.Generation)
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"container/list"
+	"sync"
+)
+
+const (
+	// maxArtifactSize bounds the size of a downloaded artifact and of its unpacked files
+	maxArtifactSize = 128 << 20
+	// maxFileSize bounds the size of a single file of an artifact or commit
+	maxFileSize = 32 << 20
+	// maxCachedCharts bounds the number of Helm charts kept in memory
+	maxCachedCharts = 64
+	// maxCachedArtifactBytes bounds the total size of the unpacked artifacts kept in memory
+	maxCachedArtifactBytes = 256 << 20
+)
+
+// lruCache keeps values up to a total cost, evicting the least recently used first
+type lruCache[V any] struct {
+	mu      sync.Mutex
+	maxCost int64
+	cost    int64
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// lruEntry is a cached value with its key and cost
+type lruEntry[V any] struct {
+	key   string
+	value V
+	cost  int64
+}
+
+// newLRUCache creates a cache holding values up to maxCost
+func newLRUCache[V any](maxCost int64) *lruCache[V] {
+	return &lruCache[V]{maxCost: maxCost, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+// get returns a cached value and marks it as recently used
+func (c *lruCache[V]) get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*lruEntry[V]).value, true
+}
+
+// add caches a value, evicting older values until the total cost fits. Values costing more
+// than the whole cache are not kept.
+func (c *lruCache[V]) add(key string, value V, cost int64) {
+	if cost > c.maxCost {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.cost -= element.Value.(*lruEntry[V]).cost
+		c.order.Remove(element)
+		delete(c.entries, key)
+	}
+	for c.cost+cost > c.maxCost {
+		oldest := c.order.Back()
+		entry := oldest.Value.(*lruEntry[V])
+		c.cost -= entry.cost
+		c.order.Remove(oldest)
+		delete(c.entries, entry.key)
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry[V]{key: key, value: value, cost: cost})
+	c.cost += cost
+}
+
+// len returns the number of cached values
+func (c *lruCache[V]) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
@@ -0,0 +1,292 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/storage/memory"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+)
+
+// commitSHA matches a full Git commit SHA
+var commitSHA = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// gitFiles reads the files of a commit. Remote refs are resolved without fetching, so a
+// commit read before is served from the cache; local repositories are opened in place.
+func (f *Fetcher) gitFiles(ctx context.Context, namespace string, source *appv1alpha1.GitSource) (map[string][]byte, error) {
+	keyRing, err := f.gitKeyRing(ctx, namespace, source.Verify)
+	if err != nil {
+		return nil, err
+	}
+
+	if dir, ok := f.localRepository(source.URL); ok {
+		repository, err := git.PlainOpen(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", source.URL, err)
+		}
+		commit, err := localCommit(repository, source.Ref)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %q in %s: %w", source.Ref, source.URL, err)
+		}
+		return commitFiles(commit, keyRing)
+	}
+
+	auth, err := f.gitAuth(ctx, namespace, source.SecretRef)
+	if err != nil {
+		return nil, err
+	}
+	hash, refName, err := resolveRemoteRef(ctx, source.URL, source.Ref, auth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %q in %s: %w", source.Ref, source.URL, err)
+	}
+
+	// A copy is only reused with the credentials it was fetched with, so a commit SHA cannot
+	// read a private repository through another tenant's copy, and with the keys it was
+	// verified with
+	key := source.URL + "@" + hash.String()
+	if basicAuth, ok := auth.(*githttp.BasicAuth); ok {
+		sum := sha256.Sum256([]byte(basicAuth.Username + "\x00" + basicAuth.Password))
+		key += "|" + hex.EncodeToString(sum[:])
+	}
+	if keyRing != "" {
+		sum := sha256.Sum256([]byte(keyRing))
+		key += "#" + hex.EncodeToString(sum[:])
+	}
+	if files, ok := f.cachedArtifact(key); ok {
+		return files, nil
+	}
+
+	repository, err := fetchCommit(ctx, source.URL, hash, refName, auth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", source.URL, err)
+	}
+	commit, err := repository.CommitObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("commit %s not found in %s: %w", hash, source.URL, err)
+	}
+	files, err := commitFiles(commit, keyRing)
+	if err != nil {
+		return nil, err
+	}
+	f.cacheArtifact(key, files)
+	return files, nil
+}
+
+// fetchCommit fetches a single commit, without its history, into memory. Without refName the
+// commit is asked for by its SHA; servers that do not allow that are fetched in full. Either
+// way the fetched objects are bounded by the artifact size limit.
+func fetchCommit(ctx context.Context, repoURL string, hash plumbing.Hash, refName plumbing.ReferenceName, auth transport.AuthMethod) (*git.Repository, error) {
+	repository, err := git.Init(&limitedStorage{Storage: memory.NewStorage()}, nil)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := repository.CreateRemote(&config.RemoteConfig{Name: git.DefaultRemoteName, URLs: []string{repoURL}})
+	if err != nil {
+		return nil, err
+	}
+
+	refSpec := config.RefSpec(hash.String() + ":refs/heads/fetched")
+	if refName != "" {
+		refSpec = config.RefSpec("+" + refName + ":" + refName)
+	}
+	options := &git.FetchOptions{RefSpecs: []config.RefSpec{refSpec}, Auth: auth, Depth: 1, Tags: git.NoTags}
+	err = remote.FetchContext(ctx, options)
+	if errors.Is(err, git.ErrExactSHA1NotSupported) {
+		options.RefSpecs = []config.RefSpec{"+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*"}
+		options.Depth = 0
+		err = remote.FetchContext(ctx, options)
+	}
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil, err
+	}
+	return repository, nil
+}
+
+// limitedStorage is an in-memory Git storage that refuses objects beyond the artifact size limit
+type limitedStorage struct {
+	*memory.Storage
+	size int64
+}
+
+// SetEncodedObject stores an object, failing once the stored objects exceed the limit
+func (s *limitedStorage) SetEncodedObject(obj plumbing.EncodedObject) (plumbing.Hash, error) {
+	s.size += obj.Size()
+	if s.size > maxArtifactSize {
+		return plumbing.ZeroHash, fmt.Errorf("repository exceeds the limit of %d bytes", maxArtifactSize)
+	}
+	return s.Storage.SetEncodedObject(obj)
+}
+
+// resolveRemoteRef resolves a branch, tag or the default branch of a remote repository to a
+// commit, returning the reference to clone. Commit SHAs are returned as they are.
+func resolveRemoteRef(ctx context.Context, repoURL, ref string, auth transport.AuthMethod) (plumbing.Hash, plumbing.ReferenceName, error) {
+	if commitSHA.MatchString(ref) {
+		return plumbing.NewHash(ref), "", nil
+	}
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{Name: git.DefaultRemoteName, URLs: []string{repoURL}})
+	refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: auth})
+	if err != nil {
+		return plumbing.ZeroHash, "", err
+	}
+	byName := map[plumbing.ReferenceName]*plumbing.Reference{}
+	for _, reference := range refs {
+		byName[reference.Name()] = reference
+	}
+
+	var candidates []plumbing.ReferenceName
+	if ref == "" {
+		head, ok := byName[plumbing.HEAD]
+		if !ok {
+			return plumbing.ZeroHash, "", fmt.Errorf("remote has no HEAD")
+		}
+		if head.Type() != plumbing.SymbolicReference {
+			return head.Hash(), "", nil
+		}
+		candidates = []plumbing.ReferenceName{head.Target()}
+	} else {
+		candidates = []plumbing.ReferenceName{plumbing.NewBranchReferenceName(ref), plumbing.NewTagReferenceName(ref)}
+	}
+	for _, name := range candidates {
+		// Annotated tags are advertised along with the commit they point to
+		if peeled, ok := byName[name+"^{}"]; ok {
+			return peeled.Hash(), name, nil
+		}
+		if reference, ok := byName[name]; ok {
+			return reference.Hash(), name, nil
+		}
+	}
+	return plumbing.ZeroHash, "", fmt.Errorf("no branch or tag named %q", ref)
+}
+
+// localCommit resolves a branch, tag or commit of a local repository, defaulting to HEAD
+func localCommit(repository *git.Repository, ref string) (*object.Commit, error) {
+	if ref == "" {
+		head, err := repository.Head()
+		if err != nil {
+			return nil, err
+		}
+		return repository.CommitObject(head.Hash())
+	}
+	hash, err := repository.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return nil, err
+	}
+	return repository.CommitObject(*hash)
+}
+
+// commitFiles verifies the signature of a commit against keyRing, if any, and reads the
+// regular files of its tree
+func commitFiles(commit *object.Commit, keyRing string) (map[string][]byte, error) {
+	if keyRing != "" {
+		if _, err := commit.Verify(keyRing); err != nil {
+			return nil, fmt.Errorf("commit %s is not signed by a trusted key: %w", commit.Hash, err)
+		}
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	files := map[string][]byte{}
+	var total int64
+	err = tree.Files().ForEach(func(file *object.File) error {
+		if file.Mode != filemode.Regular && file.Mode != filemode.Executable {
+			return nil
+		}
+		name := "/" + file.Name
+		if file.Size > maxFileSize {
+			return fmt.Errorf("file %s exceeds the limit of %d bytes", name, maxFileSize)
+		}
+		reader, err := file.Reader()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = reader.Close() }()
+		data, err := readFile(reader, name, &total)
+		if err != nil {
+			return err
+		}
+		files[name] = data
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit %s: %w", commit.Hash, err)
+	}
+	return files, nil
+}
+
+// gitAuth reads the username and password of a Secret
+func (f *Fetcher) gitAuth(ctx context.Context, namespace string, ref *appv1alpha1.LocalObjectReference) (transport.AuthMethod, error) {
+	if ref == nil {
+		return nil, nil
+	}
+	secret, err := f.secret(ctx, types.NamespacedName{Name: ref.Name, Namespace: namespace})
+	if err != nil {
+		return nil, err
+	}
+	return &githttp.BasicAuth{Username: string(secret.Data["username"]), Password: string(secret.Data["password"])}, nil
+}
+
+// gitKeyRing joins the armored OpenPGP keys of a Secret into a key ring
+func (f *Fetcher) gitKeyRing(ctx context.Context, namespace string, verify *appv1alpha1.GitVerification) (string, error) {
+	if verify == nil {
+		return "", nil
+	}
+	secret, err := f.secret(ctx, types.NamespacedName{Name: verify.SecretRef.Name, Namespace: namespace})
+	if err != nil {
+		return "", err
+	}
+	// Sorted so the key ring, and the cache key derived from it, are stable
+	var keys []string
+	for _, key := range secret.Data {
+		keys = append(keys, string(key))
+	}
+	sort.Strings(keys)
+	if len(keys) == 0 {
+		return "", fmt.Errorf("Secret %s/%s holds no keys", namespace, verify.SecretRef.Name)
+	}
+	return strings.Join(keys, "\n"), nil
+}
+
+// secret reads a Secret through the Fetcher's reader
+func (f *Fetcher) secret(ctx context.Context, name types.NamespacedName) (*corev1.Secret, error) {
+	if f.reader == nil {
+		return nil, fmt.Errorf("Secret %s requires a cluster connection", name)
+	}
+	secret := &corev1.Secret{}
+	if err := f.reader.Get(ctx, name, secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+)
+
+// fluxSourceGroupVersion is the API version of the Flux sources a kustomization may be read from
+var fluxSourceGroupVersion = schema.GroupVersion{Group: "source.toolkit.fluxcd.io", Version: "v1"}
+
+// artifactClient downloads the artifacts of Flux sources
+var artifactClient = &http.Client{Timeout: 2 * time.Minute}
+
+// Kustomization loads the files of a kustomization from a ConfigMap, an OCI artifact or the
+// artifact of a Flux source
+func (f *Fetcher) Kustomization(ctx context.Context, namespace string, source *appv1alpha1.KustomizeSource) (filesys.FileSystem, error) {
+	var files map[string][]byte
+	var err error
+	switch {
+	case source.ConfigMapRef != nil:
+		files, err = f.configMapFiles(ctx, types.NamespacedName{Name: source.ConfigMapRef.Name, Namespace: namespace})
+	case source.OCI != nil:
+		files, err = f.ociArtifactFiles(ctx, source.OCI)
+	case source.SourceRef != nil:
+		files, err = f.fluxArtifactFiles(ctx, namespace, source.SourceRef)
+	default:
+		err = fmt.Errorf("kustomize source has neither configMapRef, oci nor sourceRef")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return memoryFS(files)
+}
+
+// configMapFiles returns the keys of a ConfigMap as files in the root directory
+func (f *Fetcher) configMapFiles(ctx context.Context, name types.NamespacedName) (map[string][]byte, error) {
+	if f.reader == nil {
+		return nil, fmt.Errorf("kustomization in ConfigMap %s requires a cluster connection", name)
+	}
+	configMap := &corev1.ConfigMap{}
+	if err := f.reader.Get(ctx, name, configMap); err != nil {
+		return nil, err
+	}
+	files := map[string][]byte{}
+	for key, value := range configMap.Data {
+		files["/"+key] = []byte(value)
+	}
+	for key, value := range configMap.BinaryData {
+		files["/"+key] = value
+	}
+	return files, nil
+}
+
+// fluxArtifactFiles downloads and unpacks the latest artifact of a Flux source, checking it
+// against the digest the source reports
+func (f *Fetcher) fluxArtifactFiles(ctx context.Context, namespace string, ref *appv1alpha1.FluxSourceReference) (map[string][]byte, error) {
+	if f.reader == nil {
+		return nil, fmt.Errorf("kustomization in %s %s requires a cluster connection", ref.Kind, ref.Name)
+	}
+	kind := ref.Kind
+	if kind == "" {
+		kind = "GitRepository"
+	}
+	if ref.Namespace != "" && ref.Namespace != namespace {
+		if !f.crossNamespaceSources {
+			return nil, fmt.Errorf("%s %s/%s is in another namespace and cross-namespace sources are disabled",
+				kind, ref.Namespace, ref.Name)
+		}
+		namespace = ref.Namespace
+	}
+	fluxSource := &unstructured.Unstructured{}
+	fluxSource.SetGroupVersionKind(fluxSourceGroupVersion.WithKind(kind))
+	if err := f.reader.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: namespace}, fluxSource); err != nil {
+		return nil, err
+	}
+	url, _, _ := unstructured.NestedString(fluxSource.Object, "status", "artifact", "url")
+	digest, _, _ := unstructured.NestedString(fluxSource.Object, "status", "artifact", "digest")
+	if url == "" || digest == "" {
+		return nil, fmt.Errorf("%s %s/%s has no artifact yet", kind, namespace, ref.Name)
+	}
+	if files, ok := f.cachedArtifact(digest); ok {
+		return files, nil
+	}
+
+	algorithm, expected, _ := strings.Cut(digest, ":")
+	if algorithm != "sha256" {
+		return nil, fmt.Errorf("unsupported digest %s of %s %s/%s", digest, kind, namespace, ref.Name)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := artifactClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download artifact of %s %s/%s: %w", kind, namespace, ref.Name, err)
+	}
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download artifact of %s %s/%s: %s", kind, namespace, ref.Name, response.Status)
+	}
+	data, err := readLimited(response.Body, maxArtifactSize, fmt.Sprintf("artifact of %s %s/%s", kind, namespace, ref.Name))
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != expected {
+		return nil, fmt.Errorf("artifact of %s %s/%s does not match digest %s", kind, namespace, ref.Name, digest)
+	}
+	files, err := untar(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack artifact of %s %s/%s: %w", kind, namespace, ref.Name, err)
+	}
+	f.cacheArtifact(digest, files)
+	return files, nil
+}
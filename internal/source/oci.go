@@ -0,0 +1,113 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/credentials"
+	"oras.land/oras-go/v2/registry/remote/retry"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+)
+
+// registryClient talks to OCI registries, retrying transient failures
+var registryClient = &http.Client{Transport: retry.NewTransport(nil), Timeout: 2 * time.Minute}
+
+// newRepository returns a client of an OCI repository that reads registry credentials from
+// the Docker config
+func newRepository(reference string, plainHTTP bool) (*remote.Repository, error) {
+	repository, err := remote.NewRepository(reference)
+	if err != nil {
+		return nil, err
+	}
+	repository.PlainHTTP = plainHTTP
+	store, err := credentials.NewStoreFromDocker(credentials.StoreOptions{})
+	if err != nil {
+		return nil, err
+	}
+	repository.Client = &auth.Client{
+		Client:     registryClient,
+		Cache:      auth.NewCache(),
+		Credential: credentials.Credential(store),
+	}
+	return repository, nil
+}
+
+// ociArtifactFiles pulls an OCI artifact and unpacks its first tarball layer. Registry
+// credentials are read from the Docker config, like for Helm charts.
+func (f *Fetcher) ociArtifactFiles(ctx context.Context, source *appv1alpha1.OCIArtifactSource) (map[string][]byte, error) {
+	repository, err := newRepository(strings.TrimPrefix(source.URL, "oci://"), source.Insecure)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OCI url %q: %w", source.URL, err)
+	}
+
+	// A digest pins the artifact, so a cached copy needs no round trip to the registry;
+	// fetched content is verified against the digests
+	tag := source.Tag
+	if source.Digest != "" {
+		if files, ok := f.cachedArtifact(source.Digest); ok {
+			return files, nil
+		}
+		tag = source.Digest
+	} else if tag == "" {
+		tag = "latest"
+	}
+	manifestDescriptor, err := repository.Resolve(ctx, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s:%s: %w", source.URL, tag, err)
+	}
+	if files, ok := f.cachedArtifact(manifestDescriptor.Digest.String()); ok {
+		return files, nil
+	}
+
+	data, err := content.FetchAll(ctx, repository, manifestDescriptor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch manifest of %s:%s: %w", source.URL, tag, err)
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest of %s:%s: %w", source.URL, tag, err)
+	}
+	if len(manifest.Layers) == 0 {
+		return nil, fmt.Errorf("artifact %s:%s has no layers", source.URL, tag)
+	}
+	if manifest.Layers[0].Size > maxArtifactSize {
+		return nil, fmt.Errorf("layer of %s:%s exceeds the limit of %d bytes", source.URL, tag, maxArtifactSize)
+	}
+	// content.FetchAll verifies the layer against its digest and size
+	layer, err := content.FetchAll(ctx, repository, manifest.Layers[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch layer of %s:%s: %w", source.URL, tag, err)
+	}
+	files, err := untar(bytes.NewReader(layer))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack %s:%s: %w", source.URL, tag, err)
+	}
+	f.cacheArtifact(manifestDescriptor.Digest.String(), files)
+	return files, nil
+}
@@ -0,0 +1,462 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package source fetches the external inputs of components, such as Helm charts, values kept
+// in ConfigMaps and Secrets, kustomizations, and manifests stored in OCI artifacts or Git.
+// Remote artifacts pinned to a version, digest or commit are cached in memory, up to a bound.
+package source
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/repo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"oras.land/oras-go/v2/content"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/yaml"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+)
+
+// Fetcher loads Helm charts, kustomizations and manifests from repositories and values from
+// the cluster
+type Fetcher struct {
+	reader client.Reader
+
+	charts *lruCache[*chart.Chart]
+	// artifacts holds the files of unpacked artifacts by digest
+	artifacts *lruCache[map[string][]byte]
+	// localRepositories maps repository URLs to the local directories serving them
+	localRepositories map[string]string
+	// crossNamespaceSources allows reading Flux sources outside the bundle's namespace
+	crossNamespaceSources bool
+}
+
+// Option configures a Fetcher
+type Option func(*Fetcher)
+
+// WithLocalRepositories serves the Helm chart repositories and Git repositories at the given
+// URLs from local directories instead of the network. It is meant for tests: bundles can only
+// name remote repositories, so that they cannot read the controller's filesystem.
+func WithLocalRepositories(directories map[string]string) Option {
+	return func(f *Fetcher) {
+		for repoURL, dir := range directories {
+			f.localRepositories[strings.TrimSuffix(repoURL, "/")] = dir
+		}
+	}
+}
+
+// WithCrossNamespaceSources lets components read the artifacts of Flux sources in other
+// namespaces. Without it, a bundle can only use the sources of its own namespace, so that
+// tenants cannot render each other's manifests.
+func WithCrossNamespaceSources() Option {
+	return func(f *Fetcher) {
+		f.crossNamespaceSources = true
+	}
+}
+
+// NewFetcher returns a Fetcher that reads values and in-cluster sources through reader.
+// Without a reader, components with valuesFrom or in-cluster sources cannot be rendered.
+func NewFetcher(reader client.Reader, options ...Option) *Fetcher {
+	f := &Fetcher{
+		reader:            reader,
+		charts:            newLRUCache[*chart.Chart](maxCachedCharts),
+		artifacts:         newLRUCache[map[string][]byte](maxCachedArtifactBytes),
+		localRepositories: map[string]string{},
+	}
+	for _, option := range options {
+		option(f)
+	}
+	return f
+}
+
+// localRepository returns the local directory serving a repository URL, if any
+func (f *Fetcher) localRepository(repoURL string) (string, bool) {
+	dir, ok := f.localRepositories[strings.TrimSuffix(repoURL, "/")]
+	return dir, ok
+}
+
+// HelmChart loads a chart from an HTTP repository or an OCI registry
+func (f *Fetcher) HelmChart(ctx context.Context, source *appv1alpha1.HelmChartSource) (*chart.Chart, error) {
+	repoURL, err := url.Parse(source.RepoURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid repoURL %q: %w", source.RepoURL, err)
+	}
+	if dir, ok := f.localRepository(source.RepoURL); ok {
+		return loadLocalChart(filepath.Join(dir, source.Chart), source.Version)
+	}
+
+	// Only exact versions can be cached; ranges and the latest version may resolve differently later
+	key := strings.TrimSuffix(source.RepoURL, "/") + "/" + source.Chart + "@" + source.Version
+	cacheable := isExactVersion(source.Version)
+	if cacheable {
+		if cached, ok := f.charts.get(key); ok {
+			return cached, nil
+		}
+	}
+
+	var chrt *chart.Chart
+	switch repoURL.Scheme {
+	case "oci":
+		chrt, err = pullOCIChart(ctx, source)
+	case "http", "https":
+		chrt, err = downloadRepoChart(ctx, source)
+	default:
+		err = fmt.Errorf("unsupported repoURL scheme %q", repoURL.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cacheable {
+		f.charts.add(key, chrt, 1)
+	}
+	return chrt, nil
+}
+
+// Values reads the values of the referenced ConfigMaps and Secrets and merges them in order,
+// later references overriding earlier ones
+func (f *Fetcher) Values(ctx context.Context, namespace string, refs []appv1alpha1.ValuesReference) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	for _, ref := range refs {
+		if f.reader == nil {
+			return nil, fmt.Errorf("valuesFrom %s/%s requires a cluster connection", ref.Kind, ref.Name)
+		}
+		key := ref.Key
+		if key == "" {
+			key = "values.yaml"
+		}
+
+		var data []byte
+		var found bool
+		var err error
+		name := types.NamespacedName{Name: ref.Name, Namespace: namespace}
+		switch ref.Kind {
+		case "ConfigMap":
+			configMap := &corev1.ConfigMap{}
+			if err = f.reader.Get(ctx, name, configMap); err == nil {
+				var value string
+				value, found = configMap.Data[key]
+				data = []byte(value)
+			}
+		case "Secret":
+			secret := &corev1.Secret{}
+			if err = f.reader.Get(ctx, name, secret); err == nil {
+				data, found = secret.Data[key]
+			}
+		default:
+			return nil, fmt.Errorf("unsupported valuesFrom kind %q", ref.Kind)
+		}
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+		if !found {
+			if ref.Optional {
+				continue
+			}
+			return nil, fmt.Errorf("key %s of %s %s not found", key, ref.Kind, name)
+		}
+
+		refValues, err := chartutil.ReadValues(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse values in %s %s: %w", ref.Kind, name, err)
+		}
+		values = chartutil.CoalesceTables(refValues.AsMap(), values)
+	}
+	return values, nil
+}
+
+// Parameters reads the keys of the referenced ConfigMaps and Secrets, later sources
+// overriding earlier ones
+func (f *Fetcher) Parameters(ctx context.Context, namespace string, sources []appv1alpha1.ParameterSource) (map[string]string, error) {
+	values := map[string]string{}
+	for _, source := range sources {
+		if f.reader == nil {
+			return nil, fmt.Errorf("parameter source %s/%s requires a cluster connection", source.Kind, source.Name)
+		}
+		name := types.NamespacedName{Name: source.Name, Namespace: namespace}
+		var err error
+		switch source.Kind {
+		case "ConfigMap":
+			configMap := &corev1.ConfigMap{}
+			if err = f.reader.Get(ctx, name, configMap); err == nil {
+				for key, value := range configMap.Data {
+					values[key] = value
+				}
+			}
+		case "Secret":
+			secret := &corev1.Secret{}
+			if err = f.reader.Get(ctx, name, secret); err == nil {
+				for key, value := range secret.Data {
+					values[key] = string(value)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unsupported parameter source kind %q", source.Kind)
+		}
+		if errors.IsNotFound(err) && source.Optional {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read parameter source %s %s: %w", source.Kind, name, err)
+		}
+	}
+	return values, nil
+}
+
+// loadLocalChart loads a chart directory or archive and checks its version
+func loadLocalChart(path, version string) (*chart.Chart, error) {
+	chrt, err := loader.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if version != "" && chrt.Metadata.Version != version {
+		return nil, fmt.Errorf("chart %s has version %s, not %s", path, chrt.Metadata.Version, version)
+	}
+	return chrt, nil
+}
+
+// pullOCIChart pulls a chart from an OCI registry, resolving version ranges against its tags
+func pullOCIChart(ctx context.Context, source *appv1alpha1.HelmChartSource) (*chart.Chart, error) {
+	ref := strings.TrimSuffix(strings.TrimPrefix(source.RepoURL, "oci://"), "/") + "/" + source.Chart
+	repository, err := newRepository(ref, false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OCI repository %q: %w", ref, err)
+	}
+
+	// Helm stores the + of a semver build in a tag as _
+	tag := strings.ReplaceAll(source.Version, "+", "_")
+	if !isExactVersion(source.Version) {
+		var tags []string
+		if err := repository.Tags(ctx, "", func(page []string) error {
+			tags = append(tags, page...)
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("failed to list tags of %s: %w", ref, err)
+		}
+		if tag, err = registry.GetTagMatchingVersionOrConstraint(tags, source.Version); err != nil {
+			return nil, err
+		}
+	}
+
+	manifestDescriptor, err := repository.Resolve(ctx, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s:%s: %w", ref, tag, err)
+	}
+	data, err := content.FetchAll(ctx, repository, manifestDescriptor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch manifest of %s:%s: %w", ref, tag, err)
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest of %s:%s: %w", ref, tag, err)
+	}
+	for _, layer := range manifest.Layers {
+		if layer.MediaType != registry.ChartLayerMediaType && layer.MediaType != registry.LegacyChartLayerMediaType {
+			continue
+		}
+		if layer.Size > maxArtifactSize {
+			return nil, fmt.Errorf("chart %s:%s exceeds the limit of %d bytes", ref, tag, maxArtifactSize)
+		}
+		// content.FetchAll verifies the layer against its digest and size
+		archive, err := content.FetchAll(ctx, repository, layer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to pull %s:%s: %w", ref, tag, err)
+		}
+		return loader.LoadArchive(bytes.NewReader(archive))
+	}
+	return nil, fmt.Errorf("%s:%s is not a Helm chart", ref, tag)
+}
+
+// downloadRepoChart downloads a chart from an HTTP chart repository
+func downloadRepoChart(ctx context.Context, source *appv1alpha1.HelmChartSource) (*chart.Chart, error) {
+	indexURL := strings.TrimSuffix(source.RepoURL, "/") + "/index.yaml"
+	data, err := download(ctx, indexURL)
+	if err != nil {
+		return nil, err
+	}
+	index := &repo.IndexFile{}
+	if err := yaml.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", indexURL, err)
+	}
+	index.SortEntries()
+	chartVersion, err := index.Get(source.Chart, source.Version)
+	if err != nil {
+		return nil, fmt.Errorf("chart %s version %q not found in %s: %w", source.Chart, source.Version, source.RepoURL, err)
+	}
+	if len(chartVersion.URLs) == 0 {
+		return nil, fmt.Errorf("chart %s version %s in %s has no download URL", source.Chart, chartVersion.Version, source.RepoURL)
+	}
+	chartURL, err := repo.ResolveReferenceURL(source.RepoURL, chartVersion.URLs[0])
+	if err != nil {
+		return nil, err
+	}
+	archive, err := download(ctx, chartURL)
+	if err != nil {
+		return nil, err
+	}
+	return loader.LoadArchive(bytes.NewReader(archive))
+}
+
+// download reads a URL with artifactClient, up to the artifact size limit
+func download(ctx context.Context, downloadURL string) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := artifactClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", downloadURL, err)
+	}
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", downloadURL, response.Status)
+	}
+	return readLimited(response.Body, maxArtifactSize, downloadURL)
+}
+
+// isExactVersion reports whether version names a single chart version rather than a range
+func isExactVersion(version string) bool {
+	if version == "" {
+		return false
+	}
+	_, err := semver.StrictNewVersion(strings.TrimPrefix(version, "v"))
+	return err == nil
+}
+
+// Source loads the manifests of a component from an OCI artifact or a Git commit
+func (f *Fetcher) Source(ctx context.Context, namespace string, source *appv1alpha1.ComponentSource) (filesys.FileSystem, error) {
+	var files map[string][]byte
+	var err error
+	switch {
+	case source.OCI != nil:
+		files, err = f.ociArtifactFiles(ctx, source.OCI)
+	case source.Git != nil:
+		files, err = f.gitFiles(ctx, namespace, source.Git)
+	default:
+		err = fmt.Errorf("source has neither oci nor git")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return memoryFS(files)
+}
+
+// memoryFS writes files, keyed by absolute path, into an in-memory filesystem
+func memoryFS(files map[string][]byte) (filesys.FileSystem, error) {
+	fs := filesys.MakeFsInMemory()
+	for name, data := range files {
+		if err := fs.MkdirAll(path.Dir(name)); err != nil {
+			return nil, err
+		}
+		if err := fs.WriteFile(name, data); err != nil {
+			return nil, err
+		}
+	}
+	return fs, nil
+}
+
+// readLimited reads at most limit bytes, failing for larger inputs instead of truncating them
+func readLimited(reader io.Reader, limit int64, what string) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s exceeds the limit of %d bytes", what, limit)
+	}
+	return data, nil
+}
+
+// readFile reads a file of an artifact, adding its size to total and failing once either the
+// file or the artifact grows past its limit
+func readFile(reader io.Reader, name string, total *int64) ([]byte, error) {
+	data, err := readLimited(reader, maxFileSize, "file "+name)
+	if err != nil {
+		return nil, err
+	}
+	*total += int64(len(data))
+	if *total > maxArtifactSize {
+		return nil, fmt.Errorf("files exceed the limit of %d bytes", maxArtifactSize)
+	}
+	return data, nil
+}
+
+// cachedArtifact returns the files of an artifact unpacked before
+func (f *Fetcher) cachedArtifact(digest string) (map[string][]byte, bool) {
+	return f.artifacts.get(digest)
+}
+
+// cacheArtifact keeps the files of an unpacked artifact by digest
+func (f *Fetcher) cacheArtifact(digest string, files map[string][]byte) {
+	var size int64
+	for name, data := range files {
+		size += int64(len(name) + len(data))
+	}
+	f.artifacts.add(digest, files, size)
+}
+
+// untar reads the regular files of a gzipped tarball, keyed by their absolute path; cleaning
+// the rooted names keeps entries like ../x inside the root
+func untar(reader io.Reader) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	files := map[string][]byte{}
+	var total int64
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean("/" + header.Name)
+		data, err := readFile(tr, name, &total)
+		if err != nil {
+			return nil, err
+		}
+		files[name] = data
+	}
+}
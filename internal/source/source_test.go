@@ -0,0 +1,427 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appv1alpha1 "github.com/example/appbundle-operator/api/v1alpha1"
+)
+
+const configMapManifest = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n"
+
+// commitFile writes a file into a repository and commits it, signed with key if not nil
+func commitFile(t *testing.T, repository *git.Repository, dir, name, content string, key *openpgp.Entity) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repository.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := worktree.Add(name); err != nil {
+		t.Fatal(err)
+	}
+	_, err = worktree.Commit("Update "+name, &git.CommitOptions{
+		Author:  &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+		SignKey: key,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGitSource(t *testing.T) {
+	dir := t.TempDir()
+	repository, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, repository, dir, "deploy/configmap.yaml", configMapManifest, nil)
+	head, err := repository.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repository.CreateTag("v1", head.Hash(), nil); err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, repository, dir, "deploy/service.yaml", "kind: Service\n", nil)
+
+	fetcher := NewFetcher(nil, WithLocalRepositories(map[string]string{"https://git.example.com/repo": dir}))
+	for ref, expected := range map[string][]string{
+		"":                   {"/deploy/configmap.yaml", "/deploy/service.yaml"},
+		"v1":                 {"/deploy/configmap.yaml"},
+		head.Hash().String(): {"/deploy/configmap.yaml"},
+	} {
+		files, err := fetcher.gitFiles(context.Background(), "default", &appv1alpha1.GitSource{URL: "https://git.example.com/repo", Ref: ref})
+		if err != nil {
+			t.Fatalf("ref %q: %v", ref, err)
+		}
+		if len(files) != len(expected) {
+			t.Errorf("ref %q: expected %v, got %d files", ref, expected, len(files))
+		}
+		for _, name := range expected {
+			if _, ok := files[name]; !ok {
+				t.Errorf("ref %q: missing %s", ref, name)
+			}
+		}
+	}
+}
+
+func TestRemoteGitSource(t *testing.T) {
+	if _, err := exec.LookPath("git-upload-pack"); err != nil {
+		t.Skip("git-upload-pack is required to serve file:// repositories")
+	}
+	dir := t.TempDir()
+	repository, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, repository, dir, "deploy/configmap.yaml", configMapManifest, nil)
+	head, err := repository.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, repository, dir, "deploy/service.yaml", "kind: Service\n", nil)
+
+	credentials := func(name, password string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Data:       map[string][]byte{"username": []byte("git"), "password": []byte(password)},
+		}
+	}
+	fetcher := NewFetcher(fake.NewClientBuilder().WithObjects(credentials("first", "a"), credentials("second", "b")).Build())
+	url := "file://" + dir
+
+	for ref, expected := range map[string]int{"": 2, head.Hash().String(): 1} {
+		files, err := fetcher.gitFiles(context.Background(), "default", &appv1alpha1.GitSource{URL: url, Ref: ref})
+		if err != nil {
+			t.Fatalf("ref %q: %v", ref, err)
+		}
+		if len(files) != expected {
+			t.Errorf("ref %q: expected %d files, got %d", ref, expected, len(files))
+		}
+	}
+
+	// The same commit read with other credentials is not served from the cache
+	for _, secret := range []string{"first", "second"} {
+		source := &appv1alpha1.GitSource{URL: url, Ref: head.Hash().String(), SecretRef: &appv1alpha1.LocalObjectReference{Name: secret}}
+		if _, err := fetcher.gitFiles(context.Background(), "default", source); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fetcher.artifacts.len() != 4 {
+		t.Errorf("expected a cached copy per commit and credentials, got %d", fetcher.artifacts.len())
+	}
+}
+
+func TestGitSourceVerification(t *testing.T) {
+	trusted, err := openpgp.NewEntity("Release", "", "release@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	untrusted, err := openpgp.NewEntity("Someone", "", "someone@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var publicKey bytes.Buffer
+	writer, err := armor.Encode(&publicKey, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := trusted.Serialize(writer); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	keys := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "release-keys", Namespace: "default"},
+		Data:       map[string][]byte{"release.asc": publicKey.Bytes()},
+	}
+	source := &appv1alpha1.GitSource{
+		URL:    "https://git.example.com/repo",
+		Verify: &appv1alpha1.GitVerification{SecretRef: appv1alpha1.LocalObjectReference{Name: "release-keys"}},
+	}
+
+	for name, key := range map[string]*openpgp.Entity{"trusted": trusted, "untrusted": untrusted, "unsigned": nil} {
+		dir := t.TempDir()
+		repository, err := git.PlainInit(dir, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		commitFile(t, repository, dir, "configmap.yaml", configMapManifest, key)
+		fetcher := NewFetcher(fake.NewClientBuilder().WithObjects(keys).Build(),
+			WithLocalRepositories(map[string]string{source.URL: dir}))
+
+		_, err = fetcher.gitFiles(context.Background(), "default", source)
+		if name == "trusted" && err != nil {
+			t.Errorf("commit signed by a trusted key was rejected: %v", err)
+		}
+		if name != "trusted" && err == nil {
+			t.Errorf("%s commit was accepted", name)
+		}
+	}
+}
+
+// newRegistry serves a single artifact whose layer is a tarball of files, standing in for an
+// OCI registry, and returns the artifact's repository URL and manifest digest
+func newRegistry(t *testing.T, files map[string]string) (string, digest.Digest) {
+	t.Helper()
+	var layer bytes.Buffer
+	gz := gzip.NewWriter(&layer)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	blobs := map[digest.Digest][]byte{
+		digest.FromBytes(layer.Bytes()):    layer.Bytes(),
+		ocispec.DescriptorEmptyJSON.Digest: ocispec.DescriptorEmptyJSON.Data,
+	}
+	manifest, err := json.Marshal(ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    ocispec.DescriptorEmptyJSON,
+		Layers: []ocispec.Descriptor{{
+			MediaType: "application/vnd.cncf.flux.content.v1.tar+gzip",
+			Digest:    digest.FromBytes(layer.Bytes()),
+			Size:      int64(layer.Len()),
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifestDigest := digest.FromBytes(manifest)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case strings.HasPrefix(r.URL.Path, "/v2/manifests/manifests/"):
+			ref := strings.TrimPrefix(r.URL.Path, "/v2/manifests/manifests/")
+			if ref != "v1" && ref != manifestDigest.String() {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", ocispec.MediaTypeImageManifest)
+			w.Header().Set("Docker-Content-Digest", manifestDigest.String())
+			w.Header().Set("Content-Length", strconv.Itoa(len(manifest)))
+			if r.Method == http.MethodGet {
+				_, _ = w.Write(manifest)
+			}
+		case strings.HasPrefix(r.URL.Path, "/v2/manifests/blobs/"):
+			blob, ok := blobs[digest.Digest(strings.TrimPrefix(r.URL.Path, "/v2/manifests/blobs/"))]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write(blob)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "oci://" + strings.TrimPrefix(server.URL, "http://") + "/manifests", manifestDigest
+}
+
+func TestOCISource(t *testing.T) {
+	url, manifestDigest := newRegistry(t, map[string]string{"deploy/configmap.yaml": configMapManifest})
+	fetcher := NewFetcher(nil)
+
+	fs, err := fetcher.Source(context.Background(), "default", &appv1alpha1.ComponentSource{
+		OCI: &appv1alpha1.OCIArtifactSource{URL: url, Digest: manifestDigest.String(), Insecure: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := fs.ReadFile("/deploy/configmap.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != configMapManifest {
+		t.Errorf("unexpected content %q", data)
+	}
+
+	_, err = fetcher.Source(context.Background(), "default", &appv1alpha1.ComponentSource{
+		OCI: &appv1alpha1.OCIArtifactSource{URL: url, Digest: digest.FromString("other").String(), Insecure: true},
+	})
+	if err == nil {
+		t.Error("expected an artifact with a different digest to be rejected")
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+	cache := newLRUCache[string](10)
+	cache.add("a", "first", 4)
+	cache.add("b", "second", 4)
+	if _, ok := cache.get("a"); !ok {
+		t.Fatal("expected a to be cached")
+	}
+
+	// b is now the least recently used entry and makes room for c
+	cache.add("c", "third", 4)
+	if _, ok := cache.get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := cache.get(key); !ok {
+			t.Errorf("expected %s to be cached", key)
+		}
+	}
+
+	cache.add("huge", "too large", 11)
+	if _, ok := cache.get("huge"); ok || cache.len() != 2 {
+		t.Errorf("expected a value larger than the cache to be skipped, have %d entries", cache.len())
+	}
+}
+
+func TestUntarLimits(t *testing.T) {
+	for name, sizes := range map[string][]int{
+		"within limits":      {maxFileSize, 1},
+		"file too large":     {maxFileSize + 1},
+		"artifact too large": {maxFileSize, maxFileSize, maxFileSize, maxFileSize, 1},
+	} {
+		var artifact bytes.Buffer
+		gz := gzip.NewWriter(&artifact)
+		tw := tar.NewWriter(gz)
+		for i, size := range sizes {
+			if err := tw.WriteHeader(&tar.Header{Name: strconv.Itoa(i), Mode: 0o644, Size: int64(size)}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tw.Write(make([]byte, size)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := gz.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := untar(&artifact)
+		if name == "within limits" && err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		if name != "within limits" && err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestRepoChart(t *testing.T) {
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	chartYAML := "apiVersion: v2\nname: app\nversion: 1.2.0\n"
+	if err := tw.WriteHeader(&tar.Header{Name: "app/Chart.yaml", Mode: 0o644, Size: int64(len(chartYAML))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write([]byte(chartYAML)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	stall := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/charts/index.yaml":
+			_, _ = w.Write([]byte("apiVersion: v1\nentries:\n  app:\n" +
+				"  - apiVersion: v2\n    name: app\n    version: 1.2.0\n    urls: [app-1.2.0.tgz]\n" +
+				"  - apiVersion: v2\n    name: app\n    version: 1.1.0\n    urls: [app-1.1.0.tgz]\n"))
+		case "/charts/app-1.2.0.tgz":
+			_, _ = w.Write(archive.Bytes())
+		case "/stalled/index.yaml":
+			<-stall
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	// Cleanups run last first: release the stalled handler before closing the server
+	t.Cleanup(func() { close(stall) })
+	fetcher := NewFetcher(nil)
+
+	chrt, err := fetcher.HelmChart(context.Background(), &appv1alpha1.HelmChartSource{
+		RepoURL: server.URL + "/charts", Chart: "app", Version: "^1.0.0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chrt.Metadata.Version != "1.2.0" {
+		t.Errorf("expected the latest matching version, got %s", chrt.Metadata.Version)
+	}
+
+	if _, err := fetcher.HelmChart(context.Background(), &appv1alpha1.HelmChartSource{
+		RepoURL: server.URL + "/charts", Chart: "app", Version: "2.0.0",
+	}); err == nil {
+		t.Error("expected a missing version to be rejected")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := fetcher.HelmChart(ctx, &appv1alpha1.HelmChartSource{
+		RepoURL: server.URL + "/stalled", Chart: "app", Version: "1.2.0",
+	}); err == nil {
+		t.Error("expected a stalled repository to fail with the context")
+	}
+}